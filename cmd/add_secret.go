@@ -1,27 +1,255 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"vault-cli/internal/auth"
+	"vault-cli/internal/core"
 	"vault-cli/internal/secrets"
 	"vault-cli/internal/session"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	addTTL         string
+	addRotateEvery string
+	addType        string
+	addSet         []string
+	addSetFile     []string
+	addPrompt      []string
+	addStdin       bool
+	addFromFile    string
+	addInsecureArg bool
+)
+
 var addSecretCmd = &cobra.Command{
-	Use:   "add-secret <category> <name> <value>",
+	Use:   "add-secret <path> [value]",
 	Short: "Add or update an encrypted secret",
-	Args:  cobra.ExactArgs(3),
+	Long: `Adds or updates the secret at path, e.g. prod/payments/stripe/api_key.
+The older form with the category and name as two arguments still works.
+
+The value is asked for at a hidden prompt, twice. --stdin reads it from
+standard input instead (one trailing newline is dropped) and --from-file
+from a file (used exactly). A value on the command line ends up in shell
+history and is visible to other users in ps, so it is refused unless
+--insecure-arg is given; with it the last argument is the value.
+
+--ttl makes the secret expire that long from now (e.g. 90d, 2w, 36h);
+reads are refused after that. --rotate-every sets how often the value
+should be replaced; report and list-secrets --expiring flag it once it is
+overdue. Either can be set to "never" to clear it. Updating a secret
+without them keeps its current expiry and rotation period.
+
+--type stores several fields as one encrypted document instead of a single
+string: kv (any fields), login (username, password, url, host, port,
+database, notes), tls (certificate, private_key, chain), ssh (private_key,
+public_key, passphrase, comment) or token (token, issuer, scopes,
+expires_at, notes). Fields come from --set, --set-file and --prompt
+(hidden) on top of an optional JSON object read as the value; they replace
+the whole document. --set is refused for passwords, private keys,
+passphrases, tokens and any kv field unless --insecure-arg is given. Certificates
+and keys are parsed and checked to match, an SSH public key is derived if
+missing, and unless --ttl is given a certificate's or token's own expiry
+becomes the secret's. Read single fields with get-secret --field.`,
+	Example: `  vault add-secret prod/api/token
+  vault add-secret prod/api/token --stdin < token.txt
+  vault add-secret prod/db/main --type login --set username=app --prompt password --set host=db.internal
+  vault add-secret prod/web/tls --type tls --set-file certificate=cert.pem --set-file private_key=key.pem`,
+	Args: cobra.RangeArgs(1, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := session.Require(); err != nil {
+		var value []string
+		// After a path, or a category and name, another argument can
+		// only be a value; storing it as part of the path would leak it
+		// into the unencrypted name and the audit log.
+		if addInsecureArg && len(args) > 1 {
+			args, value = args[:len(args)-1], args[len(args)-1:]
+		} else if len(args) == 3 || len(args) == 2 && strings.Contains(args[0], "/") {
+			return usageError{errors.New("a value on the command line is exposed in shell history and ps; " +
+				"omit it to be prompted, use --stdin or --from-file, or pass --insecure-arg")}
+		}
+		category, name, err := secretArgs(args)
+		if err != nil {
 			return err
 		}
-		s := secrets.Secret{Category: args[0], Name: args[1], Value: args[2]}
-		if err := secrets.Add(database, cfg, s); err != nil {
+		s := secrets.Secret{Category: category, Name: name, Type: addType}
+		if err := setExpiry(&s, addTTL, addRotateEvery); err != nil {
+			return usageError{err}
+		}
+		sources := 0
+		for _, given := range []bool{len(value) == 1, addStdin, addFromFile != ""} {
+			if given {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return usageError{errors.New("give the value only one way: --stdin, --from-file or an argument")}
+		}
+
+		// Unlock first so a piped master password is read before the value.
+		if agentClient() == nil {
+			if err := session.Require(); err != nil {
+				return err
+			}
+			if err := ensureUnlocked(); err != nil {
+				return err
+			}
+		}
+		hasValue := sources == 1
+		switch {
+		case len(value) == 1:
+			s.Value = value[0]
+		case addStdin:
+			b, err := auth.ReadStdin()
+			if err != nil {
+				return err
+			}
+			s.Value = strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+		case addFromFile != "":
+			b, err := os.ReadFile(addFromFile)
+			if err != nil {
+				return err
+			}
+			s.Value = string(b)
+		case s.Type == "":
+			v, err := promptSecret("value for " + secrets.Path(category, name))
+			if err != nil {
+				return err
+			}
+			s.Value, hasValue = v, true
+		}
+		if err := buildDocument(&s, hasValue); err != nil {
+			return usageError{err}
+		}
+		if err := storeSecret(s); err != nil {
 			return fmt.Errorf("add-secret: %w", err)
 		}
-		fmt.Println("Secret stored.")
+		note("Secret stored.")
 		return nil
 	},
 }
+
+// setExpiry applies --ttl and --rotate-every to s.
+func setExpiry(s *secrets.Secret, ttl, rotateEvery string) error {
+	if ttl != "" {
+		s.ExpiresAt = secrets.Never
+		if ttl != secrets.Never {
+			d, err := core.ParseDuration(ttl)
+			if err != nil {
+				return fmt.Errorf("--ttl: %w", err)
+			}
+			s.ExpiresAt = time.Now().Add(d).UTC().Format(time.RFC3339)
+		}
+	}
+	if rotateEvery != "" {
+		if rotateEvery != secrets.Never {
+			if _, err := core.ParseDuration(rotateEvery); err != nil {
+				return fmt.Errorf("--rotate-every: %w", err)
+			}
+		}
+		s.RotateEvery = rotateEvery
+	}
+	return nil
+}
+
+// storeSecret writes s through the agent when one is running, otherwise
+// directly, unlocking the vault if needed.
+func storeSecret(s secrets.Secret) error {
+	if c := agentClient(); c != nil {
+		return c.AddSecret(s)
+	}
+	if err := session.Require(); err != nil {
+		return err
+	}
+	if err := ensureUnlocked(); err != nil {
+		return err
+	}
+	return secrets.Add(database, cfg, s)
+}
+
+// buildDocument assembles a typed secret's value from --set and
+// --set-file on top of any JSON object given as the value.
+func buildDocument(s *secrets.Secret, hasValue bool) error {
+	if s.Type == "" {
+		if len(addSet) > 0 || len(addSetFile) > 0 || len(addPrompt) > 0 {
+			return fmt.Errorf("--set, --set-file and --prompt need --type")
+		}
+		if !hasValue {
+			return fmt.Errorf("value required")
+		}
+		return nil
+	}
+
+	doc := secrets.Document{}
+	if hasValue {
+		var err error
+		if doc, err = secrets.ParseDocument(s.Value); err != nil {
+			return fmt.Errorf("the value of a %s secret must be a JSON object of strings", s.Type)
+		}
+	}
+	for _, kv := range addSet {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("--set %q: want field=value", kv)
+		}
+		if secrets.Sensitive(s.Type, k) && !addInsecureArg {
+			return fmt.Errorf("--set %s would expose the %s in shell history and ps; use --prompt %s or --set-file %s=<file>, or pass --insecure-arg", k, k, k, k)
+		}
+		doc[k] = v
+	}
+	for _, kv := range addSetFile {
+		k, path, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("--set-file %q: want field=path", kv)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("--set-file %s: %w", k, err)
+		}
+		doc[k] = string(b)
+	}
+	for _, k := range addPrompt {
+		v, err := promptSecret(s.Type + " " + k + " for " + secrets.Path(s.Category, s.Name))
+		if err != nil {
+			return err
+		}
+		doc[k] = v
+	}
+	s.Value = doc.Encode()
+	return secrets.ValidateValue(*s)
+}
+
+// promptSecret asks for a value twice without echoing it.
+func promptSecret(what string) (string, error) {
+	v, err := auth.PromptPassword(strings.ToUpper(what[:1]) + what[1:] + ": ")
+	if err != nil {
+		return "", err
+	}
+	if v == "" {
+		return "", errors.New("empty value")
+	}
+	confirm, err := auth.PromptPassword("Confirm " + what + ": ")
+	if err != nil {
+		return "", err
+	}
+	if v != confirm {
+		return "", errors.New("entries do not match")
+	}
+	return v, nil
+}
+
+func init() {
+	addSecretCmd.Flags().BoolVar(&addStdin, "stdin", false, "read the value from standard input")
+	addSecretCmd.Flags().StringVar(&addFromFile, "from-file", "", "read the value from a file")
+	addSecretCmd.Flags().BoolVar(&addInsecureArg, "insecure-arg", false, "allow the value, or sensitive --set fields, on the command line")
+	addSecretCmd.Flags().StringArrayVar(&addPrompt, "prompt", nil, "ask for a field of a typed secret at a hidden prompt (repeatable)")
+	addSecretCmd.Flags().StringVar(&addTTL, "ttl", "", `expire the secret after this long (e.g. 90d), or "never"`)
+	addSecretCmd.Flags().StringVar(&addType, "type", "", "store a structured secret: "+strings.Join(secrets.Types, ", "))
+	addSecretCmd.Flags().StringArrayVar(&addSet, "set", nil, "set a field of a typed secret, field=value (repeatable)")
+	addSecretCmd.Flags().StringArrayVar(&addSetFile, "set-file", nil, "set a field of a typed secret from a file, field=path (repeatable)")
+	addSecretCmd.Flags().StringVar(&addRotateEvery, "rotate-every", "", `remind to rotate the value this often (e.g. 30d), or "never"`)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"vault-cli/internal/secrets"
+)
+
+func TestAddSecretRefusesValueArgument(t *testing.T) {
+	for _, args := range [][]string{
+		{"prod/api/token", "s3cr3t"},
+		{"prod", "token", "s3cr3t"},
+	} {
+		err := addSecretCmd.RunE(addSecretCmd, args)
+		if _, ok := err.(usageError); !ok || !strings.Contains(err.Error(), "--insecure-arg") {
+			t.Errorf("add-secret %q: got %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestSetRefusesSensitiveFields(t *testing.T) {
+	t.Cleanup(func() { addSet, addInsecureArg = nil, false })
+	for _, tc := range []struct {
+		typ, set string
+		ok       bool
+	}{
+		{secrets.TypeLogin, "username=app", true},
+		{secrets.TypeLogin, "password=hunter2", false},
+		{secrets.TypeTLS, "private_key=x", false},
+		{secrets.TypeSSH, "passphrase=x", false},
+		{secrets.TypeToken, "token=x", false},
+		{secrets.TypeKV, "anything=x", false},
+	} {
+		addSet = []string{tc.set}
+		s := secrets.Secret{Type: tc.typ}
+		err := buildDocument(&s, false)
+		if refused := err != nil && strings.Contains(err.Error(), "--prompt"); refused == tc.ok {
+			t.Errorf("%s --set %s: %v", tc.typ, tc.set, err)
+		}
+	}
+
+	addSet, addInsecureArg = []string{"password=hunter2", "username=app"}, true
+	s := secrets.Secret{Type: secrets.TypeLogin}
+	if err := buildDocument(&s, false); err != nil {
+		t.Fatalf("with --insecure-arg: %v", err)
+	}
+}
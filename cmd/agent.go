@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"vault-cli/internal/agent"
+	"vault-cli/internal/auth"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/output"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocket   string
+	agentIdle     time.Duration
+	agentDetach   bool
+	agentNoUnlock bool
+
+	agentConn    *agent.Client
+	agentChecked bool
+)
+
+// agentClient returns a client for a running, unlocked agent, or nil when
+// commands should fall back to talking to the database directly.
+func agentClient() *agent.Client {
+	if agentChecked {
+		return agentConn
+	}
+	agentChecked = true
+	c, err := agent.Dial(agent.SocketPath())
+	if err != nil {
+		return nil
+	}
+	st, err := c.Status()
+	if err != nil || st.Locked {
+		return nil
+	}
+	agentConn = c
+	return agentConn
+}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the background vault agent (holds unlocked keys for other commands)",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := startCommand(cmd); err != nil {
+			return err
+		}
+		return loadRuntime()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var password, code string
+		if !agentNoUnlock {
+			var err error
+			if password, code, err = promptUnlock(); err != nil {
+				return err
+			}
+		}
+
+		if agentDetach {
+			return startDetachedAgent(password, code)
+		}
+
+		a := agent.New(cfg, database, agentSocket, agentIdle)
+		if !agentNoUnlock {
+			if err := a.Unlock(password, code); err != nil {
+				return err
+			}
+		}
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			a.Stop()
+		}()
+
+		fmt.Fprintf(os.Stderr, "Vault agent listening on %s (pid %d)\n", agentSocket, os.Getpid())
+		return a.Serve()
+	},
+}
+
+// promptUnlock collects the master password (when required) and a TOTP
+// code (when enrolled) for unlocking the agent.
+func promptUnlock() (string, string, error) {
+	var password, code string
+	var err error
+	if cfg.RequirePassword || keyring.Initialized() {
+		if password, err = auth.PromptPassword("Enter master password: "); err != nil {
+			return "", "", err
+		}
+	}
+	enrolled, err := mfa.Enrolled(database, auth.MasterUser)
+	if err != nil {
+		return "", "", err
+	}
+	if enrolled {
+		if code, err = auth.PromptPassword("Enter authentication code: "); err != nil {
+			return "", "", err
+		}
+	}
+	return password, code, nil
+}
+
+func startDetachedAgent(password, code string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{"agent", "--socket", agentSocket, "--idle", agentIdle.String(), "--no-unlock"}
+	pid, err := spawnDetached(exe, args)
+	if err != nil {
+		return fmt.Errorf("start agent: %w", err)
+	}
+
+	var c *agent.Client
+	for i := 0; i < 50; i++ {
+		if c, err = agent.Dial(agentSocket); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if c == nil {
+		return fmt.Errorf("agent did not come up on %s: %w", agentSocket, err)
+	}
+	if err := c.Unlock(password, code); err != nil {
+		return err
+	}
+	fmt.Printf("Vault agent started (pid %d).\n", pid)
+	fmt.Printf("export VAULT_AGENT_SOCK=%s\n", agentSocket)
+	return nil
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the agent is running and unlocked",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type status struct {
+			Running bool `json:"running"`
+			*agent.Status
+		}
+		c, err := agent.Dial(agentSocket)
+		if err != nil {
+			return render(status{}, output.Rows{Header: []string{"key", "value"}, Rows: [][]string{{"running", "false"}}})
+		}
+		st, err := c.Status()
+		if err != nil {
+			return err
+		}
+		return render(status{true, st}, output.Rows{Header: []string{"key", "value"}, Rows: [][]string{
+			{"running", "true"},
+			{"pid", strconv.Itoa(st.PID)},
+			{"locked", strconv.FormatBool(st.Locked)},
+			{"mode", st.Mode},
+			{"idle_timeout", st.IdleTimeout},
+			{"last_activity", st.LastActivity.Format(time.RFC3339)},
+		}})
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock the agent and drop cached key material",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := agent.Dial(agentSocket)
+		if err != nil {
+			return fmt.Errorf("agent not running: %w", err)
+		}
+		if err := c.Lock(); err != nil {
+			return err
+		}
+		note("Agent locked.")
+		return nil
+	},
+}
+
+var agentUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Unlock a running agent with the master password",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := agent.Dial(agentSocket)
+		if err != nil {
+			return fmt.Errorf("agent not running: %w", err)
+		}
+		password, code, err := promptUnlock()
+		if err != nil {
+			return err
+		}
+		if err := c.Unlock(password, code); err != nil {
+			return err
+		}
+		note("Agent unlocked.")
+		return nil
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running agent",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := agent.Dial(agentSocket)
+		if err != nil {
+			return fmt.Errorf("agent not running: %w", err)
+		}
+		if err := c.Stop(); err != nil {
+			return err
+		}
+		note("Agent stopped.")
+		return nil
+	},
+}
+
+func init() {
+	agentCmd.PersistentFlags().StringVar(&agentSocket, "socket", agent.SocketPath(), "unix socket path for the agent")
+	agentCmd.Flags().DurationVar(&agentIdle, "idle", 15*time.Minute, "lock the agent after this much inactivity (0 disables)")
+	agentCmd.Flags().BoolVar(&agentDetach, "detach", false, "run the agent in the background")
+	agentCmd.Flags().BoolVar(&agentNoUnlock, "no-unlock", false, "start the agent locked")
+	_ = agentCmd.Flags().MarkHidden("no-unlock")
+
+	agentCmd.AddCommand(agentStatusCmd, agentLockCmd, agentUnlockCmd, agentStopCmd)
+	rootCmd.AddCommand(agentCmd)
+}
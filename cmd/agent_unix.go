@@ -0,0 +1,24 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func spawnDetached(exe string, args []string) (int, error) {
+	c := exec.Command(exe, args...)
+	c.Stdin = nil
+	c.Stdout = nil
+	c.Stderr = nil
+	c.Env = os.Environ()
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+	pid := c.Process.Pid
+	_ = c.Process.Release()
+	return pid, nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func spawnDetached(exe string, args []string) (int, error) {
+	c := exec.Command(exe, args...)
+	c.Env = os.Environ()
+	c.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+	pid := c.Process.Pid
+	_ = c.Process.Release()
+	return pid, nil
+}
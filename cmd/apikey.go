@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"vault-cli/internal/apikey"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	apikeyName   string
+	apikeyScopes []string
+	apikeyTTL    string
+)
+
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys for machine clients of the web server",
+}
+
+var apikeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a scoped API key (the token is shown once)",
+	Example: `  vault apikey create --name ci --scope read:prod/* --ttl 30d
+  vault apikey create --name backup --scope download:* --scope audit:*`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		var ttl time.Duration
+		if apikeyTTL != "" && apikeyTTL != "0" {
+			var err error
+			if ttl, err = core.ParseDuration(apikeyTTL); err != nil {
+				return err
+			}
+		}
+		token, k, err := apikey.Create(database, apikeyName, apikeyScopes, ttl)
+		if err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "apikey:create", k.ID, strings.Join(k.Scopes, " "), true, "")
+
+		note("API key %s created. Send it as 'Authorization: Bearer <token>'; it cannot be shown again.", k.ID)
+		expires := "never"
+		if k.ExpiresAt != nil {
+			expires = k.ExpiresAt.Local().Format(time.RFC3339)
+		}
+		return render(struct {
+			*apikey.Key
+			Token string `json:"token"`
+		}{k, token}, output.Rows{Header: []string{"key", "value"}, Rows: [][]string{
+			{"id", k.ID},
+			{"name", k.Name},
+			{"scopes", strings.Join(k.Scopes, ",")},
+			{"expires_at", expires},
+			{"token", token},
+		}})
+	},
+}
+
+var apikeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		items, err := apikey.List(database)
+		if err != nil {
+			return err
+		}
+		type listed struct {
+			apikey.Key
+			State string `json:"state"`
+		}
+		keys := []listed{}
+		rows := output.Rows{Header: []string{"id", "name", "state", "scopes", "expires_at", "last_used_at"}}
+		for _, k := range items {
+			state := "active"
+			switch {
+			case k.RevokedAt != nil:
+				state = "revoked"
+			case k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt):
+				state = "expired"
+			}
+			expires, used := "never", "never"
+			if k.ExpiresAt != nil {
+				expires = k.ExpiresAt.Local().Format(time.RFC3339)
+			}
+			if k.LastUsedAt != nil {
+				used = k.LastUsedAt.Local().Format(time.RFC3339)
+			}
+			keys = append(keys, listed{k, state})
+			rows.Rows = append(rows.Rows, []string{k.ID, k.Name, state, strings.Join(k.Scopes, ","), expires, used})
+		}
+		return render(keys, rows)
+	},
+}
+
+var apikeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		if err := apikey.Revoke(database, args[0]); err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "apikey:revoke", args[0], "cli", true, "")
+		note("API key %s revoked.", args[0])
+		return nil
+	},
+}
+
+func init() {
+	apikeyCreateCmd.Flags().StringVar(&apikeyName, "name", "", "label for the key")
+	apikeyCreateCmd.Flags().StringArrayVar(&apikeyScopes, "scope", nil, "scope <action>:<pattern>, repeatable (actions: read, write, delete, upload, download, audit, *)")
+	apikeyCreateCmd.Flags().StringVar(&apikeyTTL, "ttl", "90d", "lifetime, e.g. 30d or 12h (0 for no expiry)")
+	_ = apikeyCreateCmd.MarkFlagRequired("name")
+	_ = apikeyCreateCmd.MarkFlagRequired("scope")
+
+	apikeyCmd.AddCommand(apikeyCreateCmd, apikeyListCmd, apikeyRevokeCmd)
+	rootCmd.AddCommand(apikeyCmd)
+}
@@ -1,23 +1,39 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
+	"strconv"
 
 	"vault-cli/internal/db"
+	"vault-cli/internal/output"
 
 	"github.com/spf13/cobra"
 )
 
+var auditLimit int
+
 var auditCmd = &cobra.Command{
 	Use:   "audit",
 	Short: "Show recent upload/download audit logs",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := db.PrintAudit(database); err != nil {
-			log.Fatalf("audit: %v", err)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		items, err := db.ListAudit(database, auditLimit)
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+		if items == nil {
+			items = []db.AuditRecord{}
+		}
+		rows := output.Rows{Header: []string{"id", "timestamp", "action", "filename", "target", "success", "error"}}
+		for _, a := range items {
+			rows.Rows = append(rows.Rows, []string{
+				strconv.Itoa(a.ID), a.TS, a.Action, a.Filename, a.Target, strconv.FormatBool(a.Success), a.Error,
+			})
 		}
+		return render(items, rows)
 	},
 }
 
 func init() {
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 200, "number of most recent events to show")
 	rootCmd.AddCommand(auditCmd)
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/backup"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupOut       string
+	backupRecipient string
+	backupFiles     bool
+	keygenOut       string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup --out <file> [--recipient <public key>] [--include-files]",
+	Short: "Write an encrypted, portable backup of secrets, file records and the audit log",
+	Long: `Writes the secrets, files and audit tables to a single encrypted archive.
+Secret values are stored decrypted inside it, so the backup can be restored
+into any vault and holds none of this vault's keys.
+
+The archive is protected by a backup passphrase you choose, or with
+--recipient by the public key from 'vault backup keygen' (restoring then
+needs the matching identity file). --include-files also stores files kept
+in the local directory (VAULT_REMOTE_PATH); files in S3 are not copied.
+
+The master password (and MFA code, if enrolled) is always asked for again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if backupOut == "" {
+			return usageError{errors.New("backup: --out is required")}
+		}
+		var recipient *[32]byte
+		if backupRecipient != "" {
+			var err error
+			if recipient, err = backup.ParsePublic(backupRecipient); err != nil {
+				return usageError{err}
+			}
+		}
+		if err := reauthenticate(); err != nil {
+			return err
+		}
+
+		a, missing, err := backup.Collect(database, cfg, backupFiles)
+		if err != nil {
+			return err
+		}
+		for _, name := range missing {
+			warn("Warning: %s is recorded as a local file but is not on disk; skipped.", name)
+		}
+
+		var pass string
+		target := "recipient"
+		if recipient == nil {
+			target = "passphrase"
+			if pass, err = auth.PromptNew("backup passphrase"); err != nil {
+				return err
+			}
+		}
+		err = core.WriteAtomic(backupOut, 0600, func(w io.Writer) error {
+			return backup.Write(w, a, pass, recipient)
+		})
+		if err != nil {
+			_ = db.RecordAudit(database, "backup", backupOut, target, false, err.Error())
+			return fmt.Errorf("backup: %w", err)
+		}
+		_ = db.RecordAudit(database, "backup", backupOut, target, true, "")
+		note("Backed up %d secrets, %d file records, %d audit entries and %d local files to %s.",
+			len(a.Secrets), len(a.Files), len(a.Audit), len(a.Manifest.Blobs), backupOut)
+		return nil
+	},
+}
+
+var backupKeygenCmd = &cobra.Command{
+	Use:   "keygen --out <identity file>",
+	Short: "Create a key pair for recipient-encrypted backups",
+	Long: `Writes a new identity (private key) to --out with mode 0600 and prints its
+public key. Pass the public key to 'vault backup --recipient' on any
+machine; only the identity file can restore those backups, so keep it
+somewhere other than the vault it protects.`,
+	Args: cobra.NoArgs,
+	// No vault is needed to make a key.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return startCommand(cmd) },
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if keygenOut == "" {
+			return usageError{errors.New("keygen: --out is required")}
+		}
+		if _, err := os.Lstat(keygenOut); err == nil {
+			return fmt.Errorf("%s already exists", keygenOut)
+		}
+		id, err := backup.GenerateIdentity()
+		if err != nil {
+			return err
+		}
+		if err := core.WriteFileAtomic(keygenOut, id.Marshal(), 0600); err != nil {
+			return err
+		}
+		fmt.Println(backup.EncodePublic(&id.Public))
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&backupOut, "out", "", "archive to write (mode 0600)")
+	backupCmd.Flags().StringVar(&backupRecipient, "recipient", "", "encrypt to this public key instead of a passphrase")
+	backupCmd.Flags().BoolVar(&backupFiles, "include-files", false, "include files stored in the local directory")
+	backupKeygenCmd.Flags().StringVar(&keygenOut, "out", "", "identity file to create")
+	backupCmd.AddCommand(backupKeygenCmd)
+	rootCmd.AddCommand(backupCmd)
+}
@@ -8,13 +8,20 @@ import (
 )
 
 var deleteSecretCmd = &cobra.Command{
-	Use:   "delete-secret <category> <name>",
-	Short: "Delete a secret",
-	Args:  cobra.ExactArgs(2),
+	Use:   "delete-secret <path>",
+	Short: "Delete a secret (see secret rm for whole subtrees)",
+	Args:  cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		category, name, err := secretArgs(args)
+		if err != nil {
+			return err
+		}
+		if c := agentClient(); c != nil {
+			return c.DeleteSecret(category, name)
+		}
 		if err := session.Require(); err != nil {
 			return err
 		}
-		return secrets.Delete(database, args[0], args[1])
+		return secrets.Delete(database, category, name)
 	},
 }
@@ -2,27 +2,41 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"vault-cli/internal/core"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	downloadOutput string
+	downloadForce  bool
+)
+
 var downloadCmd = &cobra.Command{
 	Use:   "download <file>",
 	Short: "Download and decrypt a file from AWS S3 or the local vault",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		file := args[0]
-		if err := core.DownloadHandler(file, cfg, database); err != nil {
-			log.Fatalf("Download failed: %v", err)
+		if err := core.DownloadHandler(file, downloadOutput, downloadForce, quiet, cfg, database); err != nil {
+			return fmt.Errorf("download failed: %w", err)
 		}
 
-		fmt.Println("File downloaded successfully.")
+		if downloadOutput != "-" {
+			note("File downloaded successfully.")
+		}
+		return nil
 	},
 }
 
 func init() {
+	downloadCmd.Flags().StringVarP(&downloadOutput, "out", "O", "", "write plaintext to this path, or - for stdout (default decrypted_<file>)")
+	// download took the file as --output/-o before -o became the global
+	// format flag. It prints nothing to format, so the old spelling keeps
+	// working here for existing scripts.
+	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", "", "")
+	_ = downloadCmd.Flags().MarkDeprecated("output", "use --out/-O")
+	downloadCmd.Flags().BoolVar(&downloadForce, "force", false, "overwrite the output file if it exists")
 	rootCmd.AddCommand(downloadCmd)
 }
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+
+	"vault-cli/internal/output"
+)
+
+func TestDownloadKeepsOldOutputFlag(t *testing.T) {
+	t.Cleanup(func() { downloadOutput, outputFlag = "", string(output.Table) })
+	for _, args := range [][]string{{"-O", "a.txt"}, {"--out", "a.txt"}, {"-o", "a.txt"}, {"--output", "a.txt"}} {
+		downloadOutput, outputFlag = "", string(output.Table)
+		if err := downloadCmd.ParseFlags(args); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if downloadOutput != "a.txt" || outputFlag != string(output.Table) {
+			t.Errorf("%q: out = %q, format = %q", args, downloadOutput, outputFlag)
+		}
+	}
+}
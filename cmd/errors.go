@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"vault-cli/internal/agent"
+	"vault-cli/internal/apikey"
+	"vault-cli/internal/auth"
+	"vault-cli/internal/aws"
+	"vault-cli/internal/backup"
+	"vault-cli/internal/bundle"
+	"vault-cli/internal/clipboard"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+	"vault-cli/internal/stream"
+)
+
+// Exit codes are part of the CLI's interface; see "exit codes" in the
+// readme before changing them.
+const (
+	ExitOK           = 0
+	ExitError        = 1
+	ExitUsage        = 2
+	ExitNotFound     = 3
+	ExitUnauthorized = 4
+	ExitIntegrity    = 5
+	ExitUnavailable  = 6
+	ExitExpired      = 7
+)
+
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// ExitCode maps err onto the documented process exit status.
+func ExitCode(err error) int {
+	var usage usageError
+	var status exitStatus
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, &usage),
+		errors.Is(err, secrets.ErrInvalidPath):
+		return ExitUsage
+	case errors.Is(err, auth.ErrUnauthorized),
+		errors.Is(err, session.ErrNoSession),
+		errors.Is(err, session.ErrExpired),
+		errors.Is(err, keyring.ErrLocked),
+		errors.Is(err, keyring.ErrWrongPassword),
+		errors.Is(err, agent.ErrLocked),
+		errors.Is(err, mfa.ErrCodeRequired),
+		errors.Is(err, mfa.ErrInvalidCode),
+		errors.Is(err, bundle.ErrPassphrase),
+		errors.Is(err, backup.ErrIdentity):
+		return ExitUnauthorized
+	case errors.Is(err, secrets.ErrNotFound),
+		errors.Is(err, secrets.ErrNoPolicy),
+		errors.Is(err, aws.ErrNotFound),
+		errors.Is(err, apikey.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, secrets.ErrIntegrity),
+		errors.Is(err, aws.ErrIntegrity),
+		errors.Is(err, stream.ErrAuth),
+		errors.Is(err, stream.ErrFormat):
+		return ExitIntegrity
+	case errors.Is(err, aws.ErrBackendUnavailable),
+		errors.Is(err, clipboard.ErrUnavailable):
+		return ExitUnavailable
+	case errors.Is(err, secrets.ErrExpired):
+		return ExitExpired
+	}
+	return ExitError
+}
+
+// markUsageErrors tags argument and flag errors so they exit with
+// ExitUsage rather than the generic failure code.
+func markUsageErrors(c *cobra.Command) {
+	if args := c.Args; args != nil {
+		c.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return usageError{err}
+			}
+			return nil
+		}
+	}
+	c.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+	for _, sub := range c.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"vault-cli/internal/agent"
+	"vault-cli/internal/apikey"
+	"vault-cli/internal/auth"
+	"vault-cli/internal/aws"
+	"vault-cli/internal/backup"
+	"vault-cli/internal/bundle"
+	"vault-cli/internal/clipboard"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+	"vault-cli/internal/stream"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitError},
+		{usageError{errors.New("bad flag")}, ExitUsage},
+		{secrets.ErrInvalidPath, ExitUsage},
+		{auth.ErrUnauthorized, ExitUnauthorized},
+		{auth.ErrInvalidPassword, ExitUnauthorized},
+		{&auth.LockoutError{}, ExitUnauthorized},
+		{session.ErrNoSession, ExitUnauthorized},
+		{session.ErrExpired, ExitUnauthorized},
+		{keyring.ErrLocked, ExitUnauthorized},
+		{keyring.ErrWrongPassword, ExitUnauthorized},
+		{agent.ErrLocked, ExitUnauthorized},
+		{mfa.ErrCodeRequired, ExitUnauthorized},
+		{mfa.ErrInvalidCode, ExitUnauthorized},
+		{bundle.ErrPassphrase, ExitUnauthorized},
+		{backup.ErrIdentity, ExitUnauthorized},
+		{secrets.ErrNotFound, ExitNotFound},
+		{secrets.ErrNoPolicy, ExitNotFound},
+		{aws.ErrNotFound, ExitNotFound},
+		{apikey.ErrNotFound, ExitNotFound},
+		{secrets.ErrIntegrity, ExitIntegrity},
+		{aws.ErrIntegrity, ExitIntegrity},
+		{stream.ErrAuth, ExitIntegrity},
+		{stream.ErrFormat, ExitIntegrity},
+		{aws.ErrBackendUnavailable, ExitUnavailable},
+		{clipboard.ErrUnavailable, ExitUnavailable},
+		{secrets.ErrExpired, ExitExpired},
+		{exitStatus(0), 0},
+		{exitStatus(42), 42},
+		{exitStatus(130), 130},
+	} {
+		if got := ExitCode(tc.err); got != tc.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+		if tc.err == nil {
+			continue
+		}
+		wrapped := fmt.Errorf("get-secret: %w", fmt.Errorf("prod/db: %w", tc.err))
+		if got := ExitCode(wrapped); got != tc.want {
+			t.Errorf("ExitCode(wrapped %v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/bundle"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportCategory  string
+	exportFormat    string
+	exportOutput    string
+	exportPlaintext bool
+)
+
+// bundlePayload is what an encrypted export carries, so import can restore
+// the category and parse the data without being told. Secrets holds the
+// full records, with type, expiry, rotation period and metadata; Data is
+// the flat document, which bundles from older versions carry alone.
+type bundlePayload struct {
+	Category string           `json:"category"`
+	Format   string           `json:"format"`
+	Data     string           `json:"data"`
+	Secrets  []secrets.Secret `json:"secrets,omitempty"`
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export --category <cat> [--format dotenv|json|yaml] [--plaintext] [-O file]",
+	Short: "Export a category of secrets, encrypted under a passphrase by default",
+	Long: `Exports every secret in a category. The master password (and MFA code, if
+enrolled) is always asked for again, even inside a session.
+
+By default the output is a bundle encrypted under a passphrase you choose,
+which 'vault import' can read back. --plaintext writes the dotenv, JSON or
+YAML document itself. Files written with -O get mode 0600.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportCategory == "" {
+			return usageError{errors.New("export: --category is required")}
+		}
+		if _, err := secrets.Format(exportFormat, nil); err != nil {
+			return usageError{err}
+		}
+		if err := reauthenticate(); err != nil {
+			return err
+		}
+
+		items, err := secrets.List(database, exportCategory)
+		if err != nil {
+			return err
+		}
+		// Only the category itself: formats key secrets by name, so
+		// ones further down the tree could clash.
+		items = slices.DeleteFunc(items, func(s secrets.Secret) bool { return s.Category != exportCategory })
+		if len(items) == 0 {
+			return fmt.Errorf("%w: no secrets in category %s", secrets.ErrNotFound, exportCategory)
+		}
+		target := "bundle"
+		if exportPlaintext {
+			target = "plaintext"
+		}
+		// Expired secrets go into the export too: their expiry travels
+		// with them, and one stale entry shouldn't abort the whole export.
+		for i := range items {
+			items[i].Value, err = secrets.GetIncludingExpired(database, cfg, items[i].Category, items[i].Name)
+			name := secrets.Path(items[i].Category, items[i].Name)
+			if err != nil {
+				_ = db.RecordAudit(database, "secret:export", name, target, false, err.Error())
+				return err
+			}
+			_ = db.RecordAudit(database, "secret:export", name, target, true, "")
+		}
+		out, err := secrets.Format(exportFormat, items)
+		if err != nil {
+			return err
+		}
+
+		if !exportPlaintext {
+			pass, err := auth.PromptNew("bundle passphrase")
+			if err != nil {
+				return err
+			}
+			payload, err := json.Marshal(bundlePayload{Category: exportCategory, Format: exportFormat, Data: string(out), Secrets: bundleSecrets(items)})
+			if err != nil {
+				return err
+			}
+			if out, err = bundle.Seal(payload, pass); err != nil {
+				return err
+			}
+		}
+
+		if exportOutput == "" || exportOutput == "-" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		if err := core.WriteFileAtomic(exportOutput, out, 0600); err != nil {
+			return err
+		}
+		note("Exported %d secrets to %s.", len(items), exportOutput)
+		return nil
+	},
+}
+
+// bundleSecrets strips what a bundle shouldn't carry: where the secret
+// lived, and values derived on read such as a policy's rotation period.
+func bundleSecrets(items []secrets.Secret) []secrets.Secret {
+	out := make([]secrets.Secret, len(items))
+	for i, s := range items {
+		s.Path, s.Category, s.CreatedAt, s.UpdatedAt, s.RotateDue = "", "", "", "", ""
+		if s.RotatePolicy != "" {
+			s.RotateEvery, s.RotatePolicy = "", ""
+		}
+		out[i] = s
+	}
+	return out
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportCategory, "category", "", "category to export")
+	exportCmd.Flags().StringVar(&exportFormat, "format", secrets.FormatDotenv, "dotenv, json or yaml")
+	exportCmd.Flags().StringVarP(&exportOutput, "out", "O", "", "write to this file (mode 0600) instead of stdout")
+	exportCmd.Flags().BoolVar(&exportPlaintext, "plaintext", false, "write unencrypted secrets instead of a passphrase-protected bundle")
+	rootCmd.AddCommand(exportCmd)
+}
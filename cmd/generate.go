@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"vault-cli/internal/generate"
+	"vault-cli/internal/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	genPolicy      string
+	genLength      int
+	genClasses     []string
+	genNoAmbiguous bool
+	genWords       int
+	genSeparator   string
+	genBytes       int
+	genBits        int
+	genComment     string
+	genShow        bool
+	genTTL         string
+	genRotateEvery string
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate <path>",
+	Short: "Generate a random password, passphrase, token or key pair and store it",
+	Long: `Generates a value and stores it as a secret, replacing any existing one.
+The value is never printed unless --show is given; key pairs print their
+public key.
+
+Policies:
+  password    --length characters (default 24) drawn from --classes
+              (lower, upper, digits, symbols; default all), at least one
+              from each; --no-ambiguous drops Il1O0o
+  passphrase  --words words (default 6) from a built-in list of 1506,
+              joined by --separator (default -)
+  hex         --bytes random bytes (default 32), hex encoded
+  base64      --bytes random bytes (default 32), base64 encoded
+  ed25519     OpenSSH key pair, stored as an ssh secret
+  rsa         --bits (default 3072) OpenSSH key pair, stored as an ssh secret`,
+	Example: `  vault generate prod/db/password --length 32 --rotate-every 90d
+  vault generate prod/app/session-key --policy hex --bytes 64
+  vault generate ops/deploy-key --policy ed25519 --comment deploy@ci`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		category, name, err := secretArgs(args)
+		if err != nil {
+			return err
+		}
+		res, err := generate.Generate(generate.Policy{
+			Kind:        genPolicy,
+			Length:      genLength,
+			Classes:     genClasses,
+			NoAmbiguous: genNoAmbiguous,
+			Words:       genWords,
+			Separator:   genSeparator,
+			Bytes:       genBytes,
+			Bits:        genBits,
+			Comment:     genComment,
+		})
+		if err != nil {
+			return usageError{err}
+		}
+
+		s := secrets.Secret{Category: category, Name: name, Type: res.Type, Value: res.Value}
+		if err := setExpiry(&s, genTTL, genRotateEvery); err != nil {
+			return usageError{err}
+		}
+		if err := storeSecret(s); err != nil {
+			return fmt.Errorf("generate: %w", err)
+		}
+
+		if res.Public != "" {
+			note("Stored %s: %s key pair.", secrets.Path(category, name), genPolicy)
+			fmt.Println(res.Public)
+		} else {
+			note("Stored %s: %s, ~%.0f bits.", secrets.Path(category, name), genPolicy, res.Entropy)
+		}
+		if genShow {
+			fmt.Println(res.Value)
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := generateCmd.Flags()
+	f.StringVar(&genPolicy, "policy", generate.Password, "what to generate: "+strings.Join(generate.Policies, ", "))
+	f.IntVar(&genLength, "length", 0, "password length (default 24)")
+	f.StringSliceVar(&genClasses, "classes", nil, "password character classes: lower, upper, digits, symbols (default all)")
+	f.BoolVar(&genNoAmbiguous, "no-ambiguous", false, "leave out characters that are easy to misread (Il1O0o)")
+	f.IntVar(&genWords, "words", 0, "passphrase word count (default 6)")
+	f.StringVar(&genSeparator, "separator", "", "passphrase word separator (default -)")
+	f.IntVar(&genBytes, "bytes", 0, "random byte count for hex and base64 (default 32)")
+	f.IntVar(&genBits, "bits", 0, "RSA key size (default 3072)")
+	f.StringVar(&genComment, "comment", "", "key pair comment")
+	f.BoolVar(&genShow, "show", false, "print the generated value")
+	f.StringVar(&genTTL, "ttl", "", `expire the secret after this long (e.g. 90d), or "never"`)
+	f.StringVar(&genRotateEvery, "rotate-every", "", `remind to rotate the value this often (e.g. 30d), or "never"`)
+	rootCmd.AddCommand(generateCmd)
+}
@@ -2,26 +2,157 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
+	"vault-cli/internal/clipboard"
+	"vault-cli/internal/config"
+	"vault-cli/internal/output"
 	"vault-cli/internal/secrets"
 	"vault-cli/internal/session"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	allowExpired bool
+	getField     string
+	clip         bool
+	clipTimeout  time.Duration
+)
+
 var getSecretCmd = &cobra.Command{
-	Use:   "get-secret <category> <name>",
+	Use:   "get-secret <path>",
 	Short: "Retrieve and decrypt a secret",
-	Args:  cobra.ExactArgs(2),
+	Long: `Decrypts and prints a secret. Expired secrets are refused unless
+--allow-expired is given; secrets overdue for rotation are printed with a
+warning on stderr.
+
+--field prints a single field of a typed secret (see add-secret --type);
+without it a typed secret's whole document is printed as JSON.
+
+--clip copies the value (or field) to the clipboard instead of printing
+it, then waits and clears the clipboard after --clip-timeout (default
+VAULT_CLIP_TIMEOUT or 45s; 0 leaves it). Ctrl-C clears it early.`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := session.Require(); err != nil {
+		category, name, err := secretArgs(args)
+		if err != nil {
 			return err
 		}
-		val, err := secrets.Get(database, cfg, args[0], args[1])
+		path := secrets.Path(category, name)
+		var val string
+		var meta secrets.Secret
+		if c := agentClient(); c != nil {
+			if allowExpired {
+				val, err = c.GetSecretIncludingExpired(category, name)
+			} else {
+				val, err = c.GetSecret(category, name)
+			}
+		} else {
+			if err := session.Require(); err != nil {
+				return err
+			}
+			if err := ensureUnlocked(); err != nil {
+				return err
+			}
+			if allowExpired {
+				val, err = secrets.GetIncludingExpired(database, cfg, category, name)
+			} else {
+				val, err = secrets.Get(database, cfg, category, name)
+			}
+		}
+		if err == nil {
+			meta, err = secrets.Lookup(database, category, name)
+		}
 		if err != nil {
 			return err
 		}
-		fmt.Printf("%s = %s\n", args[1], val)
-		return nil
+		switch meta.Status(time.Now()) {
+		case "expired":
+			warn("Warning: %s expired at %s.", path, meta.ExpiresAt)
+		case "rotation overdue":
+			warn("Warning: %s was due for rotation at %s.", path, meta.RotateDue)
+		}
+
+		if getField != "" {
+			v, err := secrets.GetField(val, getField)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if clip {
+				return clipValue(path+" "+getField, v, cmd.Flags().Changed("clip-timeout"))
+			}
+			if format == output.Plain {
+				fmt.Println(v)
+				return nil
+			}
+			return render(map[string]string{"path": path, "category": category, "name": name, "field": getField, "value": v}, output.Rows{
+				Header: []string{"path", "field", "value"},
+				Rows:   [][]string{{path, getField, v}},
+			})
+		}
+		if clip {
+			return clipValue(path, val, cmd.Flags().Changed("clip-timeout"))
+		}
+		if format == output.Plain {
+			fmt.Println(val)
+			return nil
+		}
+		if doc, err := secrets.ParseDocument(val); err == nil && meta.Type != "" {
+			rows := output.Rows{Header: []string{"field", "value"}}
+			for _, k := range slices.Sorted(maps.Keys(doc)) {
+				rows.Rows = append(rows.Rows, []string{k, doc[k]})
+			}
+			meta.Path, meta.Category, meta.Name, meta.Value = path, category, name, val
+			return render(meta, rows)
+		}
+		meta.Path, meta.Category, meta.Name, meta.Value = path, category, name, val
+		return render(meta, output.Rows{
+			Header: []string{"path", "value"},
+			Rows:   [][]string{{path, val}},
+		})
 	},
 }
+
+// clipValue copies value to the clipboard and, unless the timeout is
+// zero, waits to clear it again.
+func clipValue(what, value string, timeoutSet bool) error {
+	timeout := cfg.ClipTimeout
+	if timeoutSet {
+		timeout = clipTimeout
+	}
+	c, err := clipboard.Copy(value)
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		note("Copied %s to the clipboard.", what)
+		return nil
+	}
+	note("Copied %s to the clipboard; clearing in %s (Ctrl-C clears now).", what, timeout)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	select {
+	case <-time.After(timeout):
+	case <-sig:
+	}
+	if err := c.Clear(); err != nil {
+		return fmt.Errorf("clear clipboard: %w", err)
+	}
+	note("Clipboard cleared.")
+	return nil
+}
+
+func init() {
+	getSecretCmd.Flags().BoolVar(&clip, "clip", false, "copy the value to the clipboard instead of printing it")
+	getSecretCmd.Flags().DurationVar(&clipTimeout, "clip-timeout", config.DefaultClipTimeout, "clear the clipboard after this long; 0 leaves it")
+	getSecretCmd.Flags().BoolVar(&allowExpired, "allow-expired", false, "return the value even if the secret has expired")
+	getSecretCmd.Flags().StringVar(&getField, "field", "", "print one field of a typed secret")
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the master password and vault key material",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := startCommand(cmd); err != nil {
+			return err
+		}
+		return loadRuntime()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(cfg.PasswordFile); err == nil {
+			return fmt.Errorf("%s already exists; use 'vault passwd' to change the password", cfg.PasswordFile)
+		}
+		if keyring.Initialized() {
+			return keyring.ErrExists
+		}
+
+		pw, err := auth.PromptNewPassword()
+		if err != nil {
+			return err
+		}
+		hash, err := auth.HashPassword(pw)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.PasswordFile), 0700); err != nil {
+			return err
+		}
+		if err := core.WriteFileAtomic(cfg.PasswordFile, hash, 0600); err != nil {
+			return fmt.Errorf("write password file: %w", err)
+		}
+		if err := keyring.Create(database, pw); err != nil {
+			_ = os.Remove(cfg.PasswordFile)
+			return fmt.Errorf("create keyring: %w", err)
+		}
+		_ = db.RecordAudit(database, "init", cfg.PasswordFile, "keyring", true, "")
+
+		fmt.Printf("Master password written to %s.\n", cfg.PasswordFile)
+		fmt.Println("Vault key material created.")
+
+		if cfg.Mode == "local" {
+			n, err := secrets.Rotate(database, cfg)
+			if err != nil {
+				return fmt.Errorf("wrap existing secrets: %w", err)
+			}
+			if n > 0 {
+				fmt.Printf("Re-wrapped %d existing secrets under the new key.\n", n)
+			}
+		}
+
+		fmt.Println()
+		fmt.Println("To require the password for every command, set:")
+		fmt.Println("  VAULT_REQUIRE_PASSWORD=1")
+		fmt.Printf("  VAULT_PASS_FILE=%s\n", cfg.PasswordFile)
+		return nil
+	},
+}
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd",
+	Short: "Change the master password and re-wrap the vault key",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := startCommand(cmd); err != nil {
+			return err
+		}
+		return loadRuntime()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldHash, err := os.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("read password file: %w (run 'vault init' first)", err)
+		}
+
+		oldPw, err := loginWith("Current master password: ")
+		if err != nil {
+			return err
+		}
+		newPw, err := auth.PromptNewPassword()
+		if err != nil {
+			return err
+		}
+		newHash, err := auth.HashPassword(newPw)
+		if err != nil {
+			return err
+		}
+
+		tx, err := database.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if keyring.Initialized() {
+			if err := keyring.Rewrap(tx, oldPw, newPw); err != nil {
+				return fmt.Errorf("re-wrap keyring: %w", err)
+			}
+		}
+		if err := core.WriteFileAtomic(cfg.PasswordFile, newHash, 0600); err != nil {
+			return fmt.Errorf("write password file: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			_ = core.WriteFileAtomic(cfg.PasswordFile, oldHash, 0600)
+			return fmt.Errorf("commit keyring: %w", err)
+		}
+
+		_ = db.RecordAudit(database, "passwd", cfg.PasswordFile, "keyring", true, "")
+		fmt.Println("Master password changed.")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd, passwdCmd)
+}
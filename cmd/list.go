@@ -1,9 +1,11 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
+	"strconv"
 
 	"vault-cli/internal/db"
+	"vault-cli/internal/output"
 
 	"github.com/spf13/cobra"
 )
@@ -11,10 +13,21 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List stored file metadata from local database",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := db.PrintDBEntries(database); err != nil {
-			log.Fatalf("list: %v", err)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		items, err := db.ListFiles(database)
+		if err != nil {
+			return fmt.Errorf("list: %w", err)
 		}
+		if items == nil {
+			items = []db.FileRecord{}
+		}
+		rows := output.Rows{Header: []string{"id", "filename", "uploaded_at", "hash", "size", "location", "mode"}}
+		for _, f := range items {
+			rows.Rows = append(rows.Rows, []string{
+				strconv.Itoa(f.ID), f.Filename, f.Uploaded, f.Hash, strconv.FormatInt(f.Size, 10), f.Location, f.Mode,
+			})
+		}
+		return render(items, rows)
 	},
 }
 
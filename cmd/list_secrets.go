@@ -2,33 +2,153 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"vault-cli/internal/core"
+	"vault-cli/internal/output"
 	"vault-cli/internal/secrets"
 	"vault-cli/internal/session"
 
 	"github.com/spf13/cobra"
 )
 
-var cat string
+var (
+	cat      string
+	expiring string
+	withTags []string
+	asTree   bool
+)
 
 var listSecretsCmd = &cobra.Command{
-	Use:   "list-secrets",
-	Short: "List stored secrets (optionally filter by category)",
+	Use:   "list-secrets [prefix]",
+	Short: "List stored secrets, optionally only those below a path prefix",
+	Long: `Lists secrets without their values. A prefix such as prod/payments
+limits the list to that subtree; --tree draws it as a tree of path
+segments (table and plain output only).`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := session.Require(); err != nil {
-			return err
+		prefix := strings.TrimSuffix(cat, "/")
+		if len(args) == 1 {
+			prefix = strings.TrimSuffix(args[0], "/")
+		}
+		if prefix != "" {
+			if err := secrets.ValidatePath(prefix); err != nil {
+				return usageError{err}
+			}
+		}
+		var within time.Duration
+		if expiring != "" {
+			var err error
+			if within, err = core.ParseDuration(expiring); err != nil {
+				return usageError{fmt.Errorf("--expiring: %w", err)}
+			}
+		}
+
+		var items []secrets.Secret
+		var err error
+		if c := agentClient(); c != nil {
+			items, err = c.ListSecrets(prefix)
+		} else {
+			if err := session.Require(); err != nil {
+				return err
+			}
+			items, err = secrets.List(database, prefix)
 		}
-		items, err := secrets.List(database, cat)
 		if err != nil {
 			return err
 		}
+
+		now := time.Now()
+		matched := items[:0]
+		for _, s := range items {
+			if s.HasTags(withTags) && (expiring == "" || s.DueBy(now.Add(within))) {
+				matched = append(matched, s)
+			}
+		}
+		items = matched
+		if items == nil {
+			items = []secrets.Secret{}
+		}
+		if asTree && !format.Structured() {
+			printTree(os.Stdout, items, prefix, now)
+			return nil
+		}
+		rows := output.Rows{Header: []string{"path", "type", "updated_at", "expires_at", "rotate_due", "status", "tags"}}
 		for _, s := range items {
-			fmt.Printf("- %s / %s\n", s.Category, s.Name)
+			rows.Rows = append(rows.Rows, []string{s.Path, s.Type, s.UpdatedAt, s.ExpiresAt, s.RotateDue, s.Status(now), strings.Join(s.Tags, ",")})
 		}
-		return nil
+		return render(items, rows)
 	},
 }
 
+type treeNode struct {
+	children map[string]*treeNode
+	secret   *secrets.Secret
+}
+
+// printTree draws items as a tree of their path segments below prefix,
+// with each secret's type and status beside it.
+func printTree(w io.Writer, items []secrets.Secret, prefix string, now time.Time) {
+	root := &treeNode{children: map[string]*treeNode{}}
+	for i := range items {
+		s := &items[i]
+		n := root
+		if rel := strings.TrimPrefix(strings.TrimPrefix(s.Path, prefix), "/"); rel != "" {
+			for _, seg := range strings.Split(rel, "/") {
+				if n.children[seg] == nil {
+					n.children[seg] = &treeNode{children: map[string]*treeNode{}}
+				}
+				n = n.children[seg]
+			}
+		}
+		n.secret = s
+	}
+	label := prefix
+	if label == "" {
+		label = "."
+	}
+	fmt.Fprintln(w, label+treeNote(root.secret, now))
+	root.print(w, "", now)
+}
+
+func (n *treeNode) print(w io.Writer, indent string, now time.Time) {
+	names := slices.Sorted(maps.Keys(n.children))
+	for i, name := range names {
+		branch, next := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, next = "└── ", "    "
+		}
+		c := n.children[name]
+		fmt.Fprintln(w, indent+branch+name+treeNote(c.secret, now))
+		c.print(w, indent+next, now)
+	}
+}
+
+func treeNote(s *secrets.Secret, now time.Time) string {
+	if s == nil {
+		return ""
+	}
+	var parts []string
+	for _, p := range []string{s.Type, s.Status(now)} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "  (" + strings.Join(parts, ", ") + ")"
+}
+
 func init() {
-	listSecretsCmd.Flags().StringVar(&cat, "cat", "", "Filter by category")
+	listSecretsCmd.Flags().StringVar(&cat, "cat", "", "same as the prefix argument")
+	_ = listSecretsCmd.Flags().MarkDeprecated("cat", "pass the prefix as an argument")
+	listSecretsCmd.Flags().BoolVar(&asTree, "tree", false, "draw the secrets as a tree of path segments")
+	listSecretsCmd.Flags().StringVar(&expiring, "expiring", "", "only secrets that expire or are due for rotation within this long (e.g. 14d)")
+	listSecretsCmd.Flags().StringArrayVar(&withTags, "tag", nil, "only secrets with this tag (repeatable; all must match)")
 }
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"fmt"
 	"time"
 
 	"vault-cli/internal/auth"
@@ -14,13 +13,13 @@ var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Start a session (verifies master password)",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if ok := auth.VerifyPassword(cfg.PasswordFile); !ok {
-			return fmt.Errorf("access denied: wrong password")
+		if err := login(); err != nil {
+			return err
 		}
-		if err := session.Save("admin", 15*time.Minute); err != nil {
+		if err := session.Save(auth.MasterUser, 15*time.Minute); err != nil {
 			return err
 		}
-		fmt.Println("Session started (15m).")
+		note("Session started (15m).")
 		return nil
 	},
 }
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-
 	"vault-cli/internal/session"
 
 	"github.com/spf13/cobra"
@@ -13,7 +11,7 @@ var logoutCmd = &cobra.Command{
 	Short: "End the current session",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		_ = session.Clear()
-		fmt.Println("Logged out.")
+		note("Logged out.")
 		return nil
 	},
 }
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/db"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/output"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var mfaUser string
+
+var mfaCmd = &cobra.Command{
+	Use:   "mfa",
+	Short: "Manage TOTP two-factor authentication",
+}
+
+var mfaEnrollCmd = &cobra.Command{
+	Use:   "enroll",
+	Short: "Enroll an authenticator app and print recovery codes",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		if err := ensureUnlocked(); err != nil {
+			return err
+		}
+		enrolled, err := mfa.Enrolled(database, mfaUser)
+		if err != nil {
+			return err
+		}
+		if enrolled {
+			return fmt.Errorf("mfa already enrolled for %s; run 'vault mfa disable' first", mfaUser)
+		}
+
+		e, codes, err := mfa.NewEnrollment(10)
+		if err != nil {
+			return err
+		}
+		t := e.TOTP()
+		fmt.Println("Add this account to your authenticator app:")
+		fmt.Println()
+		fmt.Println("  " + t.URI(mfa.Issuer, mfaUser))
+		fmt.Println()
+		fmt.Println("Secret: " + mfa.EncodeSecret(e.Secret))
+		fmt.Println()
+		fmt.Println("Recovery codes (each works once, store them somewhere safe):")
+		for _, c := range codes {
+			fmt.Println("  " + c)
+		}
+		fmt.Println()
+
+		code, err := auth.PromptPassword("Enter the current code to confirm: ")
+		if err != nil {
+			return err
+		}
+		if err := e.Confirm(code); err != nil {
+			return err
+		}
+		if err := mfa.Save(database, cfg, mfaUser, e); err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "mfa:enroll", mfaUser, "cli", true, "")
+		note("MFA enrolled.")
+		return nil
+	},
+}
+
+var mfaDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Remove the TOTP enrollment (requires a valid code)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		if err := ensureUnlocked(); err != nil {
+			return err
+		}
+		// A login earlier in this process has already taken the master
+		// user's code, and asking again would be a replay.
+		if !passwordChecked || mfaUser != auth.MasterUser {
+			code, err := auth.PromptPassword("Enter authentication or recovery code: ")
+			if err != nil {
+				return err
+			}
+			if err := mfa.VerifyLogin(database, cfg, mfaUser, "", code); err != nil {
+				return err
+			}
+		}
+		if err := mfa.Delete(database, mfaUser); err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "mfa:disable", mfaUser, "cli", true, "")
+		note("MFA disabled.")
+		return nil
+	},
+}
+
+var mfaStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether TOTP is enrolled",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enrolled, err := mfa.Enrolled(database, mfaUser)
+		if err != nil {
+			return err
+		}
+		return render(struct {
+			User     string `json:"user"`
+			Enrolled bool   `json:"enrolled"`
+		}{mfaUser, enrolled}, output.Rows{Header: []string{"user", "enrolled"}, Rows: [][]string{{mfaUser, strconv.FormatBool(enrolled)}}})
+	},
+}
+
+// promptPassword reads the password and code for login; tests replace it.
+var promptPassword = auth.PromptPassword
+
+// login asks for the master password and, when enrolled, the code, and
+// checks both before the keyring is left open.
+func login() error {
+	_, err := loginWith("Enter master password: ")
+	return err
+}
+
+// loginWith is login with its own password prompt; it returns the
+// password for callers that need it again, like passwd.
+func loginWith(prompt string) (string, error) {
+	pw, err := promptPassword(prompt)
+	if err != nil {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	enrolled, err := mfa.Enrolled(database, auth.MasterUser)
+	if err != nil {
+		return "", err
+	}
+	var code string
+	if enrolled {
+		if code, err = promptPassword("Enter authentication code: "); err != nil {
+			return "", err
+		}
+	}
+	return pw, mfa.Login(database, cfg, "", pw, strings.TrimSpace(code))
+}
+
+func init() {
+	mfaCmd.PersistentFlags().StringVar(&mfaUser, "user", auth.MasterUser, "user to manage")
+	mfaCmd.AddCommand(mfaEnrollCmd, mfaDisableCmd, mfaStatusCmd)
+	rootCmd.AddCommand(mfaCmd)
+}
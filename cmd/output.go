@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"vault-cli/internal/output"
+	"vault-cli/internal/tui"
+)
+
+var (
+	outputFlag string
+	quiet      bool
+	format     = output.Table
+)
+
+// interactive is true when a person is reading table output on a terminal,
+// the only case where the banner and other decoration are shown.
+func interactive() bool {
+	return !quiet && format == output.Table && tui.Enabled()
+}
+
+// render writes a command's result to stdout in the selected format.
+func render(v any, rows output.Rows) error {
+	return output.Write(os.Stdout, format, v, rows)
+}
+
+// note prints a status message. It is dropped with --quiet and goes to
+// stderr when stdout carries json, yaml or plain output.
+func note(msg string, a ...any) {
+	if quiet {
+		return
+	}
+	var w io.Writer = os.Stdout
+	if format != output.Table {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, msg+"\n", a...)
+}
+
+// warn prints to stderr regardless of --quiet and the output format:
+// warnings are about the result, not progress.
+func warn(msg string, a ...any) {
+	fmt.Fprintf(os.Stderr, msg+"\n", a...)
+}
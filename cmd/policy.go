@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	policyRotateEvery string
+	policyTTL         string
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Set rotation and expiry defaults for everything under a path prefix",
+	Long: `Policies apply to every secret under a path prefix; where several
+match, the longest prefix that sets a value wins.
+
+--rotate-every applies to secrets that have no rotation period of their
+own and takes effect at once: list-secrets, report and secret show use it.
+--ttl makes values added or updated under the prefix expire that long
+after they are written, unless the write sets --ttl itself.`,
+}
+
+var policySetCmd = &cobra.Command{
+	Use:   "set <prefix>",
+	Short: "Create or replace the policy for a prefix",
+	Example: `  vault policy set prod --rotate-every 90d
+  vault policy set prod/payments --rotate-every 30d --ttl 180d`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		p := secrets.Policy{Prefix: strings.TrimSuffix(args[0], "/"), RotateEvery: policyRotateEvery, TTL: policyTTL}
+		if p.RotateEvery == "" && p.TTL == "" {
+			return usageError{errors.New("give --rotate-every, --ttl or both")}
+		}
+		if err := secrets.SetPolicy(database, p); err != nil {
+			return usageError{err}
+		}
+		_ = db.RecordAudit(database, "policy:set", p.Prefix, "rotate_every="+p.RotateEvery+" ttl="+p.TTL, true, "")
+		note("Policy for %s saved.", p.Prefix)
+		return nil
+	},
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List policies",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		ps, err := secrets.Policies(database)
+		if err != nil {
+			return err
+		}
+		if ps == nil {
+			ps = []secrets.Policy{}
+		}
+		rows := output.Rows{Header: []string{"prefix", "rotate_every", "ttl", "updated_at"}}
+		for _, p := range ps {
+			rows.Rows = append(rows.Rows, []string{p.Prefix, p.RotateEvery, p.TTL, p.UpdatedAt})
+		}
+		return render(ps, rows)
+	},
+}
+
+var policyRemoveCmd = &cobra.Command{
+	Use:   "rm <prefix>",
+	Short: "Remove the policy for a prefix",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		prefix := strings.TrimSuffix(args[0], "/")
+		if err := secrets.DeletePolicy(database, prefix); err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "policy:delete", prefix, "", true, "")
+		note("Policy for %s removed.", prefix)
+		return nil
+	},
+}
+
+func init() {
+	policySetCmd.Flags().StringVar(&policyRotateEvery, "rotate-every", "", "rotation period for secrets without their own (e.g. 90d)")
+	policySetCmd.Flags().StringVar(&policyTTL, "ttl", "", "expire values this long after they are written (e.g. 180d)")
+	policyCmd.AddCommand(policySetCmd, policyListCmd, policyRemoveCmd)
+	rootCmd.AddCommand(policyCmd)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var renderOutput string
+
+var renderCmd = &cobra.Command{
+	Use:   "render <template|->",
+	Short: "Render a Go template, filling in secrets",
+	Long: `Renders a text/template file to stdout, or with --out to a file created
+with mode 0600. Templates can call:
+
+  {{ secret "prod/db" "password" }}   decrypted secret value
+  {{ field "prod/db" "main" "host" }} one field of a typed secret (see add-secret --type)
+  {{ file "ca.pem" }}                 contents of a local file (relative to the template)
+  {{ env "HOME" }}                    environment variable; {{ env "X" "default" }}
+
+Rendering fails, and nothing is written, if any secret, file, variable or
+template field is missing.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			src []byte
+			err error
+			dir = "."
+		)
+		if args[0] == "-" {
+			src, err = io.ReadAll(os.Stdin)
+		} else {
+			src, err = os.ReadFile(args[0])
+			dir = filepath.Dir(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+
+		r := &renderer{dir: dir, cache: map[string]string{}}
+		tmpl, err := template.New(filepath.Base(args[0])).
+			Option("missingkey=error").
+			Funcs(template.FuncMap{"secret": r.secret, "field": r.field, "file": r.file, "env": r.env}).
+			Parse(string(src))
+		if err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, nil); err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+
+		if renderOutput == "" || renderOutput == "-" {
+			_, err = os.Stdout.Write(out.Bytes())
+			return err
+		}
+		return core.WriteFileAtomic(renderOutput, out.Bytes(), 0600)
+	},
+}
+
+// renderer resolves template functions. Secrets are read once per render
+// and the vault is only unlocked if the template actually uses one.
+type renderer struct {
+	dir      string
+	cache    map[string]string
+	unlocked bool
+}
+
+func (r *renderer) secret(category, name string) (string, error) {
+	target := secrets.Path(category, name)
+	if v, ok := r.cache[target]; ok {
+		return v, nil
+	}
+
+	var val string
+	var err error
+	if c := agentClient(); c != nil {
+		val, err = c.GetSecret(category, name)
+	} else {
+		if !r.unlocked {
+			if err := session.Require(); err != nil {
+				return "", err
+			}
+			if err := ensureUnlocked(); err != nil {
+				return "", err
+			}
+			r.unlocked = true
+		}
+		val, err = secrets.Get(database, cfg, category, name)
+	}
+	if err != nil {
+		_ = db.RecordAudit(database, "secret:read", target, "render", false, err.Error())
+		return "", err
+	}
+	_ = db.RecordAudit(database, "secret:read", target, "render", true, "")
+	r.cache[target] = val
+	return val, nil
+}
+
+func (r *renderer) field(category, name, field string) (string, error) {
+	val, err := r.secret(category, name)
+	if err != nil {
+		return "", err
+	}
+	return secrets.GetField(val, field)
+}
+
+func (r *renderer) file(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	b, err := os.ReadFile(path)
+	return string(b), err
+}
+
+func (r *renderer) env(name string, def ...string) (string, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	if len(def) > 0 {
+		return def[0], nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", name)
+}
+
+func init() {
+	renderCmd.Flags().StringVarP(&renderOutput, "out", "O", "", "write to this file (mode 0600) instead of stdout")
+	rootCmd.AddCommand(renderCmd)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"vault-cli/internal/secrets"
+)
+
+// renderTo renders tmpl from a file in a fresh directory to out.
+func renderTo(t *testing.T, tmpl, out string) error {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), []byte("CA"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "config.tmpl")
+	if err := os.WriteFile(src, []byte(tmpl), 0600); err != nil {
+		t.Fatal(err)
+	}
+	renderOutput = out
+	t.Cleanup(func() { renderOutput = "" })
+	return renderCmd.RunE(renderCmd, []string{src})
+}
+
+func addSecrets(t *testing.T, items ...secrets.Secret) {
+	t.Helper()
+	for _, s := range items {
+		if err := secrets.Add(database, cfg, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	testVault(t)
+	addSecrets(t,
+		secrets.Secret{Category: "prod", Name: "token", Value: "t0k"},
+		secrets.Secret{Category: "prod", Name: "db", Type: secrets.TypeLogin, Value: `{"username":"app","password":"pw"}`},
+	)
+	t.Setenv("RENDER_TEST", "set")
+
+	out := filepath.Join(t.TempDir(), "config.yaml")
+	// A file that is already there, and readable by others, is replaced.
+	if err := os.WriteFile(out, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := renderTo(t, `{{ secret "prod" "token" }} {{ secret "prod" "token" }} {{ field "prod" "db" "username" }} {{ file "ca.pem" }} {{ env "RENDER_TEST" }} {{ env "RENDER_UNSET" "fallback" }}`, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "t0k t0k app CA set fallback"; string(b) != want {
+		t.Fatalf("rendered %q, want %q", b, want)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Fatalf("output mode = %v, want 0600", perm)
+		}
+	}
+
+	// Each secret is read, and audited, once per render.
+	var reads int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM audit WHERE action='secret:read' AND filename='prod/token'`).Scan(&reads); err != nil {
+		t.Fatal(err)
+	}
+	if reads != 1 {
+		t.Fatalf("prod/token read %d times", reads)
+	}
+}
+
+func TestRenderFailsClosed(t *testing.T) {
+	testVault(t)
+	addSecrets(t, secrets.Secret{Category: "prod", Name: "db", Type: secrets.TypeLogin, Value: `{"username":"app","password":"pw"}`})
+
+	for name, tmpl := range map[string]string{
+		"missing secret": `ok {{ secret "prod" "missing" }}`,
+		"missing field":  `ok {{ field "prod" "db" "host" }}`,
+		"missing file":   `ok {{ file "missing.pem" }}`,
+		"missing env":    `ok {{ env "RENDER_UNSET" }}`,
+		"bad template":   `ok {{ secret "prod" `,
+	} {
+		out := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(out, []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := renderTo(t, tmpl, out); err == nil {
+			t.Errorf("%s: rendered without an error", name)
+		}
+		if b, _ := os.ReadFile(out); string(b) != "old" {
+			t.Errorf("%s: output replaced with %q", name, b)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(out))
+		if len(entries) != 1 {
+			t.Errorf("%s: left %d files behind", name, len(entries))
+		}
+	}
+}
+
+func TestRenderUnlocksOnlyForSecrets(t *testing.T) {
+	lockedVault(t) // no answers: any prompt fails
+	out := filepath.Join(t.TempDir(), "plain.txt")
+	if err := renderTo(t, `{{ file "ca.pem" }}`, out); err != nil {
+		t.Fatalf("template without secrets: %v", err)
+	}
+	if err := renderTo(t, `{{ secret "prod" "db" }}`, out); err == nil || !strings.Contains(err.Error(), "unexpected prompt") {
+		t.Fatalf("template with a secret on a locked vault: got %v", err)
+	}
+}
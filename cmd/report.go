@@ -1,19 +1,101 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"vault-cli/internal/core"
+	"vault-cli/internal/output"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
 
 	"github.com/spf13/cobra"
 )
 
+var reportWithin string
+
+// vaultReport adds secrets that need attention to the file summary.
+type vaultReport struct {
+	*core.Report
+	Attention []secrets.Secret `json:"secrets_needing_attention"`
+}
+
 var reportCmd = &cobra.Command{
 	Use:   "report",
-	Short: "Show vault summary report (file count, total size, recent uploads)",
-	Run: func(cmd *cobra.Command, args []string) {
-		core.GenerateReport(database)
+	Short: "Show vault summary report (file count, total size, recent uploads, secrets due)",
+	Long: `Summarises stored files and lists secrets that have expired, are overdue
+for rotation, or will be within --within (default 14d).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		within, err := core.ParseDuration(reportWithin)
+		if err != nil {
+			return usageError{fmt.Errorf("--within: %w", err)}
+		}
+		if err := session.Require(); err != nil {
+			return err
+		}
+		fr, err := core.GenerateReport(database)
+		if err != nil {
+			return fmt.Errorf("report: %w", err)
+		}
+		items, err := secrets.List(database, "")
+		if err != nil {
+			return fmt.Errorf("report: %w", err)
+		}
+		now := time.Now()
+		r := vaultReport{Report: fr, Attention: []secrets.Secret{}}
+		for _, s := range items {
+			if s.DueBy(now.Add(within)) {
+				r.Attention = append(r.Attention, s)
+			}
+		}
+
+		if format == output.Table {
+			fmt.Printf("Files Stored: %d\n", r.Files)
+			fmt.Printf("Total Size: %.2f MB\n", float64(r.TotalBytes)/1024/1024)
+			fmt.Println("\nRecent Uploads:")
+			for _, u := range r.Recent {
+				fmt.Printf("- %s (%s)\n", u.Filename, u.Uploaded)
+			}
+			fmt.Printf("\nSecrets Needing Attention (next %s):\n", reportWithin)
+			if len(r.Attention) == 0 {
+				fmt.Println("- none")
+			}
+			for _, s := range r.Attention {
+				fmt.Printf("- %s: %s\n", secrets.Path(s.Category, s.Name), describeDue(s, now))
+			}
+			return nil
+		}
+		rows := [][]string{
+			{"files", strconv.Itoa(r.Files)},
+			{"total_bytes", strconv.FormatInt(r.TotalBytes, 10)},
+		}
+		for _, u := range r.Recent {
+			rows = append(rows, []string{"recent_upload", u.Filename, u.Uploaded})
+		}
+		for _, s := range r.Attention {
+			rows = append(rows, []string{"secret_due", secrets.Path(s.Category, s.Name), describeDue(s, now)})
+		}
+		return render(r, output.Rows{Rows: rows})
 	},
 }
 
+// describeDue says which of s's dates is passed or coming up, most urgent
+// first.
+func describeDue(s secrets.Secret, now time.Time) string {
+	switch s.Status(now) {
+	case "expired":
+		return "expired " + s.ExpiresAt
+	case "rotation overdue":
+		return "rotation overdue since " + s.RotateDue
+	}
+	if s.ExpiresAt != "" && (s.RotateDue == "" || s.ExpiresAt <= s.RotateDue) {
+		return "expires " + s.ExpiresAt
+	}
+	return "rotation due " + s.RotateDue
+}
+
 func init() {
+	reportCmd.Flags().StringVar(&reportWithin, "within", "14d", "also list secrets expiring or due for rotation within this long")
 	rootCmd.AddCommand(reportCmd)
 }
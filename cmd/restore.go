@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/backup"
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreDryRun   bool
+	restorePolicy   string
+	restoreIdentity string
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup> [--dry-run] [--on-conflict fail|skip|overwrite|newer] [--identity file]",
+	Short: "Restore secrets, file records, the audit log and local files from a backup",
+	Long: `Restores a 'vault backup' archive into this vault. Secrets are re-encrypted
+with this vault's keys. File records and audit entries already present are
+left alone; the rest are added with their original timestamps.
+
+A secret or local file that exists with different contents is a conflict.
+--on-conflict decides what happens: fail (the default) stops before
+changing anything, skip keeps what is here, overwrite takes the backup's,
+and newer takes whichever was updated last.
+
+--dry-run reads and verifies the whole archive and shows what would change
+without writing anything. Recipient-encrypted backups need --identity.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		policy, err := backup.ParsePolicy(restorePolicy)
+		if err != nil {
+			return usageError{err}
+		}
+		var id *backup.Identity
+		if restoreIdentity != "" {
+			data, err := os.ReadFile(restoreIdentity)
+			if err != nil {
+				return err
+			}
+			if id, err = backup.ParseIdentity(data); err != nil {
+				return usageError{err}
+			}
+		}
+		if restoreDryRun {
+			err = session.Require()
+		} else {
+			err = reauthenticate()
+		}
+		if err != nil {
+			return err
+		}
+
+		f, err := backup.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var pass string
+		if f.Recipient() == "" {
+			if pass, err = auth.PromptPassword("Backup passphrase: "); err != nil {
+				return err
+			}
+		} else if id == nil {
+			return usageError{fmt.Errorf("restore: backup is encrypted to %s; pass --identity", f.Recipient())}
+		}
+		r, err := f.Unlock(pass, id)
+		if err != nil {
+			return err
+		}
+
+		plan, err := backup.PlanRestore(database, &r.Archive, policy, cfg.LocalPath)
+		if plan != nil {
+			if perr := showPlan(plan); perr != nil {
+				return perr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := backup.Restore(database, cfg, r, plan, cfg.LocalPath, restoreDryRun); err != nil {
+			if !restoreDryRun {
+				_ = db.RecordAudit(database, "restore", args[0], string(policy), false, err.Error())
+			}
+			return fmt.Errorf("restore: %w", err)
+		}
+		if restoreDryRun {
+			note("Dry run: backup verified, nothing was changed.")
+			return nil
+		}
+		_ = db.RecordAudit(database, "restore", args[0], string(policy), true, "")
+		note("Restored %d secrets, %d file records, %d audit entries and %d local files.",
+			len(plan.Add)+len(plan.Replace), plan.Files, plan.Audit, len(plan.Blobs))
+		return nil
+	},
+}
+
+// showPlan lists each secret and local file with what restore does to it.
+func showPlan(p *backup.Plan) error {
+	rows := output.Rows{Header: []string{"action", "name"}}
+	for _, g := range []struct {
+		action string
+		names  []string
+	}{
+		{"add", p.Add},
+		{"replace", p.Replace},
+		{"write-file", p.Blobs},
+		{"keep", p.Keep},
+		{"unchanged", p.Unchanged},
+		{"conflict", p.Conflicts},
+	} {
+		for _, n := range g.names {
+			rows.Rows = append(rows.Rows, []string{g.action, n})
+		}
+	}
+	if err := render(p, rows); err != nil {
+		return err
+	}
+	note("File records: %d to add, %d already present. Audit entries: %d to add, %d already present.",
+		p.Files, p.FilesPresent, p.Audit, p.AuditPresent)
+	return nil
+}
+
+func init() {
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "verify the backup and show what would change")
+	restoreCmd.Flags().StringVar(&restorePolicy, "on-conflict", string(backup.PolicyFail), "fail, skip, overwrite or newer")
+	restoreCmd.Flags().StringVar(&restoreIdentity, "identity", "", "identity file for recipient-encrypted backups")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -2,45 +2,47 @@ package cmd
 
 import (
 	"database/sql"
-	"log"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
 
-	"vault-cli/internal/auth"
 	"vault-cli/internal/config"
 	"vault-cli/internal/db"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/output"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/tui"
 )
 
 var (
 	cfg      *config.Config
 	database *sql.DB
-	rootCmd  = &cobra.Command{
+	// passwordChecked records that this process already asked for the
+	// master password and, when enrolled, the MFA code, so commands that
+	// insist on re-authentication don't ask twice in a row.
+	passwordChecked bool
+	rootCmd         = &cobra.Command{
 		Use:   "vault",
 		Short: "Vault CLI — Secure file encryption and storage manager",
 		Long: `Vault CLI allows you to encrypt, upload, download, and manage files securely
 using AWS KMS, S3, and DynamoDB or local vault mode.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			_ = godotenv.Load(".env")
-
-			var err error
-			cfg, err = config.LoadConfig()
-			if err != nil {
+			if err := startCommand(cmd); err != nil {
 				return err
 			}
-
-			database, err = db.OpenDB(cfg.DBPath)
-			if err != nil {
-				return err
+			if interactive() {
+				tui.ShowVaultBanner()
 			}
-			if err := db.InitDB(database); err != nil {
+
+			if err := loadRuntime(); err != nil {
 				return err
 			}
 
-			if cfg.RequirePassword {
-				if ok := auth.VerifyPassword(cfg.PasswordFile); !ok {
-					log.Fatal("Access denied: wrong password")
+			if cfg.RequirePassword && agentClient() == nil {
+				if err := login(); err != nil {
+					return err
 				}
+				passwordChecked = true
 			}
 
 			return nil
@@ -48,7 +50,73 @@ using AWS KMS, S3, and DynamoDB or local vault mode.`,
 	}
 )
 
+// startCommand applies the flags every command shares. Commands that
+// replace the root's PersistentPreRunE call it first.
+func startCommand(cmd *cobra.Command) error {
+	// Arguments and flags have been validated by now; later failures
+	// are not usage mistakes, so don't print usage.
+	cmd.SilenceUsage = true
+
+	f, err := output.Parse(outputFlag)
+	if err != nil {
+		return err
+	}
+	format = f
+	return nil
+}
+
+func loadRuntime() error {
+	_ = godotenv.Load(".env")
+
+	var err error
+	cfg, err = config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	database, err = db.OpenDB(cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	if err := db.InitDB(database); err != nil {
+		return err
+	}
+	if err := secrets.MigratePaths(database); err != nil {
+		return err
+	}
+	return keyring.Load(database)
+}
+
+// ensureUnlocked logs in, with the MFA code when one is enrolled, when
+// the vault has a keyring that this process has not opened yet and no
+// agent is serving the request.
+func ensureUnlocked() error {
+	if !keyring.Initialized() || !keyring.Locked() || agentClient() != nil {
+		return nil
+	}
+	if err := login(); err != nil {
+		return err
+	}
+	passwordChecked = true
+	return nil
+}
+
+// reauthenticate asks for the master password, and the MFA code if one is
+// enrolled, even inside a session. Commands that hand out or replace
+// secrets in bulk use it; it also unlocks the keyring for this process.
+func reauthenticate() error {
+	if passwordChecked {
+		return nil
+	}
+	if err := login(); err != nil {
+		return err
+	}
+	passwordChecked = true
+	return nil
+}
+
 func Execute() error {
+	markUsageErrors(rootCmd)
 	return rootCmd.Execute()
 }
 
@@ -59,6 +127,8 @@ func CloseDB() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", string(output.Table), "output format: table, json, yaml or plain")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress the banner and status messages")
 	rootCmd.AddCommand(
 		loginCmd,
 		logoutCmd,
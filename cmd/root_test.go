@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/config"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/session"
+)
+
+const testPassword = "correct horse"
+
+// testVault points the package globals at a fresh local vault without a
+// keyring or agent, inside a logged-in session.
+func testVault(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := session.Save(auth.MasterUser, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	database = dbtest.Open(t)
+	cfg = &config.Config{Mode: "local", PasswordFile: filepath.Join(t.TempDir(), "pw")}
+	agentChecked, agentConn, passwordChecked = true, nil, false
+	empty := dbtest.Open(t)
+	t.Cleanup(func() {
+		keyring.Lock()
+		_ = keyring.Load(empty)
+		agentChecked, passwordChecked = false, false
+		promptPassword = auth.PromptPassword
+	})
+}
+
+// lockedVault is testVault with a keyring, locked, and answers prompts
+// from the returned slice.
+func lockedVault(t *testing.T) *[]string {
+	t.Helper()
+	testVault(t)
+
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.PasswordFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Create(database, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	keyring.Lock()
+
+	answers := new([]string)
+	promptPassword = func(string) (string, error) {
+		if len(*answers) == 0 {
+			return "", errors.New("unexpected prompt")
+		}
+		a := (*answers)[0]
+		*answers = (*answers)[1:]
+		return a, nil
+	}
+	return answers
+}
+
+func TestEnsureUnlockedAsksForTheCode(t *testing.T) {
+	answers := lockedVault(t)
+	if err := keyring.Unlock(database, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	e, _, err := mfa.NewEnrollment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mfa.Save(database, cfg, auth.MasterUser, e); err != nil {
+		t.Fatal(err)
+	}
+	keyring.Lock()
+
+	*answers = []string{testPassword, ""}
+	if err := ensureUnlocked(); !errors.Is(err, mfa.ErrCodeRequired) {
+		t.Fatalf("password alone: got %v, want ErrCodeRequired", err)
+	}
+	if !keyring.Locked() {
+		t.Fatal("keyring opened without the second factor")
+	}
+
+	*answers = []string{testPassword, e.TOTP().Code()}
+	if err := ensureUnlocked(); err != nil {
+		t.Fatal(err)
+	}
+	if keyring.Locked() || !passwordChecked {
+		t.Fatal("keyring still locked after password and code")
+	}
+}
+
+func TestEnsureUnlockedWithoutMFA(t *testing.T) {
+	answers := lockedVault(t)
+	*answers = []string{"wrong"}
+	if err := ensureUnlocked(); !errors.Is(err, auth.ErrInvalidPassword) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+	*answers = []string{testPassword}
+	if err := ensureUnlocked(); err != nil || keyring.Locked() {
+		t.Fatalf("right password: %v, locked %v", err, keyring.Locked())
+	}
+}
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"fmt"
-
 	"vault-cli/internal/secrets"
 	"vault-cli/internal/session"
 
@@ -16,11 +14,14 @@ var rotateKeysCmd = &cobra.Command{
 		if err := session.Require(); err != nil {
 			return err
 		}
+		if err := ensureUnlocked(); err != nil {
+			return err
+		}
 		n, err := secrets.Rotate(database, cfg)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Rotated %d secrets.\n", n)
+		note("Rotated %d secrets.", n)
 		return nil
 	},
 }
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	runCategories []string
+	runMapFile    string
+)
+
+// exitStatus is a child process's exit code, passed through unchanged as
+// vault's own.
+type exitStatus int
+
+func (e exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+var runCmd = &cobra.Command{
+	Use:   "run [--category <cat>]... [--map <file>] -- <command> [args...]",
+	Short: "Run a command with secrets injected as environment variables",
+	Long: `Decrypts the secrets of each --category, and any listed in --map, and starts
+command with them added to its environment. Nothing is written to disk.
+
+Secret names become variable names by upper-casing them and replacing
+anything other than letters and digits with "_" (db-password -> DB_PASSWORD).
+A map file sets names explicitly, one per line:
+
+  DATABASE_URL=prod/db/url
+  API_TOKEN=api_token
+  # comments and blank lines are ignored
+
+Signals received by vault are forwarded to the command, except those a
+terminal already sends it (Ctrl-C, Ctrl-\, resizes), and vault exits with
+the command's exit status.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(runCategories) == 0 && runMapFile == "" {
+			return usageError{errors.New("run: pass --category or --map")}
+		}
+		env, err := runEnv()
+		if err != nil {
+			return err
+		}
+
+		child := exec.Command(args[0], args[1:]...)
+		child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+		child.Env = os.Environ()
+		for k, v := range env {
+			child.Env = append(child.Env, k+"="+v)
+		}
+		if err := child.Start(); err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+
+		sigs := make(chan os.Signal, 4)
+		signal.Notify(sigs, forwardedSignals...)
+		defer signal.Stop(sigs)
+		tty := term.IsTerminal(int(os.Stdin.Fd()))
+		go func() {
+			for s := range sigs {
+				if forward(s, tty) {
+					_ = child.Process.Signal(s)
+				}
+			}
+		}()
+
+		err = child.Wait()
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			// The command has already reported its own failure.
+			cmd.SilenceErrors = true
+			code := ee.ExitCode()
+			if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				code = 128 + int(ws.Signal())
+			}
+			return exitStatus(code)
+		}
+		return err
+	},
+}
+
+// forward reports whether vault should pass s on to the command. On a
+// terminal, Ctrl-C, Ctrl-\ and resizes already reach the command, which
+// shares vault's process group; sending them again would make many
+// servers skip their graceful shutdown.
+func forward(s os.Signal, tty bool) bool {
+	return !tty || !slices.Contains(terminalSignals, s)
+}
+
+// runEnv collects the variables to inject: every secret in each category,
+// then the map file's entries, which win on conflict.
+func runEnv() (map[string]string, error) {
+	type ref struct{ category, name string }
+	refs := map[string]ref{}
+
+	for _, c := range runCategories {
+		var items []secrets.Secret
+		var err error
+		if ac := agentClient(); ac != nil {
+			items, err = ac.ListSecrets(c)
+		} else {
+			if err := session.Require(); err != nil {
+				return nil, err
+			}
+			items, err = secrets.List(database, c)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Only the category itself: secrets further down the tree
+		// would clash on their names.
+		items = slices.DeleteFunc(items, func(s secrets.Secret) bool { return s.Category != c })
+		if len(items) == 0 {
+			return nil, fmt.Errorf("%w: no secrets in category %s", secrets.ErrNotFound, c)
+		}
+		for _, s := range items {
+			key := envName(s.Name)
+			if prev, ok := refs[key]; ok && prev != (ref{s.Category, s.Name}) {
+				return nil, fmt.Errorf("run: %s and %s both map to %s; use --map to rename one", secrets.Path(prev.category, prev.name), secrets.Path(s.Category, s.Name), key)
+			}
+			refs[key] = ref{s.Category, s.Name}
+		}
+	}
+
+	if runMapFile != "" {
+		f, err := os.Open(runMapFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for n := 1; sc.Scan(); n++ {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, path, ok := strings.Cut(line, "=")
+			key, path = strings.TrimSpace(key), strings.TrimSpace(path)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("%s:%d: want NAME=path/to/secret", runMapFile, n)
+			}
+			category, name, err := secrets.SplitPath(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", runMapFile, n, err)
+			}
+			refs[key] = ref{category, name}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if agentClient() == nil {
+		if err := session.Require(); err != nil {
+			return nil, err
+		}
+		if err := ensureUnlocked(); err != nil {
+			return nil, err
+		}
+	}
+	env := make(map[string]string, len(refs))
+	for key, r := range refs {
+		var val string
+		var err error
+		if ac := agentClient(); ac != nil {
+			val, err = ac.GetSecret(r.category, r.name)
+		} else {
+			val, err = secrets.Get(database, cfg, r.category, r.name)
+		}
+		target := secrets.Path(r.category, r.name)
+		if err != nil {
+			_ = db.RecordAudit(database, "secret:read", target, "run", false, err.Error())
+			return nil, err
+		}
+		_ = db.RecordAudit(database, "secret:read", target, "run", true, "")
+		env[key] = val
+	}
+	return env, nil
+}
+
+func envName(name string) string {
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+func init() {
+	runCmd.Flags().StringArrayVar(&runCategories, "category", nil, "inject every secret in this category (repeatable)")
+	runCmd.Flags().StringVar(&runMapFile, "map", "", "file of NAME=path/to/secret lines")
+	runCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(runCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vault-cli/internal/secrets"
+)
+
+func TestEnvName(t *testing.T) {
+	for in, want := range map[string]string{
+		"db-password": "DB_PASSWORD",
+		"api.token":   "API_TOKEN",
+		"Stripe_Key":  "STRIPE_KEY",
+		"2fa-seed":    "_2FA_SEED",
+		"naïve":       "NA__VE",
+	} {
+		if got := envName(in); got != want {
+			t.Errorf("envName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// runVault stores values by path and sets the run flags.
+func runVault(t *testing.T, values map[string]string, categories []string, mapFile string) {
+	t.Helper()
+	testVault(t)
+	for p, v := range values {
+		c, n, _ := secrets.SplitPath(p)
+		if err := secrets.Add(database, cfg, secrets.Secret{Category: c, Name: n, Value: v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runCategories, runMapFile = categories, ""
+	if mapFile != "" {
+		runMapFile = filepath.Join(t.TempDir(), "env.map")
+		if err := os.WriteFile(runMapFile, []byte(mapFile), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { runCategories, runMapFile = nil, "" })
+}
+
+func TestRunEnv(t *testing.T) {
+	runVault(t, map[string]string{
+		"prod/api/db-password": "pw",
+		"prod/api/token":       "t1",
+		"prod/api/deep/other":  "skipped",
+		"root_token":           "r",
+		"staging/token":        "t2",
+	}, []string{"prod/api"}, `
+# comment
+ROOT = root_token
+TOKEN=staging/token
+`)
+	env, err := runEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"DB_PASSWORD": "pw", "TOKEN": "t2", "ROOT": "r"}
+	if !reflect.DeepEqual(env, want) {
+		t.Fatalf("env = %v, want %v", env, want)
+	}
+}
+
+func TestRunEnvConflicts(t *testing.T) {
+	runVault(t, map[string]string{"a/db-password": "1", "b/db_password": "2"}, []string{"a", "b"}, "")
+	if _, err := runEnv(); err == nil || !strings.Contains(err.Error(), "both map to DB_PASSWORD") {
+		t.Fatalf("got %v, want a conflict", err)
+	}
+}
+
+func TestRunMapErrors(t *testing.T) {
+	for _, line := range []string{"NOVALUE", "=a/b", "X=", "X=a//b", "X=prod/"} {
+		runVault(t, nil, nil, line+"\n")
+		if _, err := runEnv(); err == nil || !strings.Contains(err.Error(), "env.map:1:") {
+			t.Errorf("map line %q: got %v", line, err)
+		}
+	}
+	runVault(t, nil, nil, "X=prod/missing\n")
+	if _, err := runEnv(); !errors.Is(err, secrets.ErrNotFound) {
+		t.Errorf("missing secret: got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestForward(t *testing.T) {
+	for _, tc := range []struct {
+		sig  os.Signal
+		tty  bool
+		want bool
+	}{
+		{syscall.SIGINT, true, false},
+		{syscall.SIGQUIT, true, false},
+		{syscall.SIGWINCH, true, false},
+		{syscall.SIGTERM, true, true},
+		{syscall.SIGHUP, true, true},
+		{syscall.SIGINT, false, true},
+	} {
+		if got := forward(tc.sig, tc.tty); got != tc.want {
+			t.Errorf("forward(%v, tty=%v) = %v", tc.sig, tc.tty, got)
+		}
+	}
+}
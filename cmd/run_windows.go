@@ -0,0 +1,10 @@
+//go:build windows
+
+package cmd
+
+import "os"
+
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// terminalSignals reach every process attached to the console.
+var terminalSignals = []os.Signal{os.Interrupt}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	editDescription string
+	editOwner       string
+	editTags        []string
+	editUntags      []string
+	editFields      []string
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Show, edit, copy, move and delete secrets",
+}
+
+// secretArgs takes a secret's path as one argument, or as a category and
+// a name the way commands took it before secrets had paths.
+func secretArgs(args []string) (category, name string, err error) {
+	category, name, err = secrets.SplitPath(strings.Join(args, "/"))
+	if err != nil {
+		return "", "", usageError{err}
+	}
+	return category, name, nil
+}
+
+var secretShowCmd = &cobra.Command{
+	Use:   "show <path>",
+	Short: "Show a secret's description, owner, tags and fields (not its value)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		category, name, err := secretArgs(args)
+		if err != nil {
+			return err
+		}
+		if err := session.Require(); err != nil {
+			return err
+		}
+		s, err := secrets.Lookup(database, category, name)
+		if err != nil {
+			return err
+		}
+		rotate := s.RotateEvery
+		if s.RotatePolicy != "" {
+			rotate += " (policy " + s.RotatePolicy + ")"
+		}
+		rows := output.Rows{Header: []string{"key", "value"}, Rows: [][]string{
+			{"path", s.Path},
+			{"type", s.Type},
+			{"description", s.Description},
+			{"owner", s.Owner},
+			{"tags", strings.Join(s.Tags, ", ")},
+			{"updated_at", s.UpdatedAt},
+			{"expires_at", s.ExpiresAt},
+			{"rotate_every", rotate},
+		}}
+		for _, f := range s.Fields {
+			rows.Rows = append(rows.Rows, []string{f.Name + " (" + f.Type + ")", f.Value})
+		}
+		return render(s, rows)
+	},
+}
+
+var secretEditCmd = &cobra.Command{
+	Use:   "edit <path>",
+	Short: "Edit a secret's description, owner, tags and custom fields",
+	Long: `Edits the metadata stored alongside a secret. Metadata is not
+encrypted, so it can be listed and searched without unlocking the vault;
+keep credentials out of it. The value and its rotation clock are left
+alone.
+
+Without flags the metadata is opened as YAML in $VISUAL or $EDITOR.
+
+--field takes name=value, or name:type=value where type is text (the
+default), url, username or email. An empty value removes the field.`,
+	Example: `  vault secret edit prod/db-password --owner platform --tag postgres --tag prod
+  vault secret edit prod/db-password --field console:url=https://db.example.com --field user:username=app
+  vault secret edit prod/db-password --untag prod --field console=`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		category, name, err := secretArgs(args)
+		if err != nil {
+			return err
+		}
+		if err := session.Require(); err != nil {
+			return err
+		}
+		s, err := secrets.Lookup(database, category, name)
+		if err != nil {
+			return err
+		}
+
+		m := s.Meta
+		flags := cmd.Flags()
+		if flags.Changed("description") || flags.Changed("owner") || flags.Changed("tag") || flags.Changed("untag") || flags.Changed("field") {
+			if err := applyEditFlags(&m, flags.Changed("description"), flags.Changed("owner")); err != nil {
+				return usageError{err}
+			}
+		} else if m, err = editMeta(s.Path, m); err != nil {
+			return err
+		}
+
+		target := s.Path
+		if err := secrets.SetMeta(database, category, name, m); err != nil {
+			_ = db.RecordAudit(database, "secret:edit", target, "metadata", false, err.Error())
+			return err
+		}
+		_ = db.RecordAudit(database, "secret:edit", target, "metadata", true, "")
+		note("Metadata updated.")
+		return nil
+	},
+}
+
+func applyEditFlags(m *secrets.Meta, description, owner bool) error {
+	if description {
+		m.Description = editDescription
+	}
+	if owner {
+		m.Owner = editOwner
+	}
+	m.Tags = append(slices.DeleteFunc(m.Tags, func(t string) bool { return slices.Contains(editUntags, t) }), editTags...)
+
+	for _, arg := range editFields {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("--field %q: want name=value or name:type=value", arg)
+		}
+		name, typ, _ := strings.Cut(key, ":")
+		m.Fields = slices.DeleteFunc(m.Fields, func(f secrets.Field) bool { return f.Name == name })
+		if value != "" {
+			m.Fields = append(m.Fields, secrets.Field{Name: name, Type: typ, Value: value})
+		}
+	}
+	_, err := secrets.NormalizeMeta(*m)
+	return err
+}
+
+const editHeader = `# Metadata for %s. It is stored unencrypted; do not put secrets here.
+# Field types: %s. Save and quit to apply; delete everything to cancel.
+`
+
+// editMeta opens m as YAML in the user's editor and parses the result.
+func editMeta(id string, m secrets.Meta) (secrets.Meta, error) {
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if m.Fields == nil {
+		m.Fields = []secrets.Field{}
+	}
+	body, err := yaml.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+
+	f, err := os.CreateTemp("", "vault-meta-*.yaml")
+	if err != nil {
+		return m, err
+	}
+	defer os.Remove(f.Name())
+	fmt.Fprintf(f, editHeader, id, strings.Join(secrets.FieldTypes, ", "))
+	_, err = f.Write(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return m, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	argv := append(strings.Fields(editor), f.Name())
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return m, fmt.Errorf("editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return m, err
+	}
+	if !hasContent(edited) {
+		return m, errors.New("nothing to save, metadata left unchanged")
+	}
+	var out secrets.Meta
+	if err := yaml.Unmarshal(edited, &out); err != nil {
+		return m, fmt.Errorf("metadata: %w", err)
+	}
+	return out, nil
+}
+
+// hasContent reports whether data has anything besides comments.
+func hasContent(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	secretEditCmd.Flags().StringVar(&editDescription, "description", "", "what the secret is for")
+	secretEditCmd.Flags().StringVar(&editOwner, "owner", "", "person or team responsible for the secret")
+	secretEditCmd.Flags().StringArrayVar(&editTags, "tag", nil, "add a tag (repeatable)")
+	secretEditCmd.Flags().StringArrayVar(&editUntags, "untag", nil, "remove a tag (repeatable)")
+	secretEditCmd.Flags().StringArrayVar(&editFields, "field", nil, "set a custom field, name[:type]=value; empty value removes it (repeatable)")
+	secretCmd.AddCommand(secretShowCmd, secretEditCmd)
+	rootCmd.AddCommand(secretCmd)
+}
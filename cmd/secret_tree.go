@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeRecursive bool
+	treeForce     bool
+	treeDryRun    bool
+)
+
+var secretCopyCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Short: "Copy a secret, or with -r a whole subtree",
+	Long: `Copies the secret at src to dst. With --recursive src may be a prefix,
+and every secret below it is copied to the same place below dst.
+
+Values are copied without being decrypted, along with their metadata,
+expiry and rotation period. An existing secret at a destination is only
+replaced with --force. --dry-run lists what would be written.`,
+	Example: `  vault secret cp prod/payments/stripe/api_key staging/payments/stripe/api_key
+  vault secret cp -r prod/payments staging/payments --dry-run`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return transferSecrets(strings.TrimSuffix(args[0], "/"), strings.TrimSuffix(args[1], "/"), false)
+	},
+}
+
+var secretMoveCmd = &cobra.Command{
+	Use:   "mv <src> <dst>",
+	Short: "Move or rename a secret, or with -r a whole subtree",
+	Long: `Moves the secret at src to dst. With --recursive src may be a prefix,
+and every secret below it moves to the same place below dst.
+
+Values are not decrypted and keep their timestamps, metadata, expiry and
+rotation period. An existing secret at a destination is only replaced
+with --force. --dry-run lists what would be moved.`,
+	Example: `  vault secret mv prod/db/pass prod/db/password
+  vault secret mv -r legacy/payments prod/payments`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return transferSecrets(strings.TrimSuffix(args[0], "/"), strings.TrimSuffix(args[1], "/"), true)
+	},
+}
+
+func transferSecrets(src, dst string, move bool) error {
+	if err := session.Require(); err != nil {
+		return err
+	}
+	ts, err := secrets.PlanTransfer(database, src, dst, treeRecursive)
+	if err != nil {
+		return err
+	}
+	if treeDryRun {
+		rows := output.Rows{Header: []string{"from", "to", "replaces"}}
+		for _, t := range ts {
+			rows.Rows = append(rows.Rows, []string{t.From, t.To, fmt.Sprint(t.Replaces)})
+		}
+		return render(ts, rows)
+	}
+
+	action, verb := "secret:copy", "Copied"
+	if move {
+		action, verb = "secret:move", "Moved"
+	}
+	if err := secrets.ApplyTransfer(database, ts, move, treeForce); err != nil {
+		_ = db.RecordAudit(database, action, src, dst, false, err.Error())
+		if errors.Is(err, secrets.ErrExists) {
+			return fmt.Errorf("%w (use --force to replace it)", err)
+		}
+		return err
+	}
+	for _, t := range ts {
+		_ = db.RecordAudit(database, action, t.From, t.To, true, "")
+	}
+	note("%s %d %s.", verb, len(ts), plural(len(ts), "secret"))
+	return nil
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:   "rm <path>",
+	Short: "Delete a secret, or with -r a whole subtree",
+	Long: `Deletes the secret at path. With --recursive path may be a prefix, and
+every secret below it is deleted in one transaction; that asks for the
+master password again. --dry-run lists what would be deleted.`,
+	Example: `  vault secret rm prod/payments/stripe/old_key
+  vault secret rm -r staging/payments --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p := strings.TrimSuffix(args[0], "/")
+		if err := secrets.ValidatePath(p); err != nil {
+			return usageError{err}
+		}
+		if err := session.Require(); err != nil {
+			return err
+		}
+		paths, err := secrets.Paths(database, p)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(paths) == 0:
+			return fmt.Errorf("%w: %s", secrets.ErrNotFound, p)
+		case treeRecursive:
+		case slices.Contains(paths, p):
+			paths = []string{p}
+		default:
+			return usageError{fmt.Errorf("%s is a prefix of %d secrets; use --recursive", p, len(paths))}
+		}
+		if treeDryRun {
+			rows := output.Rows{Header: []string{"path"}}
+			for _, p := range paths {
+				rows.Rows = append(rows.Rows, []string{p})
+			}
+			return render(paths, rows)
+		}
+		if treeRecursive {
+			if err := reauthenticate(); err != nil {
+				return err
+			}
+		}
+
+		if err := secrets.DeletePaths(database, paths); err != nil {
+			_ = db.RecordAudit(database, "secret:delete", p, "cli", false, err.Error())
+			return err
+		}
+		for _, p := range paths {
+			_ = db.RecordAudit(database, "secret:delete", p, "cli", true, "")
+		}
+		note("Deleted %d %s.", len(paths), plural(len(paths), "secret"))
+		return nil
+	},
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func init() {
+	for _, c := range []*cobra.Command{secretCopyCmd, secretMoveCmd, secretRemoveCmd} {
+		c.Flags().BoolVarP(&treeRecursive, "recursive", "r", false, "include every secret below the path")
+		c.Flags().BoolVar(&treeDryRun, "dry-run", false, "list what would change without changing it")
+	}
+	secretCopyCmd.Flags().BoolVarP(&treeForce, "force", "f", false, "replace secrets that already exist at the destination")
+	secretMoveCmd.Flags().BoolVarP(&treeForce, "force", "f", false, "replace secrets that already exist at the destination")
+	secretCmd.AddCommand(secretCopyCmd, secretMoveCmd, secretRemoveCmd)
+}
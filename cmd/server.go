@@ -10,6 +10,7 @@ import (
 
 var (
     listenAddr string
+    tlsOpts    server.TLSOptions
 )
 
 var serverCmd = &cobra.Command{
@@ -18,13 +19,18 @@ var serverCmd = &cobra.Command{
     RunE: func(cmd *cobra.Command, args []string) error {
         srv := server.New(cfg, database)
         fmt.Printf("Starting Vault UI server on %s...\n", listenAddr)
-        return srv.Start(listenAddr)
+        return srv.Start(listenAddr, tlsOpts)
     },
 }
 
 func init() {
     serverCmd.Flags().StringVar(&listenAddr, "addr", "127.0.0.1:8080", "address to bind the web server")
+    serverCmd.Flags().StringVar(&tlsOpts.CertFile, "tls-cert", "", "TLS certificate file (PEM)")
+    serverCmd.Flags().StringVar(&tlsOpts.KeyFile, "tls-key", "", "TLS private key file (PEM)")
+    serverCmd.Flags().BoolVar(&tlsOpts.SelfSigned, "tls-self-signed", false, "serve HTTPS with a generated self-signed certificate (kept in ~/.vault/tls)")
+    serverCmd.Flags().StringVar(&tlsOpts.ClientCAFile, "tls-client-ca", "", "CA bundle; clients presenting a certificate it signed are authenticated")
+    serverCmd.Flags().BoolVar(&tlsOpts.RequireClientCert, "tls-require-client-cert", false, "reject TLS clients without a valid client certificate")
+    serverCmd.Flags().BoolVar(&tlsOpts.AllowInsecure, "insecure", false, "allow plain HTTP on a non-loopback address")
     rootCmd.AddCommand(serverCmd)
 }
 
-
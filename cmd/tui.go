@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/session"
+	"vault-cli/internal/tui"
+	"vault-cli/internal/tui/browser"
+
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse secrets, files and the audit log interactively",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !tui.Enabled() {
+			return errors.New("vault tui needs an interactive terminal")
+		}
+		if err := session.Require(); err != nil {
+			return err
+		}
+		// The browser reads secrets in-process, so the keyring has to be
+		// unlocked here even when an agent is running.
+		if keyring.Initialized() && keyring.Locked() {
+			if err := login(); err != nil {
+				return err
+			}
+		}
+		return browser.Run(database, cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
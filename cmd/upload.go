@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"vault-cli/internal/core"
 
@@ -13,12 +12,13 @@ var uploadCmd = &cobra.Command{
 	Use:   "upload <file>",
 	Short: "Encrypt and upload a file to S3 or local vault",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		file := args[0]
-		if err := core.UploadHandler(file, cfg, database); err != nil {
-			log.Fatalf("upload failed: %v", err)
+		if err := core.UploadHandler(file, quiet, cfg, database); err != nil {
+			return fmt.Errorf("upload failed: %w", err)
 		}
-		fmt.Println("File uploaded successfully.")
+		note("File uploaded successfully.")
+		return nil
 	},
 }
 
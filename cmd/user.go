@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/db"
+	"vault-cli/internal/output"
+	"vault-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+var unlockIP string
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage login lockouts",
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "unlock [user]",
+	Short: "Clear failed-login counters for a user (default admin) or an IP",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		key := auth.UserKey(auth.MasterUser)
+		if len(args) == 1 {
+			key = auth.UserKey(args[0])
+		}
+		if unlockIP != "" {
+			key = auth.IPKey(unlockIP)
+		}
+		found, err := db.ClearLoginAttempts(database, key)
+		if err != nil {
+			return err
+		}
+		_ = db.RecordAudit(database, "login:unlock", key, "cli", true, "")
+		if !found {
+			note("No failed attempts recorded for %s.", key)
+			return nil
+		}
+		note("Unlocked %s.", key)
+		return nil
+	},
+}
+
+var userLockoutsCmd = &cobra.Command{
+	Use:   "lockouts",
+	Short: "List users and IPs with failed login attempts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := session.Require(); err != nil {
+			return err
+		}
+		items, err := db.ListLoginAttempts(database)
+		if err != nil {
+			return err
+		}
+		type lockout struct {
+			db.LoginAttempt
+			Locked bool `json:"locked"`
+		}
+		lockouts := []lockout{}
+		rows := output.Rows{Header: []string{"key", "failures", "last_failure", "locked_until"}}
+		for _, a := range items {
+			locked := time.Now().Before(a.LockedUntil)
+			until := ""
+			if locked {
+				until = a.LockedUntil.Local().Format(time.RFC3339)
+			}
+			lockouts = append(lockouts, lockout{a, locked})
+			rows.Rows = append(rows.Rows, []string{a.Key, strconv.Itoa(a.Failures), a.LastFailure.Local().Format(time.RFC3339), until})
+		}
+		return render(lockouts, rows)
+	},
+}
+
+func init() {
+	userUnlockCmd.Flags().StringVar(&unlockIP, "ip", "", "unlock a client IP instead of a user")
+	userCmd.AddCommand(userUnlockCmd, userLockoutsCmd)
+	rootCmd.AddCommand(userCmd)
+}
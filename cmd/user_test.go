@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/db"
+	"vault-cli/internal/session"
+)
+
+func TestUserCommandsNeedSession(t *testing.T) {
+	testVault(t)
+	l := auth.NewLimiter(database)
+	if err := l.Attempt(auth.UserKey(auth.MasterUser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		run  func() error
+	}{
+		{"unlock", func() error { return userUnlockCmd.RunE(userUnlockCmd, nil) }},
+		{"lockouts", func() error { return userLockoutsCmd.RunE(userLockoutsCmd, nil) }},
+	} {
+		if err := c.run(); !errors.Is(err, session.ErrNoSession) {
+			t.Errorf("user %s without a session: got %v", c.name, err)
+		}
+	}
+	if a, _ := db.GetLoginAttempt(database, auth.UserKey(auth.MasterUser)); a.Failures != 1 {
+		t.Fatalf("failures = %d, want the attempt kept", a.Failures)
+	}
+}
@@ -0,0 +1,311 @@
+package agent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/aws"
+	"vault-cli/internal/config"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+	"vault-cli/internal/secrets"
+)
+
+var ErrLocked = errors.New("agent is locked")
+
+type Request struct {
+	Op       string `json:"op"`
+	Category string `json:"category,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+
+	Type         string `json:"type,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	RotateEvery  string `json:"rotate_every,omitempty"`
+	AllowExpired bool   `json:"allow_expired,omitempty"`
+}
+
+type Response struct {
+	OK      bool             `json:"ok"`
+	Error   string           `json:"error,omitempty"`
+	Kind    string           `json:"kind,omitempty"`
+	Value   string           `json:"value,omitempty"`
+	Secrets []secrets.Secret `json:"secrets,omitempty"`
+	Status  *Status          `json:"status,omitempty"`
+}
+
+type Status struct {
+	PID          int       `json:"pid"`
+	Locked       bool      `json:"locked"`
+	Mode         string    `json:"mode"`
+	IdleTimeout  string    `json:"idle_timeout"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+type Agent struct {
+	cfg    *config.Config
+	db     *sql.DB
+	socket string
+	idle   time.Duration
+
+	mu       sync.Mutex
+	locked   bool
+	lastUsed time.Time
+	ln       net.Listener
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func SocketPath() string {
+	if p := os.Getenv("VAULT_AGENT_SOCK"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("vault-agent-%d", os.Getuid()), "agent.sock")
+	}
+	return filepath.Join(home, ".vault", "agent.sock")
+}
+
+func New(cfg *config.Config, database *sql.DB, socket string, idle time.Duration) *Agent {
+	return &Agent{
+		cfg:      cfg,
+		db:       database,
+		socket:   socket,
+		idle:     idle,
+		locked:   true,
+		lastUsed: time.Now(),
+		done:     make(chan struct{}),
+	}
+}
+
+func (a *Agent) Unlock(password, code string) error {
+	if a.cfg.RequirePassword || keyring.Initialized() {
+		if err := mfa.Login(a.db, a.cfg, "", password, code); err != nil {
+			return err
+		}
+	} else if err := mfa.VerifyLogin(a.db, a.cfg, auth.MasterUser, "", code); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.locked = false
+	a.lastUsed = time.Now()
+	return nil
+}
+
+func (a *Agent) Lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.locked = true
+	aws.PurgeKeyCache()
+	keyring.Lock()
+}
+
+func (a *Agent) Serve() error {
+	if err := os.MkdirAll(filepath.Dir(a.socket), 0700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(a.socket); err == nil {
+		if conn, err := net.DialTimeout("unix", a.socket, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("agent already running on %s", a.socket)
+		}
+		_ = os.Remove(a.socket)
+	}
+
+	ln, err := listen(a.socket)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", a.socket, err)
+	}
+	a.ln = ln
+	defer os.Remove(a.socket)
+
+	aws.EnableKeyCache()
+	if a.cfg.Mode != "local" {
+		_ = aws.Warm()
+	}
+	if a.idle > 0 {
+		go a.watchIdle()
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.Lock()
+		if a.ln != nil {
+			a.ln.Close()
+		}
+	})
+}
+
+func (a *Agent) watchIdle() {
+	interval := a.idle / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-t.C:
+			a.mu.Lock()
+			expired := !a.locked && time.Since(a.lastUsed) > a.idle
+			a.mu.Unlock()
+			if expired {
+				a.Lock()
+			}
+		}
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: "invalid request"})
+		return
+	}
+	resp := a.dispatch(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+	if req.Op == "stop" && resp.OK {
+		go a.Stop()
+	}
+}
+
+func (a *Agent) dispatch(req Request) Response {
+	switch req.Op {
+	case "status":
+		return Response{OK: true, Status: a.status()}
+	case "lock":
+		a.Lock()
+		return Response{OK: true}
+	case "unlock":
+		if err := a.Unlock(req.Password, req.Code); err != nil {
+			return failure(err)
+		}
+		return Response{OK: true}
+	case "stop":
+		return Response{OK: true}
+	}
+
+	if err := a.touch(); err != nil {
+		return failure(err)
+	}
+
+	switch req.Op {
+	case "get-secret":
+		get := secrets.Get
+		if req.AllowExpired {
+			get = secrets.GetIncludingExpired
+		}
+		val, err := get(a.db, a.cfg, req.Category, req.Name)
+		if err != nil {
+			return failure(err)
+		}
+		return Response{OK: true, Value: val}
+	case "list-secrets":
+		items, err := secrets.List(a.db, req.Category)
+		if err != nil {
+			return failure(err)
+		}
+		return Response{OK: true, Secrets: items}
+	case "add-secret":
+		s := secrets.Secret{Category: req.Category, Name: req.Name, Type: req.Type, Value: req.Value, ExpiresAt: req.ExpiresAt, RotateEvery: req.RotateEvery}
+		if err := secrets.Add(a.db, a.cfg, s); err != nil {
+			return failure(err)
+		}
+		return Response{OK: true}
+	case "delete-secret":
+		if err := secrets.Delete(a.db, req.Category, req.Name); err != nil {
+			return failure(err)
+		}
+		return Response{OK: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown op %q", req.Op)}
+	}
+}
+
+// errorKinds lets sentinel errors survive the trip over the socket; the
+// client wraps the message back around the matching sentinel.
+var errorKinds = []struct {
+	kind string
+	err  error
+}{
+	{"agent_locked", ErrLocked},
+	{"vault_locked", keyring.ErrLocked},
+	{"unauthorized", auth.ErrUnauthorized},
+	{"unauthorized", keyring.ErrWrongPassword},
+	{"unauthorized", mfa.ErrCodeRequired},
+	{"unauthorized", mfa.ErrInvalidCode},
+	{"not_found", secrets.ErrNotFound},
+	{"secret_expired", secrets.ErrExpired},
+	{"integrity", secrets.ErrIntegrity},
+	{"integrity", aws.ErrIntegrity},
+	{"backend_unavailable", aws.ErrBackendUnavailable},
+}
+
+func failure(err error) Response {
+	resp := Response{Error: err.Error()}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			resp.Kind = k.kind
+			break
+		}
+	}
+	return resp
+}
+
+func (a *Agent) touch() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	a.lastUsed = time.Now()
+	return nil
+}
+
+func (a *Agent) status() *Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	idle := "none"
+	if a.idle > 0 {
+		idle = a.idle.String()
+	}
+	return &Status{
+		PID:          os.Getpid(),
+		Locked:       a.locked,
+		Mode:         a.cfg.Mode,
+		IdleTimeout:  idle,
+		LastActivity: a.lastUsed,
+	}
+}
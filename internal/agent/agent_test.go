@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"vault-cli/internal/config"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/secrets"
+)
+
+func startAgent(t *testing.T) (*Agent, string) {
+	t.Helper()
+	dir := t.TempDir()
+	database := dbtest.Open(t)
+	cfg := &config.Config{Mode: "local"}
+	if err := secrets.Add(database, cfg, secrets.Secret{Category: "prod", Name: "token", Value: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "run", "agent.sock")
+	a := New(cfg, database, sock, 0)
+	errc := make(chan error, 1)
+	go func() { errc <- a.Serve() }()
+	t.Cleanup(func() {
+		a.Stop()
+		if err := <-errc; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	for i := 0; ; i++ {
+		if _, err := Dial(sock); err == nil {
+			break
+		} else if i == 50 {
+			t.Fatalf("agent did not come up: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return a, sock
+}
+
+func TestLockedAgentRefusesSecrets(t *testing.T) {
+	_, sock := startAgent(t)
+	c, err := Dial(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Locked {
+		t.Fatal("new agent should start locked")
+	}
+	if _, err := c.GetSecret("prod", "token"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("GetSecret on locked agent: got %v, want ErrLocked", err)
+	}
+
+	if err := c.Unlock("", ""); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.GetSecret("prod", "token")
+	if err != nil || v != "s3cret" {
+		t.Fatalf("GetSecret = %q, %v", v, err)
+	}
+	if _, err := c.GetSecret("prod", "missing"); !errors.Is(err, secrets.ErrNotFound) {
+		t.Fatalf("missing secret: got %v, want ErrNotFound", err)
+	}
+
+	if err := c.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSecret("prod", "token"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("GetSecret after Lock: got %v, want ErrLocked", err)
+	}
+}
+
+func TestSocketPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	_, sock := startAgent(t)
+	fi, err := os.Stat(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Fatalf("socket mode = %v, want 0600", perm)
+	}
+}
+
+func TestListenRefusesSharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err := listen(filepath.Join(dir, "agent.sock")); err == nil {
+		ln.Close()
+		t.Fatal("listen in a world-writable directory should fail")
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"vault-cli/internal/secrets"
+)
+
+type Client struct {
+	socket string
+}
+
+func Dial(socket string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return &Client{socket: socket}, nil
+}
+
+func (c *Client) call(req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		for _, k := range errorKinds {
+			if k.kind == resp.Kind {
+				return nil, &remoteError{msg: resp.Error, kind: k.err}
+			}
+		}
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// remoteError carries the agent's message while still matching the
+// sentinel it was reported under.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.kind }
+
+func (c *Client) Status() (*Status, error) {
+	resp, err := c.call(Request{Op: "status"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+func (c *Client) Lock() error {
+	_, err := c.call(Request{Op: "lock"})
+	return err
+}
+
+func (c *Client) Unlock(password, code string) error {
+	_, err := c.call(Request{Op: "unlock", Password: password, Code: code})
+	return err
+}
+
+func (c *Client) Stop() error {
+	_, err := c.call(Request{Op: "stop"})
+	return err
+}
+
+func (c *Client) GetSecret(category, name string) (string, error) {
+	return c.getSecret(Request{Op: "get-secret", Category: category, Name: name})
+}
+
+// GetSecretIncludingExpired is GetSecret without the expiry check.
+func (c *Client) GetSecretIncludingExpired(category, name string) (string, error) {
+	return c.getSecret(Request{Op: "get-secret", Category: category, Name: name, AllowExpired: true})
+}
+
+func (c *Client) getSecret(req Request) (string, error) {
+	resp, err := c.call(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Value, nil
+}
+
+func (c *Client) ListSecrets(category string) ([]secrets.Secret, error) {
+	resp, err := c.call(Request{Op: "list-secrets", Category: category})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Secrets, nil
+}
+
+func (c *Client) AddSecret(s secrets.Secret) error {
+	_, err := c.call(Request{Op: "add-secret", Category: s.Category, Name: s.Name, Type: s.Type, Value: s.Value, ExpiresAt: s.ExpiresAt, RotateEvery: s.RotateEvery})
+	return err
+}
+
+func (c *Client) DeleteSecret(category, name string) error {
+	_, err := c.call(Request{Op: "delete-secret", Category: category, Name: name})
+	return err
+}
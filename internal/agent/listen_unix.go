@@ -0,0 +1,42 @@
+//go:build !windows
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// listen creates the socket with a 0077 umask, so it is never reachable
+// by other users, not even between bind and chmod. Its directory must
+// belong to the current user and be writable only by them, or someone
+// else could swap the socket out from under clients.
+func listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) != os.Getuid() {
+		return nil, fmt.Errorf("socket directory %s is not owned by the current user", dir)
+	}
+	if fi.Mode().Perm()&0022 != 0 {
+		return nil, fmt.Errorf("socket directory %s is writable by other users (mode %v)", dir, fi.Mode().Perm())
+	}
+
+	old := syscall.Umask(0077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
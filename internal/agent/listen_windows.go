@@ -0,0 +1,11 @@
+//go:build windows
+
+package agent
+
+import "net"
+
+// listen relies on the directory ACLs Windows applies to the user's
+// profile; there is no umask to tighten.
+func listen(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,232 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tokens look like "vk_<id>_<secret>". Only sha256(secret) is stored; the
+// secret has 256 bits of entropy so a slow hash adds nothing.
+const prefix = "vk_"
+
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrExpired    = errors.New("api key expired")
+	ErrRevoked    = errors.New("api key revoked")
+	ErrNotFound   = errors.New("api key not found")
+)
+
+var Actions = []string{"read", "write", "delete", "upload", "download", "audit", "*"}
+
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *Key) Identity() string { return "apikey:" + k.ID }
+
+// Allows reports whether any scope grants action on resource. A scope is
+// "<action>:<pattern>"; "*" in the pattern matches any run of characters,
+// including "/", so "read:prod/*" covers every secret under prod/.
+func (k *Key) Allows(action, resource string) bool {
+	for _, s := range k.Scopes {
+		a, pattern, ok := strings.Cut(s, ":")
+		if !ok {
+			continue
+		}
+		if (a == action || a == "*") && match(pattern, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateScope(scope string) error {
+	a, pattern, ok := strings.Cut(scope, ":")
+	if !ok || pattern == "" {
+		return fmt.Errorf("invalid scope %q: want <action>:<pattern>", scope)
+	}
+	for _, v := range Actions {
+		if a == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid scope %q: action must be one of %s", scope, strings.Join(Actions, ", "))
+}
+
+func Create(database *sql.DB, name string, scopes []string, ttl time.Duration) (string, *Key, error) {
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	for _, s := range scopes {
+		if err := ValidateScope(s); err != nil {
+			return "", nil, err
+		}
+	}
+
+	idBytes := make([]byte, 5)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	id := strings.ToLower(base32.StdEncoding.EncodeToString(idBytes))
+	secretStr := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	k := &Key{ID: id, Name: name, Scopes: scopes, CreatedAt: now}
+	expires := ""
+	if ttl > 0 {
+		e := now.Add(ttl)
+		k.ExpiresAt = &e
+		expires = e.Format(time.RFC3339)
+	}
+
+	_, err := database.Exec(`INSERT INTO api_keys(id, name, hash, scopes, created_at, expires_at, last_used_at, revoked_at)
+		VALUES(?,?,?,?,?,?,'','')`, id, name, hashSecret(secretStr), strings.Join(scopes, " "), now.Format(time.RFC3339), expires)
+	if err != nil {
+		return "", nil, err
+	}
+	return prefix + id + "_" + secretStr, k, nil
+}
+
+func Authenticate(database *sql.DB, token string) (*Key, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(token), prefix)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	k, hash, err := load(database, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if k.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	_, _ = database.Exec(`UPDATE api_keys SET last_used_at=? WHERE id=?`, time.Now().UTC().Format(time.RFC3339), id)
+	return k, nil
+}
+
+func List(database *sql.DB) ([]Key, error) {
+	rows, err := database.Query(`SELECT id, name, scopes, created_at, expires_at, last_used_at, revoked_at FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Key
+	for rows.Next() {
+		k, err := scanKey(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *k)
+	}
+	return items, rows.Err()
+}
+
+func Revoke(database *sql.DB, id string) error {
+	res, err := database.Exec(`UPDATE api_keys SET revoked_at=? WHERE id=? AND revoked_at=''`, time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func load(database *sql.DB, id string) (*Key, string, error) {
+	var hash string
+	row := database.QueryRow(`SELECT id, name, scopes, created_at, expires_at, last_used_at, revoked_at, hash FROM api_keys WHERE id=?`, id)
+	k, err := scanKey(func(dest ...any) error {
+		return row.Scan(append(dest, &hash)...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return k, hash, nil
+}
+
+func scanKey(scan func(dest ...any) error) (*Key, error) {
+	var k Key
+	var scopes, created, expires, used, revoked string
+	if err := scan(&k.ID, &k.Name, &scopes, &created, &expires, &used, &revoked); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.CreatedAt, _ = time.Parse(time.RFC3339, created)
+	k.ExpiresAt = parseTime(expires)
+	k.LastUsedAt = parseTime(used)
+	k.RevokedAt = parseTime(revoked)
+	return &k, nil
+}
+
+func parseTime(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func match(pattern, s string) bool {
+	if pattern == "*" {
+		return true
+	}
+	head, tail, wild := strings.Cut(pattern, "*")
+	if !wild {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, head) {
+		return false
+	}
+	s = s[len(head):]
+	for i := 0; i <= len(s); i++ {
+		if match(tail, s[i:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"vault-cli/internal/db/dbtest"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	database := dbtest.Open(t)
+	token, k, err := Create(database, "ci", []string{"read:prod/*"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, prefix+k.ID+"_") {
+		t.Fatalf("token %q does not carry the key id", token)
+	}
+
+	var stored string
+	if err := database.QueryRow(`SELECT hash FROM api_keys WHERE id=?`, k.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	secret := strings.TrimPrefix(token, prefix+k.ID+"_")
+	if stored == secret || strings.Contains(stored, secret) || stored != hashSecret(secret) {
+		t.Fatal("the stored hash is not sha256 of the secret")
+	}
+
+	got, err := Authenticate(database, " "+token+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != k.ID || got.Name != "ci" || len(got.Scopes) != 1 || got.Scopes[0] != "read:prod/*" {
+		t.Fatalf("Authenticate returned %+v", got)
+	}
+}
+
+func TestAuthenticateRejectsBadTokens(t *testing.T) {
+	database := dbtest.Open(t)
+	token, k, err := Create(database, "ci", []string{"*:*"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := token[len(token)-1]
+	flipped := token[:len(token)-1] + string(last^1)
+	other, _, err := Create(database, "other", []string{"*:*"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := prefix + k.ID + "_" + other[strings.LastIndex(other, "_")+1:]
+
+	for name, tok := range map[string]string{
+		"empty":          "",
+		"no prefix":      strings.TrimPrefix(token, prefix),
+		"no secret":      prefix + k.ID,
+		"unknown id":     prefix + "aaaaaaaa_" + token[strings.LastIndex(token, "_")+1:],
+		"changed secret": flipped,
+		"truncated":      token[:len(token)-4],
+		"other's secret": swapped,
+	} {
+		if _, err := Authenticate(database, tok); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: got %v, want ErrInvalidKey", name, err)
+		}
+	}
+}
+
+func TestRevokedAndExpiredKeys(t *testing.T) {
+	database := dbtest.Open(t)
+	token, k, err := Create(database, "ci", []string{"read:*"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	if _, err := database.Exec(`UPDATE api_keys SET expires_at=? WHERE id=?`, past, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Authenticate(database, token); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired key: got %v", err)
+	}
+
+	if err := Revoke(database, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Authenticate(database, token); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("revoked key: got %v", err)
+	}
+	if err := Revoke(database, k.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("revoking twice: got %v", err)
+	}
+}
+
+func TestCreateValidatesScopes(t *testing.T) {
+	database := dbtest.Open(t)
+	for _, scopes := range [][]string{nil, {"read"}, {"read:"}, {"steal:*"}} {
+		if _, _, err := Create(database, "ci", scopes, 0); err == nil {
+			t.Errorf("Create accepted scopes %q", scopes)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	k := &Key{Scopes: []string{"read:prod/*", "write:staging/db/password", "*:dev/*", "download:*.tar.gz"}}
+	for _, tc := range []struct {
+		action, resource string
+		want             bool
+	}{
+		{"read", "prod/db/password", true},
+		{"read", "prod", false},
+		{"read", "production/x", false},
+		{"write", "prod/db/password", false},
+		{"write", "staging/db/password", true},
+		{"write", "staging/db/password2", false},
+		{"delete", "dev/anything/at/all", true},
+		{"download", "backup.tar.gz", true},
+		{"download", "backup.tar.gz.sig", false},
+		{"upload", "backup.tar.gz", false},
+	} {
+		if got := k.Allows(tc.action, tc.resource); got != tc.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tc.action, tc.resource, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"a*c", "abc", true},
+		{"a*c", "ac", true},
+		{"a*c", "acb", false},
+		{"*b*", "abc", true},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXcYb", false},
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+	} {
+		if got := match(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
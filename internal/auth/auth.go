@@ -4,6 +4,7 @@ import (
     "bufio"
     "errors"
     "fmt"
+    "io"
     "os"
     "strings"
 
@@ -11,32 +12,62 @@ import (
     "golang.org/x/term"
 )
 
-func VerifyPassword(passFile string) bool {
-	fmt.Print("Enter master password: ")
-
-	var pw []byte
-	var err error
+func PromptPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
 
 	if term.IsTerminal(int(os.Stdin.Fd())) {
-		pw, err = term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Println()
-	} else {
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		pw = []byte(strings.TrimSpace(input))
+		pw, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(pw), nil
 	}
 
+	input, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(input), nil
+}
+
+// stdin is shared so consecutive prompts on piped input each get their
+// own line instead of the first reader buffering everything.
+var stdin = bufio.NewReader(os.Stdin)
+
+// ReadStdin reads the rest of standard input, after anything earlier
+// prompts consumed.
+func ReadStdin() ([]byte, error) {
+	return io.ReadAll(stdin)
+}
+
+const MinPasswordLength = 8
+
+// PromptNewPassword asks for a new master password twice and checks the
+// two entries match.
+func PromptNewPassword() (string, error) {
+	return PromptNew("master password")
+}
+
+// PromptNew is PromptNewPassword for any password-like value, e.g.
+// "bundle passphrase".
+func PromptNew(what string) (string, error) {
+	pw, err := PromptPassword("New " + what + ": ")
+	if err != nil {
+		return "", err
+	}
+	if len(pw) < MinPasswordLength {
+		return "", fmt.Errorf("%s must be at least %d characters", what, MinPasswordLength)
+	}
+	confirm, err := PromptPassword("Confirm " + what + ": ")
 	if err != nil {
-		fmt.Println("error reading password:", err)
-		return false
+		return "", err
 	}
+	if pw != confirm {
+		return "", fmt.Errorf("%s entries do not match", what)
+	}
+	return pw, nil
+}
 
-    ok, err := CheckPassword(passFile, string(pw))
-    if err != nil {
-        fmt.Println("error verifying password:", err)
-        return false
-    }
-    return ok
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 }
 
 func CheckPassword(passFile, password string) (bool, error) {
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vault-cli/internal/db"
+)
+
+const MasterUser = "admin"
+
+// ErrUnauthorized is the root of every authentication failure, so callers
+// can test for it without caring which check failed.
+var (
+	ErrUnauthorized    = errors.New("access denied")
+	ErrInvalidPassword = fmt.Errorf("%w: wrong password", ErrUnauthorized)
+	ErrLockedOut       = fmt.Errorf("%w: too many failed login attempts", ErrUnauthorized)
+)
+
+type LockoutError struct {
+	Key   string
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%v for %s; retry in %s", ErrLockedOut, e.Key, e.RetryAfter().Round(time.Second))
+}
+
+func (e *LockoutError) Unwrap() error { return ErrLockedOut }
+
+func (e *LockoutError) RetryAfter() time.Duration {
+	d := time.Until(e.Until)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// Limiter tracks failed logins per key ("user:<name>", "ip:<addr>").
+// The first FreeAttempts failures cost nothing; after that each failure
+// doubles the wait from BaseDelay up to MaxDelay, and LockoutAfter
+// failures lock a client address for LockoutFor. User keys only ever
+// wait up to MaxDelay: a hard lock on a user would let anyone on the
+// network lock its owner out. Counters are forgotten after ResetAfter
+// without failures.
+type Limiter struct {
+	DB           *sql.DB
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	LockoutFor   time.Duration
+	ResetAfter   time.Duration
+	Now          func() time.Time
+}
+
+func NewLimiter(database *sql.DB) *Limiter {
+	return &Limiter{
+		DB:           database,
+		FreeAttempts: 3,
+		BaseDelay:    2 * time.Second,
+		MaxDelay:     5 * time.Minute,
+		LockoutAfter: 10,
+		LockoutFor:   15 * time.Minute,
+		ResetAfter:   24 * time.Hour,
+		Now:          time.Now,
+	}
+}
+
+func UserKey(user string) string { return "user:" + user }
+func IPKey(ip string) string     { return "ip:" + ip }
+
+// Attempt reserves a login attempt before the credentials are checked.
+// It fails if any key is still waiting out a delay, and otherwise counts
+// the attempt as a failure straight away, in the same transaction, so
+// concurrent guesses cannot all pass the check before the first failure
+// lands. A successful login calls Reset (or Release, when another factor
+// is still to come).
+func (l *Limiter) Attempt(keys ...string) error {
+	now := l.Now()
+	var locked *LockoutError
+	err := db.UpdateLoginAttempt(l.DB, keys, func(a *db.LoginAttempt) (bool, error) {
+		if locked != nil {
+			return false, nil
+		}
+		if now.Before(a.LockedUntil) {
+			locked = &LockoutError{Key: a.Key, Until: a.LockedUntil}
+			return false, nil
+		}
+		if !a.LastFailure.IsZero() && now.Sub(a.LastFailure) > l.ResetAfter {
+			a.Failures = 0
+		}
+		a.Failures++
+		a.LastFailure = now
+		a.LockedUntil = now.Add(l.delay(a.Key, a.Failures))
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if locked != nil {
+		// Undo the keys counted before the locked one was reached.
+		var counted []string
+		for _, k := range keys {
+			if k == locked.Key {
+				break
+			}
+			counted = append(counted, k)
+		}
+		if err := l.Release(counted...); err != nil {
+			return err
+		}
+		return locked
+	}
+	return nil
+}
+
+// Release takes back an attempt reserved by Attempt without clearing the
+// earlier failures.
+func (l *Limiter) Release(keys ...string) error {
+	return db.UpdateLoginAttempt(l.DB, keys, func(a *db.LoginAttempt) (bool, error) {
+		if a.Failures == 0 {
+			return false, nil
+		}
+		a.Failures--
+		a.LockedUntil = a.LastFailure.Add(l.delay(a.Key, a.Failures))
+		return true, nil
+	})
+}
+
+func (l *Limiter) Reset(keys ...string) error {
+	for _, k := range keys {
+		if _, err := db.ClearLoginAttempts(l.DB, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) delay(key string, failures int) time.Duration {
+	if failures >= l.LockoutAfter && !strings.HasPrefix(key, "user:") {
+		return l.LockoutFor
+	}
+	if failures < l.FreeAttempts {
+		return 0
+	}
+	d := l.BaseDelay << (failures - l.FreeAttempts)
+	if d <= 0 || d > l.MaxDelay {
+		d = l.MaxDelay
+	}
+	return d
+}
+
+// SecondFactor reports whether user has another factor to pass after the
+// password. The mfa package sets it; auth cannot import mfa.
+var SecondFactor func(database *sql.DB, user string) (bool, error)
+
+// Authenticate checks password against passFile for user, enforcing the
+// limiter for the user and, when ip is non-empty, the client address.
+// Failed and blocked attempts are written to the audit log. When user has
+// a second factor, a correct password does not clear the counters; the
+// second factor's check does that once it passes.
+func Authenticate(database *sql.DB, passFile, user, ip, password string) error {
+	l := NewLimiter(database)
+	keys := []string{UserKey(user)}
+	source := "cli"
+	if ip != "" {
+		keys = append(keys, IPKey(ip))
+		source = ip
+	}
+
+	if err := l.Attempt(keys...); err != nil {
+		_ = db.RecordAudit(database, "login:blocked", user, source, false, err.Error())
+		return err
+	}
+
+	ok, err := CheckPassword(passFile, password)
+	if err != nil {
+		_ = l.Release(keys...)
+		return err
+	}
+	if !ok {
+		_ = db.RecordAudit(database, "login:failed", user, source, false, "invalid credentials")
+		return ErrInvalidPassword
+	}
+
+	if SecondFactor != nil {
+		enrolled, err := SecondFactor(database, user)
+		if err != nil {
+			_ = l.Release(keys...)
+			return err
+		}
+		if enrolled {
+			// Only the second factor clears the counters; until it passes
+			// the password attempt is taken back but earlier failures stay.
+			_ = db.RecordAudit(database, "login:password", user, source, true, "")
+			return l.Release(keys...)
+		}
+	}
+	_ = db.RecordAudit(database, "login", user, source, true, "")
+	return l.Reset(keys...)
+}
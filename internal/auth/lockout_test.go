@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/db/dbtest"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func testLimiter(t *testing.T) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(dbtest.Open(t))
+	l.Now = c.now
+	return l, c
+}
+
+func TestDelaySchedule(t *testing.T) {
+	l := NewLimiter(nil)
+	for _, tc := range []struct {
+		key      string
+		failures int
+		want     time.Duration
+	}{
+		{"ip:a", 1, 0},
+		{"ip:a", 2, 0},
+		{"ip:a", 3, 2 * time.Second},
+		{"ip:a", 4, 4 * time.Second},
+		{"ip:a", 9, 128 * time.Second},
+		{"ip:a", 10, 15 * time.Minute},
+		{"user:admin", 10, 256 * time.Second},
+		{"user:admin", 100, 5 * time.Minute},
+	} {
+		if got := l.delay(tc.key, tc.failures); got != tc.want {
+			t.Errorf("delay(%s, %d) = %v, want %v", tc.key, tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestAttemptBacksOff(t *testing.T) {
+	l, c := testLimiter(t)
+	for i := 0; i < 3; i++ {
+		if err := l.Attempt("ip:a"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	var lock *LockoutError
+	if err := l.Attempt("ip:a"); !errors.As(err, &lock) || !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("fourth attempt: got %v, want a LockoutError", err)
+	}
+	c.advance(2 * time.Second)
+	if err := l.Attempt("ip:a"); err != nil {
+		t.Fatalf("after the delay: %v", err)
+	}
+	if err := l.Reset("ip:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Attempt("ip:a"); err != nil {
+		t.Fatalf("after Reset: %v", err)
+	}
+}
+
+func TestReleaseKeepsEarlierFailures(t *testing.T) {
+	l, _ := testLimiter(t)
+	for i := 0; i < 2; i++ {
+		if err := l.Attempt("user:admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Release("user:admin"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := db.GetLoginAttempt(l.DB, "user:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Failures != 1 {
+		t.Fatalf("failures after Release = %d, want 1", a.Failures)
+	}
+}
+
+func TestLockedKeyDoesNotChargeOthers(t *testing.T) {
+	l, _ := testLimiter(t)
+	for i := 0; i < 3; i++ {
+		if err := l.Attempt("ip:b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Attempt("user:admin", "ip:b"); err == nil {
+		t.Fatal("attempt from a locked address should fail")
+	}
+	a, _ := db.GetLoginAttempt(l.DB, "user:admin")
+	if a.Failures != 0 {
+		t.Fatalf("user failures = %d, want 0", a.Failures)
+	}
+}
+
+func TestConcurrentAttemptsAreCounted(t *testing.T) {
+	l := NewLimiter(dbtest.Open(t))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Attempt("ip:c") == nil {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if passed != l.FreeAttempts {
+		t.Fatalf("%d concurrent attempts passed, want %d", passed, l.FreeAttempts)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	database := dbtest.Open(t)
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passFile := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(passFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Authenticate(database, passFile, MasterUser, "10.0.0.1", "wrong"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+	if err := Authenticate(database, passFile, MasterUser, "10.0.0.1", "correct horse"); err != nil {
+		t.Fatalf("right password: %v", err)
+	}
+	for _, k := range []string{UserKey(MasterUser), IPKey("10.0.0.1")} {
+		a, _ := db.GetLoginAttempt(database, k)
+		if a.Failures != 0 {
+			t.Errorf("%s failures after a successful login = %d, want 0", k, a.Failures)
+		}
+	}
+}
+
+func TestAuthenticateLeavesCountersForSecondFactor(t *testing.T) {
+	database := dbtest.Open(t)
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passFile := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(passFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+	SecondFactor = func(*sql.DB, string) (bool, error) { return true, nil }
+	t.Cleanup(func() { SecondFactor = nil })
+
+	if err := Authenticate(database, passFile, MasterUser, "", "wrong"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := Authenticate(database, passFile, MasterUser, "", "correct horse"); err != nil {
+			t.Fatalf("right password: %v", err)
+		}
+	}
+	a, _ := db.GetLoginAttempt(database, UserKey(MasterUser))
+	if a.Failures != 1 {
+		t.Fatalf("failures after correct passwords = %d, want 1 until the second factor passes", a.Failures)
+	}
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Clients are cached for the life of the process so long-running callers
+// such as the agent and the web server reuse warm credentials and
+// connections instead of reloading the AWS config on every call.
+var (
+	clientMu  sync.Mutex
+	awsCfg    *aws.Config
+	kmsCached *kms.Client
+	s3Cached  *s3.Client
+)
+
+func loadAWSConfig() (aws.Config, error) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if awsCfg != nil {
+		return *awsCfg, nil
+	}
+	c, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return aws.Config{}, err
+	}
+	awsCfg = &c
+	return c, nil
+}
+
+func kmsClient() (*kms.Client, error) {
+	c, err := loadAWSConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if kmsCached == nil {
+		kmsCached = kms.NewFromConfig(c)
+	}
+	return kmsCached, nil
+}
+
+func s3Client() (*s3.Client, error) {
+	c, err := loadAWSConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if s3Cached == nil {
+		s3Cached = s3.NewFromConfig(c)
+	}
+	return s3Cached, nil
+}
+
+func Warm() error {
+	if _, err := kmsClient(); err != nil {
+		return err
+	}
+	_, err := s3Client()
+	return err
+}
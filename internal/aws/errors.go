@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/aws/smithy-go"
+
+	"vault-cli/internal/stream"
+)
+
+var (
+	ErrNotFound           = errors.New("file not found")
+	ErrIntegrity          = errors.New("integrity check failed")
+	ErrBackendUnavailable = errors.New("storage backend unavailable")
+)
+
+// classify tags an error from the AWS SDK or the stream decoder with the
+// sentinel callers branch on, keeping the original error in the chain.
+func classify(err error) error {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrIntegrity) || errors.Is(err, ErrBackendUnavailable) {
+		return err
+	}
+	if errors.Is(err, stream.ErrAuth) || errors.Is(err, stream.ErrFormat) {
+		return fmt.Errorf("%w: %w", ErrIntegrity, err)
+	}
+
+	var api smithy.APIError
+	if errors.As(err, &api) {
+		switch api.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchVersion":
+			return fmt.Errorf("%w: %w", ErrNotFound, err)
+		case "InvalidCiphertextException", "IncorrectKeyException":
+			return fmt.Errorf("%w: %w", ErrIntegrity, err)
+		case "ServiceUnavailable", "SlowDown", "InternalError", "RequestTimeout",
+			"ThrottlingException", "KMSInternalException", "DependencyTimeoutException", "KeyUnavailableException":
+			return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+		}
+		return err
+	}
+
+	// Anything that failed before AWS answered (no credentials, DNS,
+	// connection refused, timeouts) means the backend could not be reached.
+	var op *smithy.OperationError
+	var netErr net.Error
+	if errors.As(err, &op) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+	}
+	return err
+}
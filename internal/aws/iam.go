@@ -2,19 +2,29 @@ package aws
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-func WhoAmI() error {
-	cfg, _ := config.LoadDefaultConfig(context.TODO())
-	client := sts.NewFromConfig(cfg)
-	resp, err := client.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+type Identity struct {
+	Account string
+	UserID  string
+	ARN     string
+}
+
+func WhoAmI(ctx context.Context) (*Identity, error) {
+	cfg, err := loadAWSConfig()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	fmt.Printf("Account: %s\nUserID: %s\nARN: %s\n", *resp.Account, *resp.UserId, *resp.Arn)
-	return nil
+	return &Identity{
+		Account: aws.ToString(resp.Account),
+		UserID:  aws.ToString(resp.UserId),
+		ARN:     aws.ToString(resp.Arn),
+	}, nil
 }
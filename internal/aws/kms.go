@@ -4,44 +4,96 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 )
 
 func GenerateDataKey(kmsKeyID string) ([]byte, []byte, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	client, err := kmsClient()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("%w: aws config: %w", ErrBackendUnavailable, err)
 	}
-	client := kms.NewFromConfig(cfg)
 	out, err := client.GenerateDataKey(context.TODO(), &kms.GenerateDataKeyInput{
 		KeyId:   aws.String(kmsKeyID),
 		KeySpec: types.DataKeySpecAes256,
 	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("generate data key: %w", err)
+		return nil, nil, classify(fmt.Errorf("generate data key: %w", err))
 	}
 	return out.Plaintext, out.CiphertextBlob, nil
 }
 
 func DecryptDataKey(encryptedKey []byte) ([]byte, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if key, ok := cachedKey(encryptedKey); ok {
+		return key, nil
+	}
+	client, err := kmsClient()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: aws config: %w", ErrBackendUnavailable, err)
 	}
-	client := kms.NewFromConfig(cfg)
 	out, err := client.Decrypt(context.TODO(), &kms.DecryptInput{
 		CiphertextBlob: encryptedKey,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("decrypt data key: %w", err)
+		return nil, classify(fmt.Errorf("decrypt data key: %w", err))
 	}
+	storeKey(encryptedKey, out.Plaintext)
 	return out.Plaintext, nil
 }
 
+// The key cache is off by default. The agent turns it on so unwrapped data
+// keys stay in its memory while unlocked, and purges it when it locks.
+var keyCache struct {
+	sync.Mutex
+	enabled bool
+	keys    map[string][]byte
+}
+
+func EnableKeyCache() {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	keyCache.enabled = true
+	if keyCache.keys == nil {
+		keyCache.keys = make(map[string][]byte)
+	}
+}
+
+func PurgeKeyCache() {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	for k, v := range keyCache.keys {
+		for i := range v {
+			v[i] = 0
+		}
+		delete(keyCache.keys, k)
+	}
+}
+
+func cachedKey(encryptedKey []byte) ([]byte, bool) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	if !keyCache.enabled {
+		return nil, false
+	}
+	key, ok := keyCache.keys[string(encryptedKey)]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), key...), true
+}
+
+func storeKey(encryptedKey, plainKey []byte) {
+	keyCache.Lock()
+	defer keyCache.Unlock()
+	if !keyCache.enabled {
+		return
+	}
+	keyCache.keys[string(encryptedKey)] = append([]byte(nil), plainKey...)
+}
+
 func LocalKey() ([]byte, []byte, error) {
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
@@ -5,196 +5,391 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 
 	"vault-cli/internal/config"
 	"vault-cli/internal/db"
+	"vault-cli/internal/progress"
+	"vault-cli/internal/stream"
 )
 
+// formatStream marks objects written with the chunked stream format; objects
+// without it are the original single-shot nonce||ciphertext blobs.
+const formatStream = "stream1"
+
 func sha256Sum(b []byte) string {
 	h := sha256.Sum256(b)
 	return fmt.Sprintf("%x", h[:])
 }
 
-func EncryptAndUpload(filePath string, cfg *config.Config, database *sql.DB) error {
-	data, err := os.ReadFile(filePath)
+func zeroKey(k []byte) {
+	for i := range k {
+		k[i] = 0
+	}
+}
+
+// Result describes a completed transfer.
+type Result struct {
+	Name      string
+	Hash      string // hex SHA-256 of the plaintext
+	Size      int64  // plaintext bytes
+	VersionID string // S3 object version, when the bucket is versioned
+	Location  string
+	Mode      string
+	Duration  time.Duration
+}
+
+// UploadFile uploads the file at path under its base name. The file is
+// hashed first so the hash can be stored with the object and checked again
+// as it is encrypted.
+func UploadFile(ctx context.Context, path string, cfg *config.Config, database *sql.DB, report progress.Func) (*Result, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, progress.NewReader(f, report, progress.Hashing, info.Size())); err != nil {
+		return nil, fmt.Errorf("hash file: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
 	}
+	return upload(ctx, filepath.Base(path), f, info.Size(), hex.EncodeToString(h.Sum(nil)), cfg, database, report)
+}
+
+// Upload encrypts src chunk by chunk while streaming it to S3 as name.
+// Nothing is buffered beyond the uploader's part size.
+func Upload(ctx context.Context, name string, src io.Reader, cfg *config.Config, database *sql.DB, report progress.Func) (*Result, error) {
+	return upload(ctx, name, src, -1, "", cfg, database, report)
+}
 
+func upload(ctx context.Context, name string, src io.Reader, total int64, knownHash string, cfg *config.Config, database *sql.DB, report progress.Func) (*Result, error) {
+	start := time.Now()
 	var plainKey, encryptedKey []byte
+	var err error
 	if cfg.Mode == "local" {
 		plainKey, encryptedKey, err = LocalKey()
 	} else {
 		plainKey, encryptedKey, err = GenerateDataKey(cfg.KmsKey)
 	}
 	if err != nil {
-		return fmt.Errorf("generate key: %w", err)
+		return nil, fmt.Errorf("generate key: %w", classify(err))
+	}
+
+	client, err := s3Client()
+	if err != nil {
+		zeroKey(plainKey)
+		return nil, fmt.Errorf("%w: aws config: %w", ErrBackendUnavailable, err)
 	}
-	defer func() {
-		for i := range plainKey {
-			plainKey[i] = 0
+
+	h := sha256.New()
+	var size int64
+	pr, pw := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer zeroKey(plainKey)
+		enc, err := stream.NewWriter(pw, plainKey)
+		if err == nil {
+			size, err = io.Copy(enc, progress.NewReader(io.TeeReader(src, h), report, progress.Encrypting, total))
+		}
+		if err == nil && knownHash != "" && hex.EncodeToString(h.Sum(nil)) != knownHash {
+			err = fmt.Errorf("%s changed while uploading", name)
+		}
+		if err == nil {
+			err = enc.Close()
 		}
+		if err == nil && report != nil {
+			// Encryption is done; what remains is the uploader flushing
+			// its last buffered part.
+			report(progress.Event{Stage: progress.Uploading, Bytes: size, Total: size, Elapsed: time.Since(start)})
+		}
+		pw.CloseWithError(err)
 	}()
 
-	block, err := aes.NewCipher(plainKey)
-	if err != nil {
-		return fmt.Errorf("cipher init: %w", err)
+	meta := map[string]string{
+		"encryptedkey":    base64.StdEncoding.EncodeToString(encryptedKey),
+		"encryption_mode": cfg.Mode,
+		"format":          formatStream,
+		"uploader":        os.Getenv("VAULT_USER_ID"),
+		"upload_ts":       time.Now().UTC().Format(time.RFC3339),
 	}
-	aesgcm, err := cipher.NewGCM(block)
+	if knownHash != "" {
+		meta["file_hash"] = knownHash
+	}
+	out, err := manager.NewUploader(client).Upload(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(cfg.Bucket),
+		Key:      aws.String(name),
+		Body:     pr,
+		Metadata: meta,
+	})
+	pr.CloseWithError(err)
+	wg.Wait()
 	if err != nil {
-		return fmt.Errorf("gcm init: %w", err)
+		if database != nil {
+			_ = db.RecordAudit(database, "upload", name, "s3", false, err.Error())
+			_ = RecordAuditToDynamo("upload", name, "s3", false, err.Error())
+		}
+		return nil, classify(fmt.Errorf("s3 put: %w", err))
 	}
 
-	nonce := make([]byte, aesgcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return fmt.Errorf("nonce generation: %w", err)
+	res := &Result{
+		Name:      name,
+		Hash:      hex.EncodeToString(h.Sum(nil)),
+		Size:      size,
+		VersionID: aws.ToString(out.VersionID),
+		Location:  fmt.Sprintf("s3://%s/%s", cfg.Bucket, name),
+		Mode:      cfg.Mode,
+		Duration:  time.Since(start),
 	}
-	ciphertext := aesgcm.Seal(nonce, nonce, data, nil)
+	if database != nil {
+		_ = db.RecordFile(database, name, res.Hash, size, "s3", cfg.Mode)
+		_ = db.RecordAudit(database, "upload", name, "s3", true, "")
+		_ = RecordFileToDynamo(name, res.Hash, size, cfg.Mode, "s3")
+		_ = RecordAuditToDynamo("upload", name, "s3", true, "")
+	}
+	_ = LogToCloudWatch(fmt.Sprintf("Uploaded %s (%d bytes) to bucket %s", name, size, cfg.Bucket))
+	return res, nil
+}
 
-	encodedKey := base64.StdEncoding.EncodeToString(encryptedKey)
-	hash := sha256Sum(data)
-	keyName := filepath.Base(filePath)
+// Download decrypts the stored file name into dst. On error dst may hold
+// a partial plaintext; callers writing to files should discard it.
+func Download(ctx context.Context, name string, dst io.Writer, cfg *config.Config, database *sql.DB, report progress.Func) (*Result, error) {
+	start := time.Now()
+	obj, err := Open(ctx, name, cfg, database)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
 
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	h := sha256.New()
+	n, err := io.Copy(progress.NewWriter(io.MultiWriter(dst, h), report, progress.Downloading, obj.Size), obj)
 	if err != nil {
-		return fmt.Errorf("aws config: %w", err)
+		return nil, classify(fmt.Errorf("decrypt %s: %w", obj.Name, err))
 	}
-	client := s3.NewFromConfig(awsCfg)
+	hash := hex.EncodeToString(h.Sum(nil))
+	if obj.fileHash != "" && obj.fileHash != hash {
+		return nil, fmt.Errorf("%w: hash mismatch for %s", ErrIntegrity, obj.Name)
+	}
+	return &Result{
+		Name:      obj.Name,
+		Hash:      hash,
+		Size:      n,
+		VersionID: obj.VersionID,
+		Location:  fmt.Sprintf("s3://%s/%s", cfg.Bucket, obj.Name),
+		Mode:      cfg.Mode,
+		Duration:  time.Since(start),
+	}, nil
+}
 
-	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(cfg.Bucket),
-		Key:    aws.String(keyName),
-		Body:   bytes.NewReader(ciphertext),
-		Metadata: map[string]string{
-			"encryptedkey":    encodedKey,
-			"encryption_mode": cfg.Mode,
-			"uploader":        os.Getenv("VAULT_USER_ID"),
-			"upload_ts":       time.Now().UTC().Format(time.RFC3339),
-			"file_hash":       hash,
-		},
-	})
+// Object is a decrypted, seekable view of a stored file. Stream-format
+// objects are fetched and decrypted lazily as they are read, so ranged
+// reads only download the chunks they cover.
+type Object struct {
+	io.ReadSeeker
+	Name      string
+	Size      int64
+	ModTime   time.Time
+	VersionID string
+
+	fileHash string
+	closer   io.Closer
+}
+
+func (o *Object) Close() error {
+	if o.closer == nil {
+		return nil
+	}
+	return o.closer.Close()
+}
+
+func Open(ctx context.Context, fileName string, cfg *config.Config, database *sql.DB) (*Object, error) {
+	name := filepath.Base(fileName)
+	obj, err := openObject(ctx, name, cfg)
 	if err != nil {
+		err = classify(err)
 		if database != nil {
-			_ = db.RecordAudit(database, "upload", keyName, "s3", false, err.Error())
-			_ = RecordAuditToDynamo("upload", keyName, "s3", false, err.Error())
+			_ = db.RecordAudit(database, "download", name, "s3", false, err.Error())
+			_ = RecordAuditToDynamo("download", name, "s3", false, err.Error())
 		}
-		return fmt.Errorf("s3 put: %w", err)
+		return nil, err
 	}
-
 	if database != nil {
-		info, _ := os.Stat(filePath)
-		_ = db.RecordFile(database, keyName, hash, info.Size(), "s3", cfg.Mode)
-		_ = db.RecordAudit(database, "upload", keyName, "s3", true, "")
-		_ = RecordFileToDynamo(keyName, hash, info.Size(), cfg.Mode, "s3")
-		_ = RecordAuditToDynamo("upload", keyName, "s3", true, "")
+		_ = db.RecordAudit(database, "download", name, "s3", true, "")
+		_ = RecordAuditToDynamo("download", name, "s3", true, "")
 	}
-
-	_ = LogToCloudWatch(fmt.Sprintf("Uploaded %s (%d bytes) to bucket %s", keyName, len(data), cfg.Bucket))
-	fmt.Printf("Uploaded %s successfully.\n", keyName)
-	return nil
+	_ = LogToCloudWatch(fmt.Sprintf("Downloaded and decrypted %s from bucket %s", name, cfg.Bucket))
+	return obj, nil
 }
 
-func DownloadAndDecrypt(fileName string, cfg *config.Config, database *sql.DB) error {
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+func openObject(ctx context.Context, name string, cfg *config.Config) (*Object, error) {
+	client, err := s3Client()
 	if err != nil {
-		return fmt.Errorf("aws config: %w", err)
+		return nil, fmt.Errorf("%w: aws config: %w", ErrBackendUnavailable, err)
 	}
-	client := s3.NewFromConfig(awsCfg)
-
-	out, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(cfg.Bucket),
-		Key:    aws.String(filepath.Base(fileName)),
+		Key:    aws.String(name),
 	})
 	if err != nil {
-		if database != nil {
-			_ = db.RecordAudit(database, "download", fileName, "s3", false, err.Error())
-			_ = RecordAuditToDynamo("download", fileName, "s3", false, err.Error())
+		return nil, fmt.Errorf("s3 head: %w", err)
+	}
+
+	plainKey, err := objectKey(head.Metadata, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer zeroKey(plainKey)
+
+	modTime := aws.ToTime(head.LastModified)
+	version := aws.ToString(head.VersionId)
+	if head.Metadata["format"] != formatStream {
+		plaintext, err := legacyDecrypt(ctx, client, name, plainKey, head.Metadata, cfg)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("s3 get: %w", err)
+		return &Object{ReadSeeker: bytes.NewReader(plaintext), Name: name, Size: int64(len(plaintext)), ModTime: modTime, VersionID: version}, nil
 	}
-	defer out.Body.Close()
 
-	data, err := io.ReadAll(out.Body)
+	ra := &objectReaderAt{ctx: ctx, client: client, bucket: cfg.Bucket, key: name, version: head.VersionId}
+	r, err := stream.NewReader(ra, aws.ToInt64(head.ContentLength), plainKey)
 	if err != nil {
-		return fmt.Errorf("read s3 object: %w", err)
+		ra.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
 	}
+	return &Object{ReadSeeker: r, Name: name, Size: r.Size(), ModTime: modTime, VersionID: version, fileHash: head.Metadata["file_hash"], closer: ra}, nil
+}
 
-	meta := out.Metadata
+func objectKey(meta map[string]string, cfg *config.Config) ([]byte, error) {
 	encodedKey := meta["encryptedkey"]
 	if encodedKey == "" {
-		return fmt.Errorf("missing encrypted key metadata for %s", fileName)
+		return nil, fmt.Errorf("%w: missing encrypted key metadata", ErrIntegrity)
 	}
-
 	encryptedKey, err := base64.StdEncoding.DecodeString(encodedKey)
 	if err != nil {
-		return fmt.Errorf("decode encrypted key: %w", err)
+		return nil, fmt.Errorf("%w: decode encrypted key: %v", ErrIntegrity, err)
 	}
-
-	var plainKey []byte
 	if meta["encryption_mode"] == "local" || cfg.Mode == "local" {
-		plainKey = encryptedKey
-	} else {
-		plainKey, err = DecryptDataKey(encryptedKey)
-		if err != nil {
-			return fmt.Errorf("decrypt data key: %w", err)
-		}
+		return encryptedKey, nil
+	}
+	plainKey, err := DecryptDataKey(encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key: %w", err)
+	}
+	return plainKey, nil
+}
+
+func legacyDecrypt(ctx context.Context, client *s3.Client, name string, plainKey []byte, meta map[string]string, cfg *config.Config) ([]byte, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cfg.Bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 get: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read s3 object: %w", err)
 	}
-	defer func() {
-		for i := range plainKey {
-			plainKey[i] = 0
-		}
-	}()
 
 	block, err := aes.NewCipher(plainKey)
 	if err != nil {
-		return fmt.Errorf("cipher init: %w", err)
+		return nil, fmt.Errorf("cipher init: %w", err)
 	}
 	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return fmt.Errorf("gcm init: %w", err)
+		return nil, fmt.Errorf("gcm init: %w", err)
 	}
 
 	nonceSize := aesgcm.NonceSize()
 	if len(data) < nonceSize {
-		return fmt.Errorf("ciphertext too short for %s", fileName)
+		return nil, fmt.Errorf("%w: ciphertext too short for %s", ErrIntegrity, name)
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return fmt.Errorf("decrypt: %w", err)
+		return nil, fmt.Errorf("%w: decrypt: %v", ErrIntegrity, err)
 	}
 
 	if fileHash, ok := meta["file_hash"]; ok {
 		if sha256Sum(plaintext) != fileHash {
-			return fmt.Errorf("hash mismatch for %s: integrity check failed", fileName)
+			return nil, fmt.Errorf("%w: hash mismatch for %s", ErrIntegrity, name)
 		}
 	}
+	return plaintext, nil
+}
 
-	outFile := "decrypted_" + filepath.Base(fileName)
-	if err := os.WriteFile(outFile, plaintext, 0644); err != nil {
-		return fmt.Errorf("write file: %w", err)
-	}
+// objectReaderAt serves ReadAt from ranged GETs. A sequential reader keeps
+// a single open-ended response body; only a seek opens a new one.
+type objectReaderAt struct {
+	ctx     context.Context
+	client  *s3.Client
+	bucket  string
+	key     string
+	version *string
 
-	if database != nil {
-		_ = db.RecordAudit(database, "download", fileName, "s3", true, "")
-		_ = RecordAuditToDynamo("download", fileName, "s3", true, "")
+	mu   sync.Mutex
+	body io.ReadCloser
+	pos  int64
+}
+
+func (o *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.body == nil || off != o.pos {
+		if o.body != nil {
+			o.body.Close()
+		}
+		out, err := o.client.GetObject(o.ctx, &s3.GetObjectInput{
+			Bucket:    aws.String(o.bucket),
+			Key:       aws.String(o.key),
+			VersionId: o.version,
+			Range:     aws.String(fmt.Sprintf("bytes=%d-", off)),
+		})
+		if err != nil {
+			o.body = nil
+			return 0, fmt.Errorf("s3 get: %w", err)
+		}
+		o.body, o.pos = out.Body, off
+	}
+	n, err := io.ReadFull(o.body, p)
+	o.pos += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
 	}
+	return n, err
+}
 
-	_ = LogToCloudWatch(fmt.Sprintf("Downloaded and decrypted %s from bucket %s", fileName, cfg.Bucket))
-	fmt.Printf("Downloaded and decrypted %s successfully.\n", outFile)
-	return nil
+func (o *objectReaderAt) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
 }
@@ -0,0 +1,404 @@
+// Package backup writes and reads portable vault archives.
+//
+// An archive holds the secrets, files and audit tables and, optionally,
+// the blobs of files stored in the local directory. Secret values are
+// decrypted before they go in, so a backup does not depend on this vault's
+// keyring or KMS key and never contains either. The contents are a gzipped
+// tar sealed with the chunked stream format (see internal/stream) under a
+// random data key. That key is wrapped either under a passphrase (as an
+// internal/bundle) or for an X25519 recipient (nacl anonymous box).
+//
+// Layout: the line "VAULT-BACKUP 1", a JSON header line with the wrapped
+// key, then the stream.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+
+	"vault-cli/internal/bundle"
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+	"vault-cli/internal/stream"
+)
+
+// Schema is the archive content version this build writes and the newest
+// it can restore.
+const Schema = 1
+
+const (
+	magicLine = "VAULT-BACKUP 1\n"
+
+	wrapPassphrase = "passphrase"
+	wrapX25519     = "x25519"
+
+	publicPrefix  = "vault-pub-"
+	privatePrefix = "vault-key-"
+)
+
+var (
+	ErrNotBackup = errors.New("not a vault backup")
+	ErrSchema    = errors.New("unsupported backup schema")
+	ErrIdentity  = errors.New("backup is not encrypted to this identity")
+)
+
+// Manifest describes an archive; it is the first entry.
+type Manifest struct {
+	Schema    int    `json:"schema"`
+	CreatedAt string `json:"created_at"`
+	Mode      string `json:"mode"`
+	Secrets   int    `json:"secrets"`
+	Files     int    `json:"files"`
+	Audit     int    `json:"audit"`
+	Blobs     []Blob `json:"blobs,omitempty"`
+}
+
+// Blob is a locally stored file carried in the archive. Path is only set
+// when writing.
+type Blob struct {
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	Modified string `json:"modified"`
+	Path     string `json:"-"`
+}
+
+// Archive is everything in a backup except the blob contents.
+type Archive struct {
+	Manifest Manifest
+	Secrets  []secrets.Secret
+	Files    []db.FileRecord
+	Audit    []db.AuditRecord
+}
+
+type header struct {
+	Wrap       string `json:"wrap"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Recipient  string `json:"recipient,omitempty"`
+	Sealed     string `json:"sealed,omitempty"`
+}
+
+// Write seals a into w, followed by the contents of its blobs. The data
+// key is wrapped for recipient when it is set, otherwise by passphrase.
+func Write(w io.Writer, a *Archive, passphrase string, recipient *[32]byte) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	defer zero(key)
+
+	var h header
+	if recipient != nil {
+		sealed, err := box.SealAnonymous(nil, key, recipient, rand.Reader)
+		if err != nil {
+			return err
+		}
+		h = header{Wrap: wrapX25519, Recipient: EncodePublic(recipient), Sealed: base64.StdEncoding.EncodeToString(sealed)}
+	} else {
+		sealed, err := bundle.Seal(key, passphrase)
+		if err != nil {
+			return err
+		}
+		h = header{Wrap: wrapPassphrase, Passphrase: string(sealed)}
+	}
+	hj, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, magicLine+string(hj)+"\n"); err != nil {
+		return err
+	}
+
+	sw, err := stream.NewWriter(w, key)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(sw)
+	tw := tar.NewWriter(gz)
+
+	a.Manifest.Schema = Schema
+	a.Manifest.Secrets, a.Manifest.Files, a.Manifest.Audit = len(a.Secrets), len(a.Files), len(a.Audit)
+	for _, e := range []struct {
+		name string
+		v    any
+	}{
+		{"manifest.json", a.Manifest},
+		{"secrets.json", a.Secrets},
+		{"files.json", a.Files},
+		{"audit.json", a.Audit},
+	} {
+		if err := writeJSON(tw, e.name, e.v); err != nil {
+			return err
+		}
+	}
+	for _, b := range a.Manifest.Blobs {
+		if err := writeBlob(tw, b); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+func writeJSON(tw *tar.Writer, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func writeBlob(tw *tar.Writer, b Blob) error {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tw.WriteHeader(&tar.Header{Name: "blobs/" + b.Name, Mode: 0600, Size: b.Size, ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, f, b.Size); err != nil {
+		return fmt.Errorf("%s: %w", b.Name, err)
+	}
+	return nil
+}
+
+// File is an opened backup whose key has not been unwrapped yet.
+type File struct {
+	f   *os.File
+	h   header
+	off int64
+}
+
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	line, err := br.ReadString('\n')
+	if err != nil || line != magicLine {
+		f.Close()
+		return nil, ErrNotBackup
+	}
+	hj, err := br.ReadString('\n')
+	if err != nil {
+		f.Close()
+		return nil, ErrNotBackup
+	}
+	var h header
+	if err := json.Unmarshal([]byte(hj), &h); err != nil {
+		f.Close()
+		return nil, ErrNotBackup
+	}
+	return &File{f: f, h: h, off: int64(len(line) + len(hj))}, nil
+}
+
+// Recipient is the public key the backup is encrypted to, or "" when it
+// is protected by a passphrase.
+func (f *File) Recipient() string {
+	if f.h.Wrap == wrapX25519 {
+		return f.h.Recipient
+	}
+	return ""
+}
+
+func (f *File) Close() error { return f.f.Close() }
+
+// Unlock unwraps the data key with id (for recipient backups) or
+// passphrase and reads everything up to the blobs.
+func (f *File) Unlock(passphrase string, id *Identity) (*Reader, error) {
+	var key []byte
+	switch f.h.Wrap {
+	case wrapPassphrase:
+		k, err := bundle.Open([]byte(f.h.Passphrase), passphrase)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case wrapX25519:
+		if id == nil || EncodePublic(&id.Public) != f.h.Recipient {
+			return nil, ErrIdentity
+		}
+		sealed, err := base64.StdEncoding.DecodeString(f.h.Sealed)
+		if err != nil {
+			return nil, ErrNotBackup
+		}
+		k, ok := box.OpenAnonymous(nil, sealed, &id.Public, &id.Private)
+		if !ok {
+			return nil, ErrIdentity
+		}
+		key = k
+	default:
+		return nil, fmt.Errorf("%w: unknown key wrapping %q", ErrNotBackup, f.h.Wrap)
+	}
+	defer zero(key)
+
+	info, err := f.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	sr, err := stream.NewReader(io.NewSectionReader(f.f, f.off, info.Size()-f.off), info.Size()-f.off, key)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(sr)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{tr: tar.NewReader(gz)}
+
+	if err := r.readJSON("manifest.json", &r.Manifest); err != nil {
+		return nil, err
+	}
+	if r.Manifest.Schema < 1 || r.Manifest.Schema > Schema {
+		return nil, fmt.Errorf("%w: backup is schema %d, this vault reads up to %d", ErrSchema, r.Manifest.Schema, Schema)
+	}
+	if err := r.readJSON("secrets.json", &r.Secrets); err != nil {
+		return nil, err
+	}
+	if err := r.readJSON("files.json", &r.Files); err != nil {
+		return nil, err
+	}
+	if err := r.readJSON("audit.json", &r.Audit); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reader is an unlocked backup positioned at its blobs.
+type Reader struct {
+	Archive
+	tr *tar.Reader
+}
+
+func (r *Reader) readJSON(name string, v any) error {
+	hdr, err := r.tr.Next()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if hdr.Name != name {
+		return fmt.Errorf("%w: expected %s, found %s", ErrNotBackup, name, hdr.Name)
+	}
+	return json.NewDecoder(r.tr).Decode(v)
+}
+
+// Blobs calls fn for each blob in manifest order. fn must consume the
+// reader; the archive is authenticated as it is read, so an error here
+// can mean the backup was tampered with.
+func (r *Reader) Blobs(fn func(b Blob, src io.Reader) error) error {
+	for _, b := range r.Manifest.Blobs {
+		hdr, err := r.tr.Next()
+		if err != nil {
+			return fmt.Errorf("read blob %s: %w", b.Name, err)
+		}
+		if hdr.Name != "blobs/"+b.Name || hdr.Size != b.Size {
+			return fmt.Errorf("%w: unexpected entry %s", ErrNotBackup, hdr.Name)
+		}
+		if err := fn(b, r.tr); err != nil {
+			return err
+		}
+	}
+	if _, err := r.tr.Next(); err != io.EOF {
+		if err == nil {
+			err = errors.New("trailing entries")
+		}
+		return fmt.Errorf("%w: %v", ErrNotBackup, err)
+	}
+	return nil
+}
+
+// Identity is an X25519 key pair for recipient-encrypted backups.
+type Identity struct {
+	Public, Private [32]byte
+}
+
+func GenerateIdentity() (*Identity, error) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Public: *pub, Private: *priv}, nil
+}
+
+// Marshal encodes id for an identity file: the public key as a comment,
+// then the private key.
+func (id *Identity) Marshal() []byte {
+	return []byte("# vault backup identity\n# public key: " + EncodePublic(&id.Public) + "\n" +
+		privatePrefix + base64.RawURLEncoding.EncodeToString(id.Private[:]) + "\n")
+}
+
+func ParseIdentity(data []byte) (*Identity, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, privatePrefix) {
+			continue
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(line, privatePrefix))
+		if err != nil || len(raw) != 32 {
+			break
+		}
+		id := &Identity{}
+		copy(id.Private[:], raw)
+		pub, err := publicFor(&id.Private)
+		if err != nil {
+			return nil, err
+		}
+		id.Public = *pub
+		return id, nil
+	}
+	return nil, errors.New("no vault backup identity found")
+}
+
+func publicFor(priv *[32]byte) (*[32]byte, error) {
+	raw, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	var pub [32]byte
+	copy(pub[:], raw)
+	return &pub, nil
+}
+
+func EncodePublic(k *[32]byte) string {
+	return publicPrefix + base64.RawURLEncoding.EncodeToString(k[:])
+}
+
+func ParsePublic(s string) (*[32]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), publicPrefix))
+	if err != nil || len(raw) != 32 || !strings.HasPrefix(strings.TrimSpace(s), publicPrefix) {
+		return nil, fmt.Errorf("invalid recipient %q (want %s...)", s, publicPrefix)
+	}
+	var k [32]byte
+	copy(k[:], raw)
+	return &k, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"vault-cli/internal/bundle"
+	"vault-cli/internal/config"
+	"vault-cli/internal/db"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/secrets"
+)
+
+var local = &config.Config{Mode: "local"}
+
+// source returns a vault with a typed secret, an expired one, and a local
+// file of size bytes in dir.
+func source(t *testing.T, dir string, size int) *sql.DB {
+	t.Helper()
+	database := dbtest.Open(t)
+	err := secrets.Import(database, local, []secrets.Secret{
+		{Category: "prod", Name: "db", Type: secrets.TypeLogin, Value: `{"password":"pw","username":"app"}`,
+			Meta: secrets.Meta{Description: "main", Tags: []string{"pci"}}},
+		{Category: "prod", Name: "old", Value: "stale", ExpiresAt: "2000-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+	if err := os.WriteFile(filepath.Join(dir, "report.pdf"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordFile(database, "report.pdf", "h", int64(len(data)), dir, "local"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.RecordAudit(database, "upload", "report.pdf", dir, true, "")
+	return database
+}
+
+func writeBackup(t *testing.T, database *sql.DB, pass string, recipient *[32]byte) string {
+	t.Helper()
+	a, missing, err := Collect(database, local, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) > 0 {
+		t.Fatalf("missing blobs: %v", missing)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, a, pass, recipient); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "vault.bak")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// restore reads the whole backup at path into database.
+func restore(database *sql.DB, path, pass string, id *Identity, dir string) error {
+	f, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := f.Unlock(pass, id)
+	if err != nil {
+		return err
+	}
+	p, err := PlanRestore(database, &r.Archive, PolicyFail, dir)
+	if err != nil {
+		return err
+	}
+	return Restore(database, local, r, p, dir, false)
+}
+
+func TestRoundTrip(t *testing.T) {
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	src := source(t, srcDir, 200<<10)
+	path := writeBackup(t, src, "backup pass", nil)
+
+	dst := dbtest.Open(t)
+	if err := restore(dst, path, "backup pass", nil, dstDir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"db", "old"} {
+		want, _ := secrets.Lookup(src, "prod", name)
+		got, err := secrets.Lookup(dst, "prod", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != want.Type || got.ExpiresAt != want.ExpiresAt || got.UpdatedAt != want.UpdatedAt || !reflect.DeepEqual(got.Meta, want.Meta) {
+			t.Errorf("%s restored as %+v, want %+v", name, got, want)
+		}
+		wv, _ := secrets.GetIncludingExpired(src, local, "prod", name)
+		gv, err := secrets.GetIncludingExpired(dst, local, "prod", name)
+		if err != nil || gv != wv {
+			t.Errorf("%s value = %q, %v; want %q", name, gv, err, wv)
+		}
+	}
+
+	want, _ := os.ReadFile(filepath.Join(srcDir, "report.pdf"))
+	got, err := os.ReadFile(filepath.Join(dstDir, "report.pdf"))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("restored blob differs (%v)", err)
+	}
+	files, _ := db.ListFiles(dst)
+	if len(files) != 1 || files[0].Location != dstDir {
+		t.Fatalf("file records = %+v", files)
+	}
+	if audit, _ := db.AllAudit(dst); len(audit) == 0 {
+		t.Fatal("audit log not restored")
+	}
+
+	// Restoring the same backup again changes nothing.
+	if err := restore(dst, path, "backup pass", nil, dstDir); err != nil {
+		t.Fatalf("second restore: %v", err)
+	}
+	if files, _ := db.ListFiles(dst); len(files) != 1 {
+		t.Fatalf("second restore duplicated file records: %d", len(files))
+	}
+}
+
+func TestRecipient(t *testing.T) {
+	src := source(t, t.TempDir(), 16)
+	id, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseIdentity(id.Marshal())
+	if err != nil || *parsed != *id {
+		t.Fatalf("ParseIdentity(Marshal()) = %v, %v", parsed, err)
+	}
+	pub, err := ParsePublic(EncodePublic(&id.Public))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeBackup(t, src, "", pub)
+
+	other, _ := GenerateIdentity()
+	if err := restore(dbtest.Open(t), path, "", other, t.TempDir()); !errors.Is(err, ErrIdentity) {
+		t.Fatalf("other identity: got %v, want ErrIdentity", err)
+	}
+	if err := restore(dbtest.Open(t), path, "", nil, t.TempDir()); !errors.Is(err, ErrIdentity) {
+		t.Fatalf("no identity: got %v, want ErrIdentity", err)
+	}
+	if err := restore(dbtest.Open(t), path, "", id, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path := writeBackup(t, source(t, t.TempDir(), 16), "backup pass", nil)
+	if err := restore(dbtest.Open(t), path, "guess", nil, t.TempDir()); !errors.Is(err, bundle.ErrPassphrase) {
+		t.Fatalf("got %v, want ErrPassphrase", err)
+	}
+}
+
+func TestTamperedOrTruncatedBackupFails(t *testing.T) {
+	path := writeBackup(t, source(t, t.TempDir(), 300<<10), "backup pass", nil)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := bytes.Index(data, []byte("\n"))
+	header += bytes.Index(data[header+1:], []byte("\n")) + 2
+
+	cases := map[string][]byte{
+		"magic":        append([]byte("VAULT-BACKUP 9"), data[14:]...),
+		"first chunk":  flip(data, header+40),
+		"middle":       flip(data, header+(len(data)-header)/2),
+		"last byte":    flip(data, len(data)-1),
+		"cut in half":  data[:header+(len(data)-header)/2],
+		"cut one byte": data[:len(data)-1],
+		"header only":  data[:header],
+	}
+	for name, bad := range cases {
+		p := filepath.Join(t.TempDir(), "bad.bak")
+		if err := os.WriteFile(p, bad, 0600); err != nil {
+			t.Fatal(err)
+		}
+		dst, dir := dbtest.Open(t), t.TempDir()
+		if err := restore(dst, p, "backup pass", nil, dir); err == nil {
+			t.Errorf("%s: restore succeeded", name)
+		}
+		if items, _ := secrets.List(dst, ""); len(items) != 0 {
+			t.Errorf("%s: %d secrets written by a failed restore", name, len(items))
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: files left in the local directory: %v", name, entries)
+		}
+	}
+}
+
+func flip(b []byte, i int) []byte {
+	out := append([]byte(nil), b...)
+	out[i] ^= 0x01
+	return out
+}
+
+func TestConflictPolicies(t *testing.T) {
+	src := source(t, t.TempDir(), 16)
+	a, _, err := Collect(src, local, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := dbtest.Open(t)
+	if err := secrets.Add(dst, local, secrets.Secret{Category: "prod", Name: "db", Value: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PlanRestore(dst, a, PolicyFail, ""); !errors.Is(err, ErrConflict) {
+		t.Fatalf("fail policy: got %v", err)
+	}
+	p, err := PlanRestore(dst, a, PolicySkip, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Keep, []string{"prod/db"}) || !reflect.DeepEqual(p.Add, []string{"prod/old"}) {
+		t.Fatalf("skip plan: keep %v add %v", p.Keep, p.Add)
+	}
+	p, err = PlanRestore(dst, a, PolicyOverwrite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Replace, []string{"prod/db"}) {
+		t.Fatalf("overwrite plan: replace %v", p.Replace)
+	}
+	// dst's copy was written after the backup's, so newer keeps it.
+	p, err = PlanRestore(dst, a, PolicyNewer, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Replace) != 0 {
+		t.Fatalf("newer plan replaced %v", p.Replace)
+	}
+}
+
+func TestBlobNamesCannotEscape(t *testing.T) {
+	a := &Archive{Manifest: Manifest{Blobs: []Blob{{Name: "../evil"}}}}
+	if _, err := PlanRestore(dbtest.Open(t), a, PolicyFail, t.TempDir()); !errors.Is(err, ErrNotBackup) {
+		t.Fatalf("got %v, want ErrNotBackup", err)
+	}
+}
@@ -0,0 +1,367 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"vault-cli/internal/config"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+)
+
+// Policy decides what happens when a secret or local file in the backup
+// already exists with different contents.
+type Policy string
+
+const (
+	PolicyFail      Policy = "fail"
+	PolicySkip      Policy = "skip"
+	PolicyOverwrite Policy = "overwrite"
+	PolicyNewer     Policy = "newer"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyFail, PolicySkip, PolicyOverwrite, PolicyNewer:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q (want fail, skip, overwrite or newer)", s)
+}
+
+var ErrConflict = errors.New("backup conflicts with existing data")
+
+// Collect reads the vault into an archive, decrypting every secret. With
+// blobs set it also picks up files kept in a local directory; ones that
+// are no longer on disk are returned in missing.
+func Collect(database *sql.DB, cfg *config.Config, blobs bool) (a *Archive, missing []string, err error) {
+	a = &Archive{Manifest: Manifest{CreatedAt: time.Now().UTC().Format(time.RFC3339), Mode: cfg.Mode}}
+
+	if a.Secrets, err = secrets.List(database, ""); err != nil {
+		return nil, nil, err
+	}
+	for i := range a.Secrets {
+		s := &a.Secrets[i]
+		if s.Value, err = secrets.GetIncludingExpired(database, cfg, s.Category, s.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if a.Files, err = db.ListFiles(database); err != nil {
+		return nil, nil, err
+	}
+	if a.Audit, err = db.AllAudit(database); err != nil {
+		return nil, nil, err
+	}
+	if !blobs {
+		return a, nil, nil
+	}
+
+	// Files are newest first; only the latest version of each name is
+	// on disk.
+	seen := map[string]bool{}
+	for _, f := range a.Files {
+		if f.Location == "s3" || seen[f.Filename] {
+			continue
+		}
+		seen[f.Filename] = true
+		b, err := statBlob(f.Filename, filepath.Join(f.Location, f.Filename))
+		if errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, f.Filename)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		a.Manifest.Blobs = append(a.Manifest.Blobs, b)
+	}
+	return a, missing, nil
+}
+
+func statBlob(name, path string) (Blob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Blob{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Blob{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return Blob{}, err
+	}
+	return Blob{
+		Name:     name,
+		Hash:     hex.EncodeToString(h.Sum(nil)),
+		Size:     info.Size(),
+		Modified: info.ModTime().UTC().Format(time.RFC3339),
+		Path:     path,
+	}, nil
+}
+
+// Plan is what a restore will change. Secrets and blobs are listed by
+// name; file and audit rows already present are left alone.
+type Plan struct {
+	Add          []string `json:"add,omitempty"`
+	Replace      []string `json:"replace,omitempty"`
+	Keep         []string `json:"keep,omitempty"`
+	Unchanged    []string `json:"unchanged,omitempty"`
+	Conflicts    []string `json:"conflicts,omitempty"`
+	Blobs        []string `json:"blobs,omitempty"`
+	Files        int      `json:"files"`
+	FilesPresent int      `json:"files_present"`
+	Audit        int      `json:"audit"`
+	AuditPresent int      `json:"audit_present"`
+
+	secrets []secrets.Secret
+	files   []db.FileRecord
+	audit   []db.AuditRecord
+	blobs   map[string]bool
+}
+
+// PlanRestore compares a with the vault. localDir is where blobs are
+// restored to. With PolicyFail any conflict is an ErrConflict, returned
+// along with the plan so it can still be shown.
+func PlanRestore(database *sql.DB, a *Archive, policy Policy, localDir string) (*Plan, error) {
+	if len(a.Manifest.Blobs) > 0 && localDir == "" {
+		return nil, errors.New("backup contains local files; set VAULT_REMOTE_PATH to restore them")
+	}
+	p := &Plan{blobs: map[string]bool{}}
+
+	type current struct{ hash, updated string }
+	existing := map[string]current{}
+	rows, err := database.Query(`SELECT category, name, hash, updated_at FROM secrets`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c, n string
+		var cur current
+		if err := rows.Scan(&c, &n, &cur.hash, &cur.updated); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[secrets.Path(c, n)] = cur
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range a.Secrets {
+		id := secrets.Path(s.Category, s.Name)
+		cur, ok := existing[id]
+		switch {
+		case !ok:
+			p.Add = append(p.Add, id)
+		case cur.hash == core.BytesSHA256Hex([]byte(s.Value)):
+			p.Unchanged = append(p.Unchanged, id)
+			continue
+		case policy == PolicyOverwrite, policy == PolicyNewer && s.UpdatedAt > cur.updated:
+			p.Replace = append(p.Replace, id)
+		case policy == PolicyFail:
+			p.Conflicts = append(p.Conflicts, id)
+			continue
+		default:
+			p.Keep = append(p.Keep, id)
+			continue
+		}
+		p.secrets = append(p.secrets, s)
+	}
+
+	have, err := existingKeys(database, `SELECT filename, uploaded_at, hash FROM files`, 3)
+	if err != nil {
+		return nil, err
+	}
+	restored := map[string]bool{}
+	for _, b := range a.Manifest.Blobs {
+		restored[b.Name] = true
+	}
+	for _, f := range a.Files {
+		if have[f.Filename+"\x00"+f.Uploaded+"\x00"+f.Hash] {
+			p.FilesPresent++
+			continue
+		}
+		if f.Location != "s3" && restored[f.Filename] {
+			f.Location = localDir
+		}
+		p.files = append(p.files, f)
+	}
+	p.Files = len(p.files)
+
+	have, err = existingKeys(database, `SELECT action, filename, target, success, err, ts FROM audit`, 6)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range a.Audit {
+		if have[auditKey(e)] {
+			p.AuditPresent++
+			continue
+		}
+		p.audit = append(p.audit, e)
+	}
+	p.Audit = len(p.audit)
+
+	for _, b := range a.Manifest.Blobs {
+		if b.Name != filepath.Base(b.Name) || b.Name == "." || b.Name == ".." {
+			return nil, fmt.Errorf("%w: bad file name %q", ErrNotBackup, b.Name)
+		}
+		id := "file:" + b.Name
+		cur, err := statBlob(b.Name, filepath.Join(localDir, b.Name))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			p.Blobs = append(p.Blobs, b.Name)
+		case err != nil:
+			return nil, err
+		case cur.Hash == b.Hash:
+			p.Unchanged = append(p.Unchanged, id)
+			continue
+		case policy == PolicyOverwrite, policy == PolicyNewer && b.Modified > cur.Modified:
+			p.Blobs = append(p.Blobs, b.Name)
+		case policy == PolicyFail:
+			p.Conflicts = append(p.Conflicts, id)
+			continue
+		default:
+			p.Keep = append(p.Keep, id)
+			continue
+		}
+		p.blobs[b.Name] = true
+	}
+
+	if len(p.Conflicts) > 0 {
+		return p, fmt.Errorf("%w: %s (choose --on-conflict skip, overwrite or newer)", ErrConflict, strings.Join(p.Conflicts, ", "))
+	}
+	return p, nil
+}
+
+func existingKeys(database *sql.DB, query string, cols int) (map[string]bool, error) {
+	rows, err := database.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	have := map[string]bool{}
+	vals := make([]string, cols)
+	ptrs := make([]any, cols)
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		have[strings.Join(vals, "\x00")] = true
+	}
+	return have, rows.Err()
+}
+
+func auditKey(e db.AuditRecord) string {
+	success := "0"
+	if e.Success {
+		success = "1"
+	}
+	return strings.Join([]string{e.Action, e.Filename, e.Target, success, e.Error, e.TS}, "\x00")
+}
+
+// Restore applies p. Every blob is read and checked against the manifest
+// even when it is not being restored, so a dry run still verifies the
+// whole archive. Blobs are staged beside their destination and only moved
+// into place once the database transaction has committed; a failure
+// before that leaves the vault as it was.
+func Restore(database *sql.DB, cfg *config.Config, r *Reader, p *Plan, localDir string, dryRun bool) error {
+	staged := map[string]string{}
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+	if len(p.blobs) > 0 && !dryRun {
+		if err := os.MkdirAll(localDir, 0755); err != nil {
+			return err
+		}
+	}
+	err := r.Blobs(func(b Blob, src io.Reader) error {
+		h := sha256.New()
+		var dst io.Writer = io.Discard
+		var tmp *os.File
+		if p.blobs[b.Name] && !dryRun {
+			var err error
+			if tmp, err = os.CreateTemp(localDir, "."+b.Name+".restore-"); err != nil {
+				return err
+			}
+			staged[b.Name] = tmp.Name()
+			dst = tmp
+		}
+		_, err := io.Copy(dst, io.TeeReader(src, h))
+		if tmp != nil {
+			if err == nil {
+				err = tmp.Sync()
+			}
+			if cerr := tmp.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s: %w", b.Name, err)
+		}
+		if hex.EncodeToString(h.Sum(nil)) != b.Hash {
+			return fmt.Errorf("%w: %s does not match its recorded hash (it changed while the backup ran)", ErrNotBackup, b.Name)
+		}
+		return nil
+	})
+	if err != nil || dryRun {
+		return err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	if err := apply(tx, cfg, p); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for name, tmp := range staged {
+		if err := os.Rename(tmp, filepath.Join(localDir, name)); err != nil {
+			return fmt.Errorf("restore %s: %w", name, err)
+		}
+		delete(staged, name)
+	}
+	return nil
+}
+
+func apply(tx *sql.Tx, cfg *config.Config, p *Plan) error {
+	if err := secrets.Restore(tx, cfg, p.secrets); err != nil {
+		return err
+	}
+	for _, f := range p.files {
+		if _, err := tx.Exec(`INSERT INTO files(filename, uploaded_at, hash, size, location, mode) VALUES(?,?,?,?,?,?)`,
+			f.Filename, f.Uploaded, f.Hash, f.Size, f.Location, f.Mode); err != nil {
+			return fmt.Errorf("restore file record %s: %w", f.Filename, err)
+		}
+	}
+	for _, e := range p.audit {
+		success := 0
+		if e.Success {
+			success = 1
+		}
+		if _, err := tx.Exec(`INSERT INTO audit(action, filename, target, success, err, ts) VALUES(?,?,?,?,?,?)`,
+			e.Action, e.Filename, e.Target, success, e.Error, e.TS); err != nil {
+			return fmt.Errorf("restore audit log: %w", err)
+		}
+	}
+	return nil
+}
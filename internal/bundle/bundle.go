@@ -0,0 +1,96 @@
+// Package bundle seals exported vault data for transport. The payload is
+// encrypted with AES-256-GCM under a key derived from a passphrase
+// (argon2id, the same parameters as the keyring) and armored as a PEM
+// block so it survives copy and paste.
+package bundle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	pemType     = "VAULT BUNDLE"
+	kdfArgon2id = "argon2id$t=3$m=65536$p=4"
+)
+
+var (
+	ErrNotBundle  = errors.New("not a vault bundle")
+	ErrPassphrase = errors.New("wrong bundle passphrase or corrupted bundle")
+)
+
+// Is reports whether data looks like a sealed bundle.
+func Is(data []byte) bool {
+	return bytes.Contains(data, []byte("-----BEGIN "+pemType+"-----"))
+}
+
+func Seal(payload []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	headers := map[string]string{"KDF": kdfArgon2id, "Salt": base64.StdEncoding.EncodeToString(salt)}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ct := gcm.Seal(nonce, nonce, payload, additional(headers))
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Headers: headers, Bytes: ct}), nil
+}
+
+func Open(data []byte, passphrase string) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemType {
+		return nil, ErrNotBundle
+	}
+	if block.Headers["KDF"] != kdfArgon2id {
+		return nil, errors.New("bundle: unsupported kdf " + block.Headers["KDF"])
+	}
+	salt, err := base64.StdEncoding.DecodeString(block.Headers["Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, ErrNotBundle
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Bytes) < gcm.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	n := gcm.NonceSize()
+	plain, err := gcm.Open(nil, block.Bytes[:n], block.Bytes[n:], additional(block.Headers))
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, 3, 64*1024, 4, 32)
+	defer func() {
+		for i := range key {
+			key[i] = 0
+		}
+	}()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additional binds the headers to the ciphertext so they can't be swapped.
+func additional(h map[string]string) []byte {
+	return []byte(h["KDF"] + "\n" + h["Salt"])
+}
@@ -0,0 +1,76 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+)
+
+var payload = []byte(`{"category":"prod","format":"dotenv","data":"A=1\n"}`)
+
+func TestSealOpen(t *testing.T) {
+	sealed, err := Seal(payload, "passphrase1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Is(sealed) {
+		t.Fatal("Is(sealed) = false")
+	}
+	if bytes.Contains(sealed, []byte("prod")) {
+		t.Fatal("payload visible in the bundle")
+	}
+	got, err := Open(sealed, "passphrase1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("Open = %q", got)
+	}
+	if _, err := Open(sealed, "passphrase2"); !errors.Is(err, ErrPassphrase) {
+		t.Fatalf("wrong passphrase: got %v", err)
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	sealed, err := Seal(payload, "passphrase1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(sealed)
+
+	reseal := func(edit func(b *pem.Block)) []byte {
+		b := &pem.Block{Type: block.Type, Headers: map[string]string{}, Bytes: append([]byte(nil), block.Bytes...)}
+		for k, v := range block.Headers {
+			b.Headers[k] = v
+		}
+		edit(b)
+		return pem.EncodeToMemory(b)
+	}
+	for name, data := range map[string][]byte{
+		"flipped nonce":      reseal(func(b *pem.Block) { b.Bytes[0] ^= 1 }),
+		"flipped ciphertext": reseal(func(b *pem.Block) { b.Bytes[20] ^= 1 }),
+		"flipped tag":        reseal(func(b *pem.Block) { b.Bytes[len(b.Bytes)-1] ^= 1 }),
+		"truncated":          reseal(func(b *pem.Block) { b.Bytes = b.Bytes[:len(b.Bytes)-1] }),
+		"nonce only":         reseal(func(b *pem.Block) { b.Bytes = b.Bytes[:12] }),
+		"short":              reseal(func(b *pem.Block) { b.Bytes = b.Bytes[:5] }),
+		"other salt":         reseal(func(b *pem.Block) { b.Headers["Salt"] = "AAAAAAAAAAAAAAAAAAAAAA==" }),
+	} {
+		if _, err := Open(data, "passphrase1"); !errors.Is(err, ErrPassphrase) {
+			t.Errorf("%s: got %v, want ErrPassphrase", name, err)
+		}
+	}
+
+	if _, err := Open(reseal(func(b *pem.Block) { b.Headers["KDF"] = "scrypt" }), "passphrase1"); err == nil {
+		t.Error("unknown kdf accepted")
+	}
+	if _, err := Open(reseal(func(b *pem.Block) { delete(b.Headers, "Salt") }), "passphrase1"); !errors.Is(err, ErrNotBundle) {
+		t.Errorf("missing salt: got %v", err)
+	}
+	if _, err := Open(sealed[:len(sealed)/2], "passphrase1"); !errors.Is(err, ErrNotBundle) {
+		t.Errorf("cut-off PEM: got %v", err)
+	}
+	if _, err := Open([]byte("A=1\n"), "passphrase1"); !errors.Is(err, ErrNotBundle) {
+		t.Errorf("plain text: got %v", err)
+	}
+}
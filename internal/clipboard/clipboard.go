@@ -0,0 +1,146 @@
+// Package clipboard puts secret values on the clipboard and takes them off
+// again.
+//
+// Values go out as an OSC 52 escape sequence when a terminal is attached,
+// which also works over SSH and inside tmux, and through wl-copy, xclip,
+// xsel or pbcopy when one is installed and has a display, since plenty of
+// terminals ignore OSC 52.
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"golang.org/x/term"
+)
+
+var ErrUnavailable = errors.New("no clipboard available: not attached to a terminal and no wl-copy, xclip, xsel or pbcopy found")
+
+type tool struct {
+	display string // environment variable that must be set, if any
+	copy    []string
+	paste   []string
+	clear   []string // nil: copy an empty string
+}
+
+var tools = []tool{
+	{display: "WAYLAND_DISPLAY", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}, clear: []string{"wl-copy", "--clear"}},
+	{display: "DISPLAY", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	{display: "DISPLAY", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}, clear: []string{"xsel", "--clipboard", "--clear"}},
+}
+
+func native() *tool {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("pbcopy"); err == nil {
+			return &tool{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}
+		}
+		return nil
+	}
+	for i := range tools {
+		t := &tools[i]
+		if os.Getenv(t.display) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(t.copy[0]); err == nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// Copied is a value Copy put on the clipboard.
+type Copied struct {
+	// Via names the methods used, e.g. "OSC 52" and "wl-copy".
+	Via  []string
+	text string
+	tty  io.Writer
+	tool *tool
+}
+
+// Copy puts text on the clipboard by every available method. It fails
+// only if none worked.
+func Copy(text string) (*Copied, error) {
+	c := &Copied{text: text}
+	var errs []error
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		if _, err := sequence(text).WriteTo(os.Stderr); err != nil {
+			errs = append(errs, err)
+		} else {
+			c.tty = os.Stderr
+			c.Via = append(c.Via, "OSC 52")
+		}
+	}
+	if t := native(); t != nil {
+		if err := run(t.copy, text); err != nil {
+			errs = append(errs, err)
+		} else {
+			c.tool = t
+			c.Via = append(c.Via, t.copy[0])
+		}
+	}
+	if len(c.Via) == 0 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, ErrUnavailable
+	}
+	return c, nil
+}
+
+// Clear empties the clipboard. A native clipboard that no longer holds
+// the copied value is left alone; OSC 52 cannot be read back, so the
+// terminal's clipboard is always cleared.
+func (c *Copied) Clear() error {
+	var errs []error
+	if c.tty != nil {
+		if _, err := sequence("").Clear().WriteTo(c.tty); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.tool != nil && c.holds() {
+		if c.tool.clear != nil {
+			errs = append(errs, run(c.tool.clear, ""))
+		} else {
+			errs = append(errs, run(c.tool.copy, ""))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// holds reports whether the native clipboard still has the copied value,
+// assuming it does if it cannot be read.
+func (c *Copied) holds() bool {
+	out, err := exec.Command(c.tool.paste[0], c.tool.paste[1:]...).Output()
+	if err != nil {
+		return true
+	}
+	return bytes.Equal(bytes.TrimSuffix(out, []byte("\n")), []byte(strings.TrimSuffix(c.text, "\n")))
+}
+
+// sequence wraps the OSC 52 sequence for tmux or screen when running
+// inside one, since they swallow it otherwise.
+func sequence(text string) osc52.Sequence {
+	s := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		s = s.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		s = s.Screen()
+	}
+	return s
+}
+
+func run(argv []string, stdin string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	if err := cmd.Run(); err != nil {
+		return errors.New(argv[0] + ": " + err.Error())
+	}
+	return nil
+}
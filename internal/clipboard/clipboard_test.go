@@ -0,0 +1,53 @@
+package clipboard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fileTool is a native clipboard backed by a file.
+func fileTool(t *testing.T, contents string) (*tool, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clipboard")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &tool{copy: []string{"sh", "-c", `cat > "$0"`, path}, paste: []string{"cat", path}}, path
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestClearEmptiesClipboardStillHoldingValue(t *testing.T) {
+	tl, path := fileTool(t, "s3cret\n")
+	var tty bytes.Buffer
+	c := &Copied{text: "s3cret", tool: tl, tty: &tty}
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, path); got != "" {
+		t.Fatalf("clipboard = %q after Clear", got)
+	}
+	if want := sequence("").Clear().String(); tty.String() != want {
+		t.Fatalf("terminal got %q, want %q", tty.String(), want)
+	}
+}
+
+func TestClearLeavesNewerClipboardContents(t *testing.T) {
+	tl, path := fileTool(t, "something else")
+	c := &Copied{text: "s3cret", tool: tl}
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, path); got != "something else" {
+		t.Fatalf("clipboard = %q, want it untouched", got)
+	}
+}
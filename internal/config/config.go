@@ -2,7 +2,12 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,8 +19,20 @@ type Config struct {
 	RequirePassword bool
 	PasswordFile    string
 	DBPath          string
+	CORSOrigins     []string
+	AllowedHosts    []string
+	MaxUploadBytes  int64
+	ClipTimeout     time.Duration
 }
 
+// DefaultClipTimeout is how long a copied secret stays on the clipboard
+// when VAULT_CLIP_TIMEOUT is not set.
+const DefaultClipTimeout = 45 * time.Second
+
+// DefaultMaxUpload caps request bodies accepted by the web server when
+// VAULT_MAX_UPLOAD_SIZE is not set.
+const DefaultMaxUpload = 1 << 30
+
 func LoadConfig() (*Config, error) {
 	mode := os.Getenv("VAULT_MODE")
 	if mode == "" {
@@ -25,15 +42,47 @@ func LoadConfig() (*Config, error) {
 	if db == "" {
 		db = "vault.db"
 	}
+	passFile := os.Getenv("VAULT_PASS_FILE")
+	if passFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			passFile = filepath.Join(home, ".vault", "vault_pass.txt")
+		}
+	}
 	cfg := &Config{
 		Bucket:       os.Getenv("VAULT_BUCKET"),
 		KmsKey:       os.Getenv("VAULT_KMS_KEY"),
 		Region:       os.Getenv("AWS_REGION"),
 		Mode:         mode,
 		LocalPath:    os.Getenv("VAULT_REMOTE_PATH"),
-		PasswordFile: os.Getenv("VAULT_PASS_FILE"),
+		PasswordFile: passFile,
 		DBPath:       db,
 	}
+	for _, o := range strings.Split(os.Getenv("VAULT_CORS_ORIGINS"), ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			cfg.CORSOrigins = append(cfg.CORSOrigins, o)
+		}
+	}
+	for _, h := range strings.Split(os.Getenv("VAULT_ALLOWED_HOSTS"), ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			cfg.AllowedHosts = append(cfg.AllowedHosts, h)
+		}
+	}
+	cfg.MaxUploadBytes = DefaultMaxUpload
+	if v := os.Getenv("VAULT_MAX_UPLOAD_SIZE"); v != "" {
+		n, err := parseSize(v)
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_MAX_UPLOAD_SIZE: %w", err)
+		}
+		cfg.MaxUploadBytes = n
+	}
+	cfg.ClipTimeout = DefaultClipTimeout
+	if v := os.Getenv("VAULT_CLIP_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("VAULT_CLIP_TIMEOUT: invalid duration %q", v)
+		}
+		cfg.ClipTimeout = d
+	}
 	if os.Getenv("VAULT_REQUIRE_PASSWORD") == "1" {
 		cfg.RequirePassword = true
 	}
@@ -50,3 +99,29 @@ func LoadConfig() (*Config, error) {
 	}
 	return cfg, nil
 }
+
+// parseSize accepts a byte count with an optional K, M or G suffix
+// (powers of 1024), e.g. "512M".
+func parseSize(v string) (int64, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	v = strings.TrimSuffix(v, "B")
+	mult := int64(1)
+	if n := len(v); n > 0 {
+		switch v[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			v = v[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", v)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigLists(t *testing.T) {
+	t.Setenv("VAULT_MODE", "local")
+	t.Setenv("VAULT_REMOTE_PATH", t.TempDir())
+	t.Setenv("VAULT_CORS_ORIGINS", " https://app.example.com/ ,,http://localhost:3000")
+	t.Setenv("VAULT_ALLOWED_HOSTS", "Vault.Example.com, ,internal")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://app.example.com", "http://localhost:3000"}; !reflect.DeepEqual(cfg.CORSOrigins, want) {
+		t.Errorf("CORSOrigins = %q, want %q", cfg.CORSOrigins, want)
+	}
+	if want := []string{"vault.example.com", "internal"}; !reflect.DeepEqual(cfg.AllowedHosts, want) {
+		t.Errorf("AllowedHosts = %q, want %q", cfg.AllowedHosts, want)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"1024": 1024,
+		"512K": 512 << 10,
+		"512M": 512 << 20,
+		"2g":   2 << 30,
+		"10MB": 10 << 20,
+	} {
+		if got, err := parseSize(in); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "0", "-1K", "1.5G", "lots"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) succeeded", in)
+		}
+	}
+}
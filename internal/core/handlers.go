@@ -1,71 +1,178 @@
 package core
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"time"
 
 	"vault-cli/internal/aws"
 	"vault-cli/internal/config"
+	"vault-cli/internal/progress"
 	"vault-cli/internal/tui"
 )
 
-func UploadHandler(file string, cfg *config.Config, db *sql.DB) error {
-	tui.ShowVaultBanner()
-
-	fmt.Println("\nStarting Secure Upload Process...\n")
-	fmt.Println("Features:")
-	fmt.Println("AES-256 encryption with AWS KMS key")
-	fmt.Println("Secure S3 upload over TLS")
-	fmt.Println("Local SQLite metadata tracking")
-	fmt.Println("CloudWatch audit logging\n")
+// UploadHandler encrypts and uploads file. With quiet set nothing is
+// printed unless the upload fails.
+func UploadHandler(file string, quiet bool, cfg *config.Config, db *sql.DB) error {
+	interactive := !quiet && tui.Enabled()
+	if interactive {
+		fmt.Print("\nStarting Secure Upload Process...\n\n")
+		fmt.Println("Features:")
+		fmt.Println("AES-256 encryption with AWS KMS key")
+		fmt.Println("Secure S3 upload over TLS")
+		fmt.Println("Local SQLite metadata tracking")
+		fmt.Print("CloudWatch audit logging\n\n")
+	}
 
-	start := time.Now()
-	go tui.RunTUI()
-	err := aws.EncryptAndUpload(file, cfg, db)
+	var w io.Writer = os.Stdout
+	if quiet {
+		w = io.Discard
+	}
+	err := runTransfer(w, interactive, "Upload Complete!", "Uploading "+filepath.Base(file),
+		func(ctx context.Context, report progress.Func) ([]tui.Field, error) {
+			res, err := aws.UploadFile(ctx, file, cfg, db, report)
+			if err != nil {
+				return nil, err
+			}
+			return resultFields(file, "", res), nil
+		})
 	if err != nil {
-		fmt.Printf("\n❌ Upload Failed: %v\n", err)
 		return fmt.Errorf("encrypt/upload: %w", err)
 	}
-	_ = aws.LogToCloudWatch(fmt.Sprintf("File uploaded: %s", file))
+	return nil
+}
 
-	elapsed := time.Since(start)
+// DownloadHandler decrypts file to output: a path, or "-" for stdout. An
+// empty output means decrypted_<file> in the working directory. Existing
+// files are only replaced when force is set, and quiet suppresses
+// everything but the plaintext itself.
+func DownloadHandler(file, output string, force, quiet bool, cfg *config.Config, db *sql.DB) error {
+	// Status goes to stderr when the plaintext itself is going to stdout.
+	var msg io.Writer = os.Stdout
+	if output == "-" {
+		msg = os.Stderr
+	}
+	if quiet {
+		msg = io.Discard
+	}
+	interactive := !quiet && tui.Enabled() && output != "-"
+	if interactive {
+		fmt.Fprintln(msg, "Features:")
+		fmt.Fprintln(msg, "  • Fetch encrypted file from AWS S3")
+		fmt.Fprintln(msg, "  • Decrypt via AWS KMS data key")
+		fmt.Fprintln(msg, "  • Chunk-level integrity verification (AES-GCM)")
+	}
+	if output == "" {
+		output = "decrypted_" + filepath.Base(file)
+	}
 
-	fmt.Printf("\nUpload Complete!\n")
-	fmt.Printf("File: %s\n", file)
-	fmt.Printf("Hash: SHA256_PLACEHOLDER\n")
-	fmt.Printf("Storage: AWS S3\n")
-	fmt.Printf("Mode: %s\n", cfg.Mode)
-	fmt.Printf("Duration: %v\n", elapsed)
+	err := runTransfer(msg, interactive, "Download Complete!", "Downloading "+filepath.Base(file),
+		func(ctx context.Context, report progress.Func) ([]tui.Field, error) {
+			var (
+				res *aws.Result
+				err error
+			)
+			if output == "-" {
+				res, err = aws.Download(ctx, file, os.Stdout, cfg, db, report)
+			} else {
+				res, err = DownloadToFile(ctx, file, output, force, cfg, db, report)
+			}
+			if err != nil {
+				return nil, err
+			}
+			saved := output
+			if output == "-" {
+				saved = ""
+			}
+			return resultFields(file, saved, res), nil
+		})
+	if err != nil {
+		return fmt.Errorf("decrypt/download: %w", err)
+	}
+	return nil
+}
 
+// runTransfer shows job's progress in the TUI when interactive; otherwise
+// it runs job quietly and prints the result fields under heading to w.
+func runTransfer(w io.Writer, interactive bool, heading, title string, job func(context.Context, progress.Func) ([]tui.Field, error)) error {
+	if interactive {
+		return tui.RunTransfer(title, job)
+	}
+	fields, err := job(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n%s\n", heading)
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s: %s\n", f.Label, f.Value)
+	}
 	return nil
 }
 
-func DownloadHandler(file string, cfg *config.Config, db *sql.DB) error {
-	tui.ShowVaultBanner()
+func resultFields(file, savedAs string, res *aws.Result) []tui.Field {
+	fields := []tui.Field{
+		{Label: "File", Value: file},
+		{Label: "Hash", Value: "SHA256:" + res.Hash},
+		{Label: "Size", Value: fmt.Sprintf("%d bytes", res.Size)},
+		{Label: "Storage", Value: res.Location},
+	}
+	if savedAs != "" {
+		fields = append(fields, tui.Field{Label: "Saved As", Value: savedAs})
+	}
+	if res.VersionID != "" {
+		fields = append(fields, tui.Field{Label: "Version", Value: res.VersionID})
+	}
+	return append(fields,
+		tui.Field{Label: "Mode", Value: res.Mode},
+		tui.Field{Label: "Duration", Value: res.Duration.Round(time.Millisecond).String()},
+	)
+}
 
-	fmt.Println("Features:")
-	fmt.Println("  • Fetch encrypted file from AWS S3")
-	fmt.Println("  • Decrypt via AWS KMS data key")
-	fmt.Println("  • Integrity verification via SHA-256")
+// DownloadToFile decrypts into a 0600 temp file beside path and only moves
+// it into place once the whole file has been read and authenticated, so a
+// failed download never leaves partial plaintext behind.
+func DownloadToFile(ctx context.Context, file, path string, force bool, cfg *config.Config, db *sql.DB, report progress.Func) (*aws.Result, error) {
+	if !force {
+		if _, err := os.Lstat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+	}
 
-	start := time.Now()
-	go tui.RunTUI()
-	err := aws.DownloadAndDecrypt(file, cfg, db)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
 	if err != nil {
-		fmt.Printf("\n❌ Download Failed: %v\n", err)
-		return fmt.Errorf("decrypt/download: %w", err)
+		return nil, err
 	}
-	_ = aws.LogToCloudWatch(fmt.Sprintf("File downloaded: %s", file))
+	defer os.Remove(tmp.Name())
 
-	elapsed := time.Since(start)
-
-	fmt.Printf("\nDownload Complete!\n")
-	fmt.Printf("File: %s\n", file)
-	fmt.Printf("Hash: SHA256_PLACEHOLDER\n")
-	fmt.Printf("Saved As: decrypted_%s\n", file)
-	fmt.Printf("Mode: %s\n", cfg.Mode)
-	fmt.Printf("Duration: %v\n", elapsed)
+	res, err := aws.Download(ctx, file, tmp, cfg, db, report)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	if !force {
+		// Link refuses to replace an existing file, closing the window
+		// between the check above and now.
+		err = os.Link(tmp.Name(), path)
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%s already exists (use --force to overwrite)", path)
+		}
+		if err == nil {
+			return res, nil
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return res, nil
 }
@@ -1,37 +1,37 @@
 package core
 
-import (
-	"database/sql"
-	"fmt"
-)
+import "database/sql"
 
-func GenerateReport(db *sql.DB) {
-	rows, err := db.Query("SELECT COUNT(*), SUM(size) FROM files")
-	if err != nil {
-		fmt.Println("report error:", err)
-		return
-	}
-	defer rows.Close()
+type Report struct {
+	Files      int            `json:"files"`
+	TotalBytes int64          `json:"total_bytes"`
+	Recent     []RecentUpload `json:"recent_uploads"`
+}
 
-	var count int
-	var totalSize int64
-	rows.Next()
-	rows.Scan(&count, &totalSize)
+type RecentUpload struct {
+	Filename string `json:"filename"`
+	Uploaded string `json:"uploaded_at"`
+}
 
-	fmt.Printf("Files Stored: %d\n", count)
-	fmt.Printf("Total Size: %.2f MB\n", float64(totalSize)/1024/1024)
+// GenerateReport summarises stored files: count, total size and the five
+// most recent uploads.
+func GenerateReport(db *sql.DB) (*Report, error) {
+	r := &Report{Recent: []RecentUpload{}}
+	if err := db.QueryRow("SELECT COUNT(*), COALESCE(SUM(size), 0) FROM files").Scan(&r.Files, &r.TotalBytes); err != nil {
+		return nil, err
+	}
 
-	fmt.Println("\nRecent Uploads:")
-	rows2, err := db.Query("SELECT filename, uploaded_at FROM files ORDER BY uploaded_at DESC LIMIT 5")
+	rows, err := db.Query("SELECT filename, uploaded_at FROM files ORDER BY uploaded_at DESC LIMIT 5")
 	if err != nil {
-		fmt.Println("report error:", err)
-		return
+		return nil, err
 	}
-	defer rows2.Close()
-
-	for rows2.Next() {
-		var name, ts string
-		rows2.Scan(&name, &ts)
-		fmt.Printf("- %s (%s)\n", name, ts)
+	defer rows.Close()
+	for rows.Next() {
+		var u RecentUpload
+		if err := rows.Scan(&u.Filename, &u.Uploaded); err != nil {
+			return nil, err
+		}
+		r.Recent = append(r.Recent, u)
 	}
+	return r, rows.Err()
 }
@@ -3,8 +3,13 @@ package core
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func FileSHA256(path string) (string, error) {
@@ -33,3 +38,61 @@ func BytesSHA256Hex(b []byte) string {
 	h := sha256.Sum256(b)
 	return hex.EncodeToString(h[:])
 }
+
+// WriteFileAtomic writes data to a temp file next to path and renames it
+// into place, so readers never observe a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteAtomic(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteAtomic is WriteFileAtomic for content produced by write, so large
+// output can be streamed. The temp file has mode perm from the start, and
+// path is left untouched if write fails.
+func WriteAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ParseDuration accepts everything time.ParseDuration does plus whole
+// days ("30d") and weeks ("2w"), which are the units people use for TTLs.
+// The duration must be positive.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	d, err := time.ParseDuration(s)
+	if n := len(s); err != nil && n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		var v int
+		if v, err = strconv.Atoi(s[:n-1]); err == nil {
+			d = time.Duration(v) * 24 * time.Hour
+			if s[n-1] == 'w' {
+				d *= 7
+			}
+		}
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 90d, 2w, 12h)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type LoginAttempt struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func GetLoginAttempt(db *sql.DB, key string) (LoginAttempt, error) {
+	a := LoginAttempt{Key: key}
+	var last, until string
+	err := db.QueryRow(`SELECT failures, last_failure, locked_until FROM login_attempts WHERE key=?`, key).
+		Scan(&a.Failures, &last, &until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+	a.LastFailure, _ = time.Parse(time.RFC3339, last)
+	a.LockedUntil, _ = time.Parse(time.RFC3339, until)
+	return a, nil
+}
+
+// UpdateLoginAttempt reads the counters for each key, passes them to
+// update and writes back the ones it changed, all inside one IMMEDIATE
+// transaction, so concurrent logins (from any process) see each other's
+// updates. An error from update rolls everything back.
+func UpdateLoginAttempt(db *sql.DB, keys []string, update func(a *LoginAttempt) (bool, error)) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA busy_timeout = 5000`); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return err
+	}
+	err = func() error {
+		for _, key := range keys {
+			a := LoginAttempt{Key: key}
+			var last, until string
+			err := conn.QueryRowContext(ctx, `SELECT failures, last_failure, locked_until FROM login_attempts WHERE key=?`, key).
+				Scan(&a.Failures, &last, &until)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			a.LastFailure, _ = time.Parse(time.RFC3339, last)
+			a.LockedUntil, _ = time.Parse(time.RFC3339, until)
+			changed, err := update(&a)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, `
+				INSERT INTO login_attempts(key, failures, last_failure, locked_until) VALUES(?,?,?,?)
+				ON CONFLICT(key) DO UPDATE SET
+					failures=excluded.failures,
+					last_failure=excluded.last_failure,
+					locked_until=excluded.locked_until
+			`, a.Key, a.Failures, a.LastFailure.UTC().Format(time.RFC3339), a.LockedUntil.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		_, _ = conn.ExecContext(ctx, `ROLLBACK`)
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `COMMIT`)
+	return err
+}
+
+func ClearLoginAttempts(db *sql.DB, key string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM login_attempts WHERE key=?`, key)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func ListLoginAttempts(db *sql.DB) ([]LoginAttempt, error) {
+	rows, err := db.Query(`SELECT key, failures, last_failure, locked_until FROM login_attempts ORDER BY last_failure DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []LoginAttempt
+	for rows.Next() {
+		var a LoginAttempt
+		var last, until string
+		if err := rows.Scan(&a.Key, &a.Failures, &last, &until); err != nil {
+			return nil, err
+		}
+		a.LastFailure, _ = time.Parse(time.RFC3339, last)
+		a.LockedUntil, _ = time.Parse(time.RFC3339, until)
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
@@ -44,8 +44,57 @@ func InitDB(db *sql.DB) error {
 			hash TEXT NOT NULL,
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL DEFAULT '',
+			rotate_every TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '',
+			fields TEXT NOT NULL DEFAULT '',
 			UNIQUE(category, name)
 		);`,
+
+		`CREATE TABLE IF NOT EXISTS login_attempts (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure TEXT NOT NULL,
+			locked_until TEXT NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS mfa (
+			user TEXT PRIMARY KEY,
+			ciphertext BLOB NOT NULL,
+			nonce BLOB NOT NULL,
+			mode TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS keyring (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			kdf TEXT NOT NULL,
+			salt BLOB NOT NULL,
+			wrapped BLOB NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL,
+			last_used_at TEXT NOT NULL,
+			revoked_at TEXT NOT NULL
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS policies (
+			prefix TEXT PRIMARY KEY,
+			rotate_every TEXT NOT NULL,
+			ttl TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
 	}
 
 	for _, s := range stmts {
@@ -54,60 +103,79 @@ func InitDB(db *sql.DB) error {
 		}
 	}
 
-	fmt.Println("Database initialized with tables: files, audit, secrets")
+	// Columns added after a table was first released; databases created
+	// before then get them here.
+	columns := []struct{ table, column, decl string }{
+		{"secrets", "expires_at", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "rotate_every", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "type", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "description", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "owner", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "tags", "TEXT NOT NULL DEFAULT ''"},
+		{"secrets", "fields", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := addColumn(db, c.table, c.column, c.decl); err != nil {
+			return fmt.Errorf("InitDB failed: %v", err)
+		}
+	}
+
 	return nil
 }
 
-func RecordFile(db *sql.DB, filename, hash string, size int64, location, mode string) error {
-	_, err := db.Exec(`INSERT INTO files(filename, uploaded_at, hash, size, location, mode) VALUES(?,?,?,?,?,?)`,
-		filename, time.Now().UTC().Format(time.RFC3339), hash, size, location, mode)
-	return err
+// SchemaVersion is the last data migration applied to the database,
+// kept in SQLite's user_version. New columns don't need one: InitDB adds
+// them on every start.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var v int
+	err := db.QueryRow(`PRAGMA user_version`).Scan(&v)
+	return v, err
 }
 
-func RecordAudit(db *sql.DB, action, filename, target string, success bool, errMsg string) error {
-	sc := 0
-	if success {
-		sc = 1
-	}
-	_, err := db.Exec(`INSERT INTO audit(action, filename, target, success, err, ts) VALUES(?,?,?,?,?,?)`,
-		action, filename, target, sc, errMsg, time.Now().UTC().Format(time.RFC3339))
+// SetSchemaVersion records that migration v has run. Inside a
+// transaction it commits or rolls back with the migration itself.
+func SetSchemaVersion(ex interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, v int) error {
+	_, err := ex.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, v))
 	return err
 }
 
-func PrintDBEntries(db *sql.DB) error {
-	rows, err := db.Query(`SELECT id, filename, uploaded_at, hash, size, location, mode FROM files ORDER BY uploaded_at DESC`)
+func addColumn(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
-	fmt.Println("Files:")
 	for rows.Next() {
-		var id int
-		var fn, ts, hash, loc, mode string
-		var size int64
-		if err := rows.Scan(&id, &fn, &ts, &hash, &size, &loc, &mode); err != nil {
+		var name string
+		if err := rows.Scan(&name); err != nil {
 			return err
 		}
-		fmt.Printf("%d %s %s %s %d %s %s\n", id, fn, ts, hash, size, loc, mode)
+		if name == column {
+			return nil
+		}
 	}
-	return rows.Err()
-}
-
-func PrintAudit(db *sql.DB) error {
-	rows, err := db.Query(`SELECT id, action, filename, target, success, err, ts FROM audit ORDER BY ts DESC LIMIT 200`)
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		return err
 	}
-	defer rows.Close()
-	fmt.Println("Audit:")
-	for rows.Next() {
-		var id int
-		var action, filename, target, errMsg, ts string
-		var success int
-		if err := rows.Scan(&id, &action, &filename, &target, &success, &errMsg, &ts); err != nil {
-			return err
-		}
-		fmt.Printf("%d %s %s -> %s success=%d err=%s at %s\n", id, action, filename, target, success, errMsg, ts)
+	rows.Close()
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err
+}
+
+func RecordFile(db *sql.DB, filename, hash string, size int64, location, mode string) error {
+	_, err := db.Exec(`INSERT INTO files(filename, uploaded_at, hash, size, location, mode) VALUES(?,?,?,?,?,?)`,
+		filename, time.Now().UTC().Format(time.RFC3339), hash, size, location, mode)
+	return err
+}
+
+func RecordAudit(db *sql.DB, action, filename, target string, success bool, errMsg string) error {
+	sc := 0
+	if success {
+		sc = 1
 	}
-	return nil
+	_, err := db.Exec(`INSERT INTO audit(action, filename, target, success, err, ts) VALUES(?,?,?,?,?,?)`,
+		action, filename, target, sc, errMsg, time.Now().UTC().Format(time.RFC3339))
+	return err
 }
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInitDBAddsMissingColumns(t *testing.T) {
+	database, err := OpenDB(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	// A secrets table from before expiry, types and metadata.
+	if _, err := database.Exec(`CREATE TABLE secrets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		category TEXT NOT NULL,
+		name TEXT NOT NULL,
+		ciphertext BLOB NOT NULL,
+		nonce BLOB NOT NULL,
+		mode TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := InitDB(database); err != nil {
+			t.Fatalf("InitDB run %d: %v", i+1, err)
+		}
+	}
+	if _, err := database.Exec(`SELECT expires_at, rotate_every, type, description, owner, tags, fields FROM secrets`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	database, err := OpenDB(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if v, err := SchemaVersion(database); err != nil || v != 0 {
+		t.Fatalf("new database: version %d, %v", v, err)
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSchemaVersion(tx, 1); err != nil {
+		t.Fatal(err)
+	}
+	_ = tx.Rollback()
+	if v, _ := SchemaVersion(database); v != 0 {
+		t.Fatalf("version %d after rollback, want 0", v)
+	}
+
+	if err := SetSchemaVersion(database, 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := SchemaVersion(database); v != 1 {
+		t.Fatalf("version %d, want 1", v)
+	}
+}
@@ -0,0 +1,25 @@
+// Package dbtest opens throwaway vault databases for tests.
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"vault-cli/internal/db"
+)
+
+// Open returns an initialized database in a temporary directory, closed
+// when the test ends.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	database, err := db.OpenDB(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.InitDB(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
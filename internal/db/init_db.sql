@@ -28,9 +28,58 @@ CREATE TABLE IF NOT EXISTS secrets (
   hash TEXT NOT NULL,          
   created_at TEXT NOT NULL,
   updated_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL DEFAULT '',
+  rotate_every TEXT NOT NULL DEFAULT '',
+  type TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  owner TEXT NOT NULL DEFAULT '',
+  tags TEXT NOT NULL DEFAULT '',
+  fields TEXT NOT NULL DEFAULT '',
   UNIQUE(category, name)
 );
 
+CREATE TABLE IF NOT EXISTS login_attempts (
+  key TEXT PRIMARY KEY,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure TEXT NOT NULL,
+  locked_until TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa (
+  user TEXT PRIMARY KEY,
+  ciphertext BLOB NOT NULL,
+  nonce BLOB NOT NULL,
+  mode TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS keyring (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  kdf TEXT NOT NULL,
+  salt BLOB NOT NULL,
+  wrapped BLOB NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  hash TEXT NOT NULL,
+  scopes TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  last_used_at TEXT NOT NULL,
+  revoked_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS policies (
+  prefix TEXT PRIMARY KEY,
+  rotate_every TEXT NOT NULL,
+  ttl TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
 
 RecordFileToDynamo(keyName, hash, info.Size(), cfg.Mode, "s3")
 RecordAuditToDynamo("upload", keyName, "s3", true, "")
\ No newline at end of file
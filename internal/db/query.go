@@ -48,6 +48,19 @@ func ListAudit(db *sql.DB, limit int) ([]AuditRecord, error) {
     if err != nil {
         return nil, err
     }
+    return scanAudit(rows)
+}
+
+// AllAudit returns the whole audit log, oldest first.
+func AllAudit(db *sql.DB) ([]AuditRecord, error) {
+    rows, err := db.Query(`SELECT id, action, filename, target, success, err, ts FROM audit ORDER BY id`)
+    if err != nil {
+        return nil, err
+    }
+    return scanAudit(rows)
+}
+
+func scanAudit(rows *sql.Rows) ([]AuditRecord, error) {
     defer rows.Close()
 
     var items []AuditRecord
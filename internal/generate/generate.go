@@ -0,0 +1,254 @@
+// Package generate makes random passwords, passphrases, byte strings and
+// key pairs for new and rotated secrets. All randomness comes from
+// crypto/rand.
+package generate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"vault-cli/internal/secrets"
+)
+
+// Policies.
+const (
+	Password   = "password"
+	Passphrase = "passphrase"
+	Hex        = "hex"
+	Base64     = "base64"
+	Ed25519    = "ed25519"
+	RSA        = "rsa"
+)
+
+var Policies = []string{Password, Passphrase, Hex, Base64, Ed25519, RSA}
+
+// Character classes for Password.
+const (
+	Lower   = "lower"
+	Upper   = "upper"
+	Digits  = "digits"
+	Symbols = "symbols"
+)
+
+var classes = map[string]string{
+	Lower:   "abcdefghijklmnopqrstuvwxyz",
+	Upper:   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	Digits:  "0123456789",
+	Symbols: "!#$%&*+-=?@^_~.,:;",
+}
+
+// ambiguous characters are dropped with Policy.NoAmbiguous.
+const ambiguous = "Il1O0o"
+
+//go:embed words.txt
+var wordList string
+
+var words = strings.Fields(wordList)
+
+// Policy says what to generate. Zero values take the defaults listed
+// beside each field.
+type Policy struct {
+	Kind string // Password
+
+	Length      int      // Password: 24
+	Classes     []string // Password: all of Lower, Upper, Digits, Symbols
+	NoAmbiguous bool
+
+	Words     int    // Passphrase: 6
+	Separator string // Passphrase: "-"
+
+	Bytes int // Hex, Base64: 32
+
+	Bits    int    // RSA: 3072
+	Comment string // Ed25519, RSA
+}
+
+// Result is a generated value. Type is the secrets type to store it as;
+// Public is set for key pairs and is safe to print.
+type Result struct {
+	Type    string
+	Value   string
+	Public  string
+	Entropy float64 // bits; 0 for key pairs
+}
+
+func Generate(p Policy) (Result, error) {
+	switch p.Kind {
+	case "", Password:
+		return password(p)
+	case Passphrase:
+		return passphrase(p)
+	case Hex, Base64:
+		return randomBytes(p)
+	case Ed25519, RSA:
+		return keyPair(p)
+	}
+	return Result{}, fmt.Errorf("unknown policy %q (want %s)", p.Kind, strings.Join(Policies, ", "))
+}
+
+func password(p Policy) (Result, error) {
+	if p.Length == 0 {
+		p.Length = 24
+	}
+	if len(p.Classes) == 0 {
+		p.Classes = []string{Lower, Upper, Digits, Symbols}
+	}
+	var sets []string
+	for _, c := range p.Classes {
+		set, ok := classes[c]
+		if !ok {
+			return Result{}, fmt.Errorf("unknown character class %q (want lower, upper, digits or symbols)", c)
+		}
+		if p.NoAmbiguous {
+			set = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, set)
+		}
+		if !slices.Contains(sets, set) {
+			sets = append(sets, set)
+		}
+	}
+	if p.Length < len(sets) || p.Length > 1024 {
+		return Result{}, fmt.Errorf("length must be between %d and 1024", len(sets))
+	}
+	alphabet := strings.Join(sets, "")
+
+	// One character from each class, the rest from all of them, then
+	// shuffled so the guaranteed ones aren't always first.
+	out := make([]byte, 0, p.Length)
+	for _, set := range sets {
+		c, err := pick(set)
+		if err != nil {
+			return Result{}, err
+		}
+		out = append(out, c)
+	}
+	for len(out) < p.Length {
+		c, err := pick(alphabet)
+		if err != nil {
+			return Result{}, err
+		}
+		out = append(out, c)
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return Result{}, err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return Result{Value: string(out), Entropy: float64(p.Length) * math.Log2(float64(len(alphabet)))}, nil
+}
+
+func passphrase(p Policy) (Result, error) {
+	if p.Words == 0 {
+		p.Words = 6
+	}
+	if p.Words < 3 || p.Words > 64 {
+		return Result{}, fmt.Errorf("words must be between 3 and 64")
+	}
+	if p.Separator == "" {
+		p.Separator = "-"
+	}
+	out := make([]string, p.Words)
+	for i := range out {
+		n, err := randInt(len(words))
+		if err != nil {
+			return Result{}, err
+		}
+		out[i] = words[n]
+	}
+	return Result{Value: strings.Join(out, p.Separator), Entropy: float64(p.Words) * math.Log2(float64(len(words)))}, nil
+}
+
+func randomBytes(p Policy) (Result, error) {
+	if p.Bytes == 0 {
+		p.Bytes = 32
+	}
+	if p.Bytes < 8 || p.Bytes > 4096 {
+		return Result{}, fmt.Errorf("bytes must be between 8 and 4096")
+	}
+	b := make([]byte, p.Bytes)
+	if _, err := rand.Read(b); err != nil {
+		return Result{}, err
+	}
+	v := hex.EncodeToString(b)
+	if p.Kind == Base64 {
+		v = base64.StdEncoding.EncodeToString(b)
+	}
+	return Result{Value: v, Entropy: float64(8 * p.Bytes)}, nil
+}
+
+// keyPair stores an OpenSSH private key as an ssh secret; the public key
+// is in authorized_keys format.
+func keyPair(p Policy) (Result, error) {
+	var priv any
+	switch p.Kind {
+	case Ed25519:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return Result{}, err
+		}
+		priv = k
+	case RSA:
+		if p.Bits == 0 {
+			p.Bits = 3072
+		}
+		if p.Bits < 2048 || p.Bits > 8192 {
+			return Result{}, fmt.Errorf("rsa bits must be between 2048 and 8192")
+		}
+		k, err := rsa.GenerateKey(rand.Reader, p.Bits)
+		if err != nil {
+			return Result{}, err
+		}
+		priv = k
+	}
+	block, err := ssh.MarshalPrivateKey(priv, p.Comment)
+	if err != nil {
+		return Result{}, err
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return Result{}, err
+	}
+	pub := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if p.Comment != "" {
+		pub += " " + p.Comment
+	}
+	doc := secrets.Document{"private_key": string(pem.EncodeToMemory(block)), "public_key": pub}
+	if p.Comment != "" {
+		doc["comment"] = p.Comment
+	}
+	return Result{Type: secrets.TypeSSH, Value: doc.Encode(), Public: pub}, nil
+}
+
+func pick(set string) (byte, error) {
+	n, err := randInt(len(set))
+	if err != nil {
+		return 0, err
+	}
+	return set[n], nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,111 @@
+package generate
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"vault-cli/internal/secrets"
+)
+
+func TestPasswordUsesEveryClass(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		r, err := Generate(Policy{Length: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Value) != 4 {
+			t.Fatalf("length %d, want 4", len(r.Value))
+		}
+		for name, set := range classes {
+			if !strings.ContainsAny(r.Value, set) {
+				t.Fatalf("%q has no %s character", r.Value, name)
+			}
+		}
+	}
+}
+
+func TestPasswordOptions(t *testing.T) {
+	r, err := Generate(Policy{Length: 200, Classes: []string{Lower, Digits}, NoAmbiguous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(r.Value, ambiguous) || strings.ContainsAny(r.Value, classes[Upper]+classes[Symbols]) {
+		t.Fatalf("unexpected characters in %q", r.Value)
+	}
+	if want := 200 * 5.0; r.Entropy < want || r.Entropy > want+1 {
+		t.Fatalf("entropy = %.1f, want about %.0f bits", r.Entropy, want)
+	}
+
+	for _, p := range []Policy{
+		{Length: 3},
+		{Length: 2000},
+		{Classes: []string{"emoji"}},
+		{Kind: "pin"},
+		{Kind: Passphrase, Words: 2},
+		{Kind: Hex, Bytes: 4},
+		{Kind: RSA, Bits: 1024},
+	} {
+		if _, err := Generate(p); err == nil {
+			t.Errorf("Generate(%+v) succeeded", p)
+		}
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	r, err := Generate(Policy{Kind: Passphrase, Words: 4, Separator: "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Split(r.Value, ".")); n != 4 {
+		t.Fatalf("%q has %d words, want 4", r.Value, n)
+	}
+}
+
+func TestRandomBytes(t *testing.T) {
+	r, err := Generate(Policy{Kind: Hex, Bytes: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := hex.DecodeString(r.Value); err != nil || len(b) != 16 {
+		t.Fatalf("hex %q: %d bytes, %v", r.Value, len(b), err)
+	}
+	r, err = Generate(Policy{Kind: Base64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := base64.StdEncoding.DecodeString(r.Value); err != nil || len(b) != 32 {
+		t.Fatalf("base64 %q: %d bytes, %v", r.Value, len(b), err)
+	}
+}
+
+func TestKeyPair(t *testing.T) {
+	r, err := Generate(Policy{Kind: Ed25519, Comment: "deploy@ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Type != secrets.TypeSSH || !strings.HasSuffix(r.Public, " deploy@ci") {
+		t.Fatalf("type %q, public %q", r.Type, r.Public)
+	}
+	doc, err := secrets.ParseDocument(r.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.ValidateValue(secrets.Secret{Type: r.Type, Value: r.Value}); err != nil {
+		t.Fatalf("generated value is not a valid ssh secret: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(doc["private_key"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.Public))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pub.Marshal()) != string(signer.PublicKey().Marshal()) {
+		t.Fatal("public key does not match the private key")
+	}
+}
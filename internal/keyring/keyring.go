@@ -0,0 +1,232 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// The keyring holds a random 32-byte master key wrapped under a key derived
+// from the master password (argon2id). In local mode, per-secret data keys
+// are wrapped with the master key instead of being stored raw, so changing
+// the password only re-wraps this one row.
+
+var (
+	ErrLocked         = errors.New("vault is locked: master password required")
+	ErrWrongPassword  = errors.New("keyring: wrong password")
+	ErrNotInitialized = errors.New("keyring not initialized; run 'vault init'")
+	ErrExists         = errors.New("keyring already initialized")
+)
+
+const kdfArgon2id = "argon2id$t=3$m=65536$p=4"
+
+var state struct {
+	sync.Mutex
+	initialized bool
+	master      []byte
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func Load(database *sql.DB) error {
+	var n int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM keyring`).Scan(&n); err != nil {
+		return err
+	}
+	state.Lock()
+	state.initialized = n > 0
+	state.Unlock()
+	return nil
+}
+
+func Initialized() bool {
+	state.Lock()
+	defer state.Unlock()
+	return state.initialized
+}
+
+func Locked() bool {
+	state.Lock()
+	defer state.Unlock()
+	return state.master == nil
+}
+
+func Lock() {
+	state.Lock()
+	defer state.Unlock()
+	for i := range state.master {
+		state.master[i] = 0
+	}
+	state.master = nil
+}
+
+func Create(database *sql.DB, password string) error {
+	if err := Load(database); err != nil {
+		return err
+	}
+	if Initialized() {
+		return ErrExists
+	}
+	master := make([]byte, 32)
+	if _, err := rand.Read(master); err != nil {
+		return err
+	}
+	salt, wrapped, err := wrapMaster(master, password)
+	if err != nil {
+		return err
+	}
+	ts := time.Now().UTC().Format(time.RFC3339)
+	if _, err := database.Exec(`INSERT INTO keyring(id, kdf, salt, wrapped, created_at, updated_at) VALUES(1,?,?,?,?,?)`,
+		kdfArgon2id, salt, wrapped, ts, ts); err != nil {
+		return err
+	}
+	state.Lock()
+	state.initialized = true
+	state.master = master
+	state.Unlock()
+	return nil
+}
+
+func Unlock(database *sql.DB, password string) error {
+	master, err := unwrapStored(database, password)
+	if err != nil {
+		return err
+	}
+	state.Lock()
+	state.initialized = true
+	state.master = master
+	state.Unlock()
+	return nil
+}
+
+// Rewrap re-encrypts the master key under newPassword inside tx. Secrets are
+// untouched because their data keys are wrapped by the master key itself.
+func Rewrap(tx *sql.Tx, oldPassword, newPassword string) error {
+	master, err := unwrapStored(tx, oldPassword)
+	if err != nil {
+		return err
+	}
+	defer zero(master)
+	salt, wrapped, err := wrapMaster(master, newPassword)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE keyring SET kdf=?, salt=?, wrapped=?, updated_at=? WHERE id=1`,
+		kdfArgon2id, salt, wrapped, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func Wrap(dataKey []byte) ([]byte, error) {
+	master, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zero(master)
+	return seal(master, dataKey)
+}
+
+func Unwrap(wrapped []byte) ([]byte, error) {
+	master, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zero(master)
+	return open(master, wrapped)
+}
+
+func masterKey() ([]byte, error) {
+	state.Lock()
+	defer state.Unlock()
+	if state.master == nil {
+		return nil, ErrLocked
+	}
+	return append([]byte(nil), state.master...), nil
+}
+
+func unwrapStored(q execer, password string) ([]byte, error) {
+	var kdf string
+	var salt, wrapped []byte
+	err := q.QueryRow(`SELECT kdf, salt, wrapped FROM keyring WHERE id=1`).Scan(&kdf, &salt, &wrapped)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotInitialized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if kdf != kdfArgon2id {
+		return nil, fmt.Errorf("keyring: unsupported kdf %q", kdf)
+	}
+	kek := derive(password, salt)
+	defer zero(kek)
+	master, err := open(kek, wrapped)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return master, nil
+}
+
+func wrapMaster(master []byte, password string) ([]byte, []byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	kek := derive(password, salt)
+	defer zero(kek)
+	wrapped, err := seal(kek, master)
+	if err != nil {
+		return nil, nil, err
+	}
+	return salt, wrapped, nil
+}
+
+func derive(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, 3, 64*1024, 4, 32)
+}
+
+func seal(key, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("keyring: ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
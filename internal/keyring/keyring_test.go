@@ -0,0 +1,140 @@
+package keyring
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"vault-cli/internal/db/dbtest"
+)
+
+// newKeyring creates a keyring and leaves the package state locked and
+// uninitialised again when the test ends.
+func newKeyring(t *testing.T, password string) *sql.DB {
+	t.Helper()
+	database := dbtest.Open(t)
+	empty := dbtest.Open(t)
+	t.Cleanup(func() {
+		Lock()
+		_ = Load(empty)
+	})
+	if err := Create(database, password); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestWrapRoundTrip(t *testing.T) {
+	newKeyring(t, "correct horse")
+	key := bytes.Repeat([]byte{7}, 32)
+	wrapped, err := Wrap(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Fatal("wrapped key contains the plain key")
+	}
+	got, err := Unwrap(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Fatal("Unwrap(Wrap(k)) != k")
+	}
+}
+
+func TestUnwrapRejectsTampering(t *testing.T) {
+	newKeyring(t, "correct horse")
+	wrapped, err := Wrap(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range wrapped {
+		bad := append([]byte(nil), wrapped...)
+		bad[i] ^= 1
+		if _, err := Unwrap(bad); err == nil {
+			t.Fatalf("flipping byte %d went unnoticed", i)
+		}
+	}
+	if _, err := Unwrap(wrapped[:len(wrapped)-1]); err == nil {
+		t.Fatal("truncated key unwrapped")
+	}
+	if _, err := Unwrap(wrapped[:4]); err == nil {
+		t.Fatal("key shorter than a nonce unwrapped")
+	}
+}
+
+func TestLockAndUnlock(t *testing.T) {
+	database := newKeyring(t, "correct horse")
+	wrapped, err := Wrap([]byte("data key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Lock()
+	if !Locked() {
+		t.Fatal("Locked() = false after Lock")
+	}
+	if _, err := Unwrap(wrapped); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Unwrap while locked: got %v, want ErrLocked", err)
+	}
+	if err := Unlock(database, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+	if !Locked() {
+		t.Fatal("a wrong password unlocked the keyring")
+	}
+	if err := Unlock(database, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Unwrap(wrapped); err != nil || string(got) != "data key" {
+		t.Fatalf("Unwrap after Unlock = %q, %v", got, err)
+	}
+}
+
+func TestCreateTwice(t *testing.T) {
+	database := newKeyring(t, "correct horse")
+	if err := Create(database, "other"); !errors.Is(err, ErrExists) {
+		t.Fatalf("second Create: got %v, want ErrExists", err)
+	}
+}
+
+func TestRewrapKeepsMasterKey(t *testing.T) {
+	database := newKeyring(t, "correct horse")
+	wrapped, err := Wrap([]byte("data key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Rewrap(tx, "wrong", "new password"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Rewrap with the wrong password: got %v", err)
+	}
+	if err := Rewrap(tx, "correct horse", "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	Lock()
+	if err := Unlock(database, "correct horse"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("old password after Rewrap: got %v", err)
+	}
+	if err := Unlock(database, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Unwrap(wrapped); err != nil || string(got) != "data key" {
+		t.Fatalf("key wrapped before Rewrap = %q, %v", got, err)
+	}
+}
+
+func TestUnlockUninitialized(t *testing.T) {
+	if err := Unlock(dbtest.Open(t), "x"); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("got %v, want ErrNotInitialized", err)
+	}
+}
@@ -0,0 +1,221 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/config"
+	"vault-cli/internal/db"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/secrets"
+)
+
+const Issuer = "Vault CLI"
+
+var (
+	ErrNotEnrolled  = errors.New("mfa not enrolled")
+	ErrCodeRequired = errors.New("mfa code required")
+	ErrInvalidCode  = errors.New("invalid mfa code")
+)
+
+// Enrollment is stored encrypted (same envelope as secrets) in the mfa
+// table. Recovery codes are kept only as bcrypt hashes and are removed once
+// used; LastCounter rejects replay of an already accepted TOTP code.
+type Enrollment struct {
+	Secret      []byte   `json:"secret"`
+	Recovery    []string `json:"recovery"`
+	LastCounter int64    `json:"last_counter"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+func (e *Enrollment) TOTP() TOTP { return New(e.Secret) }
+
+// Confirm checks the code an authenticator app shows right after
+// enrollment and marks it used, so it can't log in again. Recovery codes
+// don't count.
+func (e *Enrollment) Confirm(code string) error {
+	counter, ok := e.TOTP().Match(code)
+	if !ok {
+		return ErrInvalidCode
+	}
+	e.LastCounter = counter
+	return nil
+}
+
+// NewEnrollment creates a fresh secret and n recovery codes. The plain
+// recovery codes are returned once and never stored.
+func NewEnrollment(n int) (*Enrollment, []string, error) {
+	secret, err := NewSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	e := &Enrollment{Secret: secret, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(b32.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		e.Recovery = append(e.Recovery, string(hash))
+	}
+	return e, codes, nil
+}
+
+func init() { auth.SecondFactor = Enrolled }
+
+func Enrolled(database *sql.DB, user string) (bool, error) {
+	var n int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM mfa WHERE user=?`, user).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func Save(database *sql.DB, cfg *config.Config, user string, e *Enrollment) error {
+	doc, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ct, nonce, mode, err := secrets.Encrypt(cfg, doc)
+	if err != nil {
+		return err
+	}
+	_, err = database.Exec(`
+		INSERT INTO mfa(user, ciphertext, nonce, mode, created_at) VALUES(?,?,?,?,?)
+		ON CONFLICT(user) DO UPDATE SET
+			ciphertext=excluded.ciphertext,
+			nonce=excluded.nonce,
+			mode=excluded.mode
+	`, user, ct, nonce, mode, e.CreatedAt)
+	return err
+}
+
+func Load(database *sql.DB, cfg *config.Config, user string) (*Enrollment, error) {
+	var ct, nonce, mode string
+	err := database.QueryRow(`SELECT ciphertext, nonce, mode FROM mfa WHERE user=?`, user).Scan(&ct, &nonce, &mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := secrets.Decrypt(cfg, ct, nonce, mode)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt mfa enrollment: %w", err)
+	}
+	var e Enrollment
+	if err := json.Unmarshal(doc, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func Delete(database *sql.DB, user string) error {
+	_, err := database.Exec(`DELETE FROM mfa WHERE user=?`, user)
+	return err
+}
+
+// Check validates a TOTP or recovery code against e, updating e in place
+// (replay counter, consumed recovery code). It reports whether e changed
+// and must be saved.
+func Check(e *Enrollment, code string) bool {
+	code = strings.TrimSpace(code)
+	if counter, ok := e.TOTP().Match(code); ok {
+		if counter <= e.LastCounter {
+			return false
+		}
+		e.LastCounter = counter
+		return true
+	}
+	code = strings.ToLower(code)
+	for i, h := range e.Recovery {
+		if bcrypt.CompareHashAndPassword([]byte(h), []byte(code)) == nil {
+			e.Recovery = append(e.Recovery[:i], e.Recovery[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyLogin enforces the second factor for user when enrolled. It shares
+// the login limiter, so bad codes count as failed attempts.
+func VerifyLogin(database *sql.DB, cfg *config.Config, user, ip, code string) error {
+	enrolled, err := Enrolled(database, user)
+	if err != nil || !enrolled {
+		return err
+	}
+	if strings.TrimSpace(code) == "" {
+		return ErrCodeRequired
+	}
+
+	l := auth.NewLimiter(database)
+	keys := []string{auth.UserKey(user)}
+	source := "cli"
+	if ip != "" {
+		keys = append(keys, auth.IPKey(ip))
+		source = ip
+	}
+	if err := l.Attempt(keys...); err != nil {
+		return err
+	}
+
+	e, err := Load(database, cfg, user)
+	if err != nil {
+		_ = l.Release(keys...)
+		return err
+	}
+	if !Check(e, code) {
+		_ = db.RecordAudit(database, "login:mfa-failed", user, source, false, "invalid code")
+		return ErrInvalidCode
+	}
+	if err := Save(database, cfg, user, e); err != nil {
+		return err
+	}
+	_ = db.RecordAudit(database, "login:mfa", user, source, true, "")
+	return l.Reset(keys...)
+}
+
+// Login checks the master password and then the second factor, and only
+// once both pass leaves the keyring open and the limiter cleared. The
+// enrollment is encrypted under the keyring, so checking the code has to
+// open it; it is locked again if the code fails and it was locked before.
+func Login(database *sql.DB, cfg *config.Config, ip, password, code string) error {
+	if err := auth.Authenticate(database, cfg.PasswordFile, auth.MasterUser, ip, password); err != nil {
+		return err
+	}
+	enrolled, err := Enrolled(database, auth.MasterUser)
+	if err != nil {
+		return err
+	}
+	if enrolled && strings.TrimSpace(code) == "" {
+		return ErrCodeRequired
+	}
+	wasLocked := keyring.Locked()
+	if keyring.Initialized() {
+		if err := keyring.Unlock(database, password); err != nil {
+			return err
+		}
+	}
+	if err := VerifyLogin(database, cfg, auth.MasterUser, ip, code); err != nil {
+		if wasLocked {
+			keyring.Lock()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package mfa
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/config"
+	"vault-cli/internal/db"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/keyring"
+)
+
+func enroll(t *testing.T, database *sql.DB, cfg *config.Config) *Enrollment {
+	t.Helper()
+	e, _, err := NewEnrollment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(database, cfg, auth.MasterUser, e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestCheckRejectsReplay(t *testing.T) {
+	e, codes, err := NewEnrollment(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := e.TOTP().Code()
+	if !Check(e, code) {
+		t.Fatal("current code rejected")
+	}
+	if Check(e, code) {
+		t.Fatal("the same code was accepted twice")
+	}
+	if !Check(e, codes[0]) || len(e.Recovery) != 1 {
+		t.Fatal("recovery code not accepted and consumed")
+	}
+	if Check(e, codes[0]) {
+		t.Fatal("a used recovery code was accepted again")
+	}
+}
+
+// A correct password must not clear the failures a guessed code left,
+// or the code could be brute-forced by sending the password every time.
+func TestConfirmedCodeCannotLogIn(t *testing.T) {
+	database := dbtest.Open(t)
+	cfg := &config.Config{Mode: "local"}
+	e, codes, err := NewEnrollment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Confirm(codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("recovery code confirmed enrollment: %v", err)
+	}
+	code := e.TOTP().Code()
+	if err := e.Confirm(code); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(database, cfg, auth.MasterUser, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyLogin(database, cfg, auth.MasterUser, "", code); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("confirmation code replayed for login: got %v", err)
+	}
+}
+
+func TestCodeFailuresSurviveCorrectPassword(t *testing.T) {
+	database := dbtest.Open(t)
+	cfg := &config.Config{Mode: "local"}
+	e := enroll(t, database, cfg)
+
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passFile := filepath.Join(t.TempDir(), "pw")
+	if err := os.WriteFile(passFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := e.TOTP().CodeAt(e.TOTP().Counter(time.Now()) + 5)
+	l := auth.NewLimiter(database)
+	var blocked bool
+	for i := 0; i < l.FreeAttempts+1; i++ {
+		if err := auth.Authenticate(database, passFile, auth.MasterUser, "", "correct horse"); err != nil {
+			if errors.Is(err, auth.ErrLockedOut) {
+				blocked = true
+				break
+			}
+			t.Fatalf("password %d: %v", i+1, err)
+		}
+		err := VerifyLogin(database, cfg, auth.MasterUser, "", wrong)
+		if errors.Is(err, auth.ErrLockedOut) {
+			blocked = true
+			break
+		}
+		if !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("code %d: got %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+	if !blocked {
+		t.Fatal("repeated wrong codes were never delayed")
+	}
+	a, _ := db.GetLoginAttempt(database, auth.UserKey(auth.MasterUser))
+	if a.Failures != l.FreeAttempts {
+		t.Fatalf("failures = %d, want %d", a.Failures, l.FreeAttempts)
+	}
+}
+
+func TestVerifyLoginResetsOnSuccess(t *testing.T) {
+	database := dbtest.Open(t)
+	cfg := &config.Config{Mode: "local"}
+	e := enroll(t, database, cfg)
+
+	totp := e.TOTP()
+	if err := VerifyLogin(database, cfg, auth.MasterUser, "", totp.CodeAt(totp.Counter(time.Now())+5)); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("wrong code: got %v", err)
+	}
+	if err := VerifyLogin(database, cfg, auth.MasterUser, "", totp.Code()); err != nil {
+		t.Fatalf("right code: %v", err)
+	}
+	a, _ := db.GetLoginAttempt(database, auth.UserKey(auth.MasterUser))
+	if a.Failures != 0 {
+		t.Fatalf("failures after the second factor passed = %d, want 0", a.Failures)
+	}
+}
+
+func TestLoginKeepsKeyringLockedOnBadCode(t *testing.T) {
+	database := dbtest.Open(t)
+	empty := dbtest.Open(t)
+	t.Cleanup(func() {
+		keyring.Lock()
+		_ = keyring.Load(empty)
+	})
+	cfg := &config.Config{Mode: "local", PasswordFile: filepath.Join(t.TempDir(), "pw")}
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.PasswordFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Create(database, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	e := enroll(t, database, cfg)
+	keyring.Lock()
+
+	if err := Login(database, cfg, "", "correct horse", ""); !errors.Is(err, ErrCodeRequired) {
+		t.Fatalf("no code: got %v", err)
+	}
+	if !keyring.Locked() {
+		t.Fatal("keyring opened without a code")
+	}
+	totp := e.TOTP()
+	if err := Login(database, cfg, "", "correct horse", totp.CodeAt(totp.Counter(time.Now())+5)); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("wrong code: got %v", err)
+	}
+	if !keyring.Locked() {
+		t.Fatal("keyring left open after a wrong code")
+	}
+	if err := Login(database, cfg, "", "correct horse", totp.Code()); err != nil {
+		t.Fatalf("right code: %v", err)
+	}
+	if keyring.Locked() {
+		t.Fatal("keyring still locked after a full login")
+	}
+}
@@ -0,0 +1,85 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238 is the SHA1 secret from RFC 6238 appendix B.
+var rfc6238 = []byte("12345678901234567890")
+
+func at(sec int64) func() time.Time {
+	return func() time.Time { return time.Unix(sec, 0) }
+}
+
+func TestRFC6238Vectors(t *testing.T) {
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	} {
+		totp := New(rfc6238)
+		totp.Digits = 8
+		totp.Now = at(tc.unix)
+		if got := totp.Code(); got != tc.code {
+			t.Errorf("code at %d = %s, want %s", tc.unix, got, tc.code)
+		}
+		if !totp.Verify(tc.code) {
+			t.Errorf("Verify(%s) at %d = false", tc.code, tc.unix)
+		}
+	}
+}
+
+func TestMatchWindow(t *testing.T) {
+	totp := New(rfc6238)
+	totp.Now = at(1111111111)
+	now := totp.Counter(totp.Now())
+
+	for _, d := range []int64{-1, 0, 1} {
+		c, ok := totp.Match(totp.CodeAt(now + d))
+		if !ok || c != now+d {
+			t.Errorf("code for step %+d: got (%d, %v), want (%d, true)", d, c, ok, now+d)
+		}
+	}
+	for _, d := range []int64{-2, 2} {
+		if _, ok := totp.Match(totp.CodeAt(now + d)); ok {
+			t.Errorf("code for step %+d accepted outside the skew window", d)
+		}
+	}
+
+	totp.Skew = 0
+	if _, ok := totp.Match(totp.CodeAt(now - 1)); ok {
+		t.Error("previous step accepted with Skew 0")
+	}
+}
+
+func TestMatchRejectsMalformedCodes(t *testing.T) {
+	totp := New(rfc6238)
+	totp.Now = at(59)
+	code := totp.Code()
+	for _, c := range []string{"", code[:5], code + "0", "abcdef"} {
+		if totp.Verify(c) {
+			t.Errorf("Verify(%q) = true", c)
+		}
+	}
+	if !totp.Verify(" " + code + " ") {
+		t.Error("surrounding spaces should be ignored")
+	}
+}
+
+func TestSecretEncoding(t *testing.T) {
+	s := EncodeSecret(rfc6238)
+	got, err := DecodeSecret("  " + s[:4] + " " + s[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(rfc6238) {
+		t.Fatalf("DecodeSecret(EncodeSecret(x)) = %q", got)
+	}
+}
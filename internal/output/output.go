@@ -0,0 +1,108 @@
+// Package output renders command results as an aligned table, plain
+// tab-separated lines, JSON or YAML so scripts can rely on a stable shape.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	Plain Format = "plain"
+)
+
+func Parse(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Table, JSON, YAML, Plain:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want table, json, yaml or plain)", s)
+}
+
+// Structured reports whether f is meant for programs rather than people.
+func (f Format) Structured() bool {
+	return f == JSON || f == YAML
+}
+
+// Rows is the tabular view of a result: a header and one row per item.
+// Plain output prints the rows without the header.
+type Rows struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Write renders v in f. JSON and YAML encode v itself; table and plain
+// use rows.
+func Write(w io.Writer, f Format, v any, rows Rows) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		return writeYAML(w, v)
+	case Plain:
+		for _, r := range rows.Rows {
+			if _, err := fmt.Fprintln(w, strings.Join(r, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(rows.Header) > 0 {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(rows.Header, "\t")))
+	}
+	for _, r := range rows.Rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeYAML goes through JSON so field names and order match the json
+// tags, then clears the flow style the JSON source would otherwise keep.
+func writeYAML(w io.Writer, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	var reset func(n *yaml.Node)
+	reset = func(n *yaml.Node) {
+		if n.Kind != yaml.ScalarNode {
+			n.Style = 0
+		} else if n.Style == yaml.DoubleQuotedStyle {
+			n.Style = 0
+		}
+		for _, c := range n.Content {
+			reset(c)
+		}
+	}
+	reset(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type item struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+	Note  string   `json:"note,omitempty"`
+}
+
+var (
+	items = []item{{Name: "a", Count: 1, Tags: []string{"x", "y"}}, {Name: "yes", Count: 2, Note: "0123"}}
+	rows  = Rows{Header: []string{"name", "count"}, Rows: [][]string{{"a", "1"}, {"yes", "2"}}}
+)
+
+func render(t *testing.T, f Format) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, f, items, rows); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]Format{"table": Table, "JSON": JSON, "yaml": YAML, "Plain": Plain} {
+		if got, err := Parse(in); err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := Parse("xml"); err == nil {
+		t.Error("Parse(xml) succeeded")
+	}
+	if Table.Structured() || Plain.Structured() || !JSON.Structured() || !YAML.Structured() {
+		t.Error("Structured() is wrong")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var got []item
+	if err := json.Unmarshal([]byte(render(t, JSON)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Tags[1] != "y" || got[1].Note != "0123" {
+		t.Fatalf("JSON round trip = %+v", got)
+	}
+}
+
+func TestYAMLKeepsJSONNamesAndTypes(t *testing.T) {
+	out := render(t, YAML)
+	if strings.Contains(out, "{") || strings.Contains(out, "[") {
+		t.Fatalf("YAML kept flow style:\n%s", out)
+	}
+	var got []item
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	// "yes" and "0123" must stay strings rather than a bool and a number.
+	if len(got) != 2 || got[1].Name != "yes" || got[1].Note != "0123" || got[0].Tags[0] != "x" {
+		t.Fatalf("YAML round trip = %+v\n%s", got, out)
+	}
+	if !strings.Contains(out, "count: 1") {
+		t.Fatalf("YAML does not use the json field names:\n%s", out)
+	}
+}
+
+func TestPlainHasNoHeader(t *testing.T) {
+	if got, want := render(t, Plain), "a\t1\nyes\t2\n"; got != want {
+		t.Fatalf("plain = %q, want %q", got, want)
+	}
+}
+
+func TestTableAlignsColumns(t *testing.T) {
+	lines := strings.Split(strings.TrimRight(render(t, Table), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("table = %q", lines)
+	}
+	col := strings.Index(lines[0], "COUNT")
+	for _, l := range lines[1:] {
+		if len(l) <= col || l[col-1] != ' ' || l[col] == ' ' {
+			t.Fatalf("column not aligned at %d in %q", col, lines)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package progress reports how far a transfer has got. The aws layer emits
+// Events through a Func as bytes move; the TUI (or anything else) renders
+// them.
+package progress
+
+import (
+	"io"
+	"time"
+)
+
+type Stage string
+
+const (
+	Hashing     Stage = "hashing"
+	Encrypting  Stage = "encrypting"
+	Uploading   Stage = "uploading"
+	Downloading Stage = "downloading"
+)
+
+type Event struct {
+	Stage   Stage
+	Bytes   int64
+	Total   int64 // -1 when unknown
+	Elapsed time.Duration
+	Rate    float64       // bytes per second
+	ETA     time.Duration // 0 when Total is unknown
+}
+
+// Fraction is Bytes/Total clamped to [0, 1], or 0 when Total is unknown.
+func (e Event) Fraction() float64 {
+	if e.Total <= 0 {
+		return 0
+	}
+	f := float64(e.Bytes) / float64(e.Total)
+	return min(max(f, 0), 1)
+}
+
+type Func func(Event)
+
+// interval throttles events so a fast local copy doesn't flood the UI.
+const interval = 100 * time.Millisecond
+
+// now is the meters' clock, replaced in tests.
+var now = time.Now
+
+type meter struct {
+	fn    Func
+	stage Stage
+	total int64
+	n     int64
+	start time.Time
+	last  time.Time
+	done  bool
+}
+
+func newMeter(fn Func, stage Stage, total int64) *meter {
+	t := now()
+	m := &meter{fn: fn, stage: stage, total: total, start: t, last: t}
+	fn(m.event(t))
+	return m
+}
+
+// add counts n more bytes. Final events are always sent, but only once
+// for the same count: a reader's EOF after it reached the total adds
+// nothing new. Events in between are sent at most every interval.
+func (m *meter) add(n int, final bool) {
+	m.n += int64(n)
+	if final && m.done && n == 0 {
+		return
+	}
+	t := now()
+	if final || t.Sub(m.last) >= interval {
+		m.done = final
+		m.last = t
+		m.fn(m.event(t))
+	}
+}
+
+// complete reports whether n more bytes bring the meter to its total.
+func (m *meter) complete(n int) bool {
+	return n > 0 && m.n+int64(n) == m.total
+}
+
+func (m *meter) event(now time.Time) Event {
+	e := Event{Stage: m.stage, Bytes: m.n, Total: m.total, Elapsed: now.Sub(m.start)}
+	if secs := e.Elapsed.Seconds(); secs > 0 {
+		e.Rate = float64(m.n) / secs
+	}
+	if m.total > 0 && e.Rate > 0 && m.n < m.total {
+		e.ETA = time.Duration(float64(m.total-m.n) / e.Rate * float64(time.Second))
+	}
+	return e
+}
+
+type reader struct {
+	r io.Reader
+	m *meter
+}
+
+// NewReader reports bytes read from r under stage. With a nil fn it
+// returns r unchanged.
+func NewReader(r io.Reader, fn Func, stage Stage, total int64) io.Reader {
+	if fn == nil {
+		return r
+	}
+	return &reader{r: r, m: newMeter(fn, stage, total)}
+}
+
+func (p *reader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.m.add(n, err == io.EOF || p.m.complete(n))
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	m *meter
+}
+
+// NewWriter reports bytes written to w under stage. With a nil fn it
+// returns w unchanged.
+func NewWriter(w io.Writer, fn Func, stage Stage, total int64) io.Writer {
+	if fn == nil {
+		return w
+	}
+	return &writer{w: w, m: newMeter(fn, stage, total)}
+}
+
+func (p *writer) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.m.add(n, p.m.complete(n))
+	return n, err
+}
@@ -0,0 +1,132 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces the meters' clock; advance moves it forward.
+func fakeClock(t *testing.T) (advance func(time.Duration)) {
+	t.Helper()
+	cur := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return cur }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { cur = cur.Add(d) }
+}
+
+func record(events *[]Event) Func {
+	return func(e Event) { *events = append(*events, e) }
+}
+
+func TestFraction(t *testing.T) {
+	for _, tc := range []struct {
+		bytes, total int64
+		want         float64
+	}{
+		{0, 100, 0},
+		{25, 100, 0.25},
+		{100, 100, 1},
+		{150, 100, 1},
+		{-5, 100, 0},
+		{50, 0, 0},
+		{50, -1, 0},
+	} {
+		if got := (Event{Bytes: tc.bytes, Total: tc.total}).Fraction(); got != tc.want {
+			t.Errorf("Fraction(%d/%d) = %v, want %v", tc.bytes, tc.total, got, tc.want)
+		}
+	}
+}
+
+func TestReaderFinishesAtTotal(t *testing.T) {
+	fakeClock(t)
+	var events []Event
+	r := NewReader(strings.NewReader("0123456789"), record(&events), Downloading, 10)
+
+	buf := make([]byte, 10)
+	if n, err := r.Read(buf); n != 10 || err != nil {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	// No time has passed, so only the final event gets through the
+	// throttle: reaching the total must count as final without an EOF.
+	if len(events) != 2 || events[1].Bytes != 10 || events[1].Fraction() != 1 {
+		t.Fatalf("events after reading everything = %+v", events)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("Read at EOF = %d, %v", n, err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("EOF after the total repeated the final event: %+v", events)
+	}
+}
+
+func TestReaderFinishesAtEOFWithUnknownTotal(t *testing.T) {
+	fakeClock(t)
+	var events []Event
+	if _, err := io.Copy(io.Discard, NewReader(strings.NewReader("abc"), record(&events), Hashing, -1)); err != nil {
+		t.Fatal(err)
+	}
+	last := events[len(events)-1]
+	if last.Bytes != 3 || last.Total != -1 || last.ETA != 0 || last.Fraction() != 0 {
+		t.Fatalf("last event = %+v", last)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	advance := fakeClock(t)
+	var events []Event
+	w := NewWriter(io.Discard, record(&events), Uploading, 100)
+	if len(events) != 1 || events[0].Bytes != 0 || events[0].Stage != Uploading {
+		t.Fatalf("first event = %+v", events)
+	}
+
+	for range 5 {
+		advance(10 * time.Millisecond)
+		w.Write(make([]byte, 10))
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events within one interval", len(events))
+	}
+	advance(interval)
+	w.Write(make([]byte, 10))
+	if len(events) != 2 || events[1].Bytes != 60 {
+		t.Fatalf("after an interval: %+v", events)
+	}
+	w.Write(make([]byte, 40))
+	if len(events) != 3 || events[2].Bytes != 100 {
+		t.Fatalf("reaching the total was throttled: %+v", events)
+	}
+}
+
+func TestRateAndETA(t *testing.T) {
+	advance := fakeClock(t)
+	var events []Event
+	w := NewWriter(&bytes.Buffer{}, record(&events), Downloading, 1000)
+
+	advance(2 * time.Second)
+	w.Write(make([]byte, 250))
+	e := events[len(events)-1]
+	if e.Elapsed != 2*time.Second || e.Rate != 125 || e.ETA != 6*time.Second {
+		t.Fatalf("at 250/1000 after 2s: %+v", e)
+	}
+
+	advance(6 * time.Second)
+	w.Write(make([]byte, 750))
+	e = events[len(events)-1]
+	if e.Bytes != 1000 || e.Rate != 125 || e.ETA != 0 {
+		t.Fatalf("at the total: %+v", e)
+	}
+}
+
+func TestNilFunc(t *testing.T) {
+	r := strings.NewReader("x")
+	if got := NewReader(r, nil, Hashing, 1); got != io.Reader(r) {
+		t.Error("NewReader with a nil Func wrapped the reader")
+	}
+	var buf bytes.Buffer
+	if got := NewWriter(&buf, nil, Downloading, 1); got != io.Writer(&buf) {
+		t.Error("NewWriter with a nil Func wrapped the writer")
+	}
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"vault-cli/internal/core"
+)
+
+var ErrExpired = errors.New("secret expired")
+
+// Never, as a Secret's ExpiresAt or RotateEvery, clears the stored value
+// when the secret is written. Leaving either empty keeps what is stored.
+const Never = "never"
+
+// Expired reports whether s has an expiry at or before now.
+func (s Secret) Expired(now time.Time) bool {
+	t, ok := parseTime(s.ExpiresAt)
+	return ok && !t.After(now)
+}
+
+// RotationDue is when s should next be rotated: its last update plus
+// RotateEvery. ok is false when no rotation period is set.
+func (s Secret) RotationDue() (due time.Time, ok bool) {
+	if s.RotateEvery == "" {
+		return time.Time{}, false
+	}
+	every, err := core.ParseDuration(s.RotateEvery)
+	if err != nil {
+		return time.Time{}, false
+	}
+	updated, ok := parseTime(s.UpdatedAt)
+	if !ok {
+		return time.Time{}, false
+	}
+	return updated.Add(every), true
+}
+
+// DueBy reports whether s expires or needs rotating at or before t.
+func (s Secret) DueBy(t time.Time) bool {
+	if s.Expired(t) {
+		return true
+	}
+	due, ok := s.RotationDue()
+	return ok && !due.After(t)
+}
+
+func parseTime(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, err == nil
+}
+
+// Status names whichever of s's dates has passed by now: "expired",
+// "rotation overdue", or "" when neither has.
+func (s Secret) Status(now time.Time) string {
+	if s.Expired(now) {
+		return "expired"
+	}
+	if due, ok := s.RotationDue(); ok && !due.After(now) {
+		return "rotation overdue"
+	}
+	return ""
+}
+
+// ValidateExpiry checks the ExpiresAt and RotateEvery a caller wants to
+// store: RFC 3339 and a duration respectively, or Never, or empty.
+func ValidateExpiry(s Secret) error {
+	if s.ExpiresAt != "" && s.ExpiresAt != Never {
+		if _, ok := parseTime(s.ExpiresAt); !ok {
+			return fmt.Errorf("invalid expires_at %q (want RFC 3339 or %q)", s.ExpiresAt, Never)
+		}
+	}
+	if s.RotateEvery != "" && s.RotateEvery != Never {
+		if _, err := core.ParseDuration(s.RotateEvery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vault-cli/internal/db/dbtest"
+)
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		s    Secret
+		want string
+	}{
+		{Secret{}, ""},
+		{Secret{ExpiresAt: "2026-06-01T00:00:00Z"}, "expired"},
+		{Secret{ExpiresAt: "2026-07-01T00:00:00Z"}, ""},
+		{Secret{UpdatedAt: "2026-01-01T00:00:00Z", RotateEvery: "90d"}, "rotation overdue"},
+		{Secret{UpdatedAt: "2026-05-01T00:00:00Z", RotateEvery: "90d"}, ""},
+		{Secret{UpdatedAt: "2026-01-01T00:00:00Z", RotateEvery: "90d", ExpiresAt: "2026-02-01T00:00:00Z"}, "expired"},
+	} {
+		if got := tc.s.Status(now); got != tc.want {
+			t.Errorf("Status(%+v) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestDueBy(t *testing.T) {
+	s := Secret{UpdatedAt: "2026-01-01T00:00:00Z", RotateEvery: "2w"}
+	if s.DueBy(time.Date(2026, 1, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("due a day early")
+	}
+	if !s.DueBy(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("not due after two weeks")
+	}
+}
+
+func TestValidateExpiry(t *testing.T) {
+	for _, s := range []Secret{
+		{},
+		{ExpiresAt: Never, RotateEvery: Never},
+		{ExpiresAt: "2030-01-01T00:00:00Z", RotateEvery: "12h"},
+	} {
+		if err := ValidateExpiry(s); err != nil {
+			t.Errorf("ValidateExpiry(%+v): %v", s, err)
+		}
+	}
+	for _, s := range []Secret{
+		{ExpiresAt: "tomorrow"},
+		{ExpiresAt: "2030-01-01"},
+		{RotateEvery: "0d"},
+		{RotateEvery: "-1h"},
+	} {
+		if err := ValidateExpiry(s); err == nil {
+			t.Errorf("ValidateExpiry(%+v) succeeded", s)
+		}
+	}
+}
+
+func TestGetRefusesExpired(t *testing.T) {
+	database := dbtest.Open(t)
+	if err := Add(database, local, Secret{Category: "ci", Name: "token", Value: "v", ExpiresAt: "2020-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(database, local, "ci", "token"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Get: got %v, want ErrExpired", err)
+	}
+	if v, err := GetIncludingExpired(database, local, "ci", "token"); err != nil || v != "v" {
+		t.Fatalf("GetIncludingExpired = %q, %v", v, err)
+	}
+}
+
+func TestPolicyInheritance(t *testing.T) {
+	database := dbtest.Open(t)
+	for _, p := range []Policy{
+		{Prefix: "prod", RotateEvery: "90d", TTL: "30d"},
+		{Prefix: "prod/db", RotateEvery: "7d"},
+	} {
+		if err := SetPolicy(database, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Add(database, local, Secret{Category: "prod/db", Name: "pw", Value: "v"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Lookup(database, "prod/db", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RotateEvery != "7d" || s.RotatePolicy != "prod/db" {
+		t.Fatalf("rotation = %q from %q, want 7d from the longest prefix", s.RotateEvery, s.RotatePolicy)
+	}
+	expires, ok := parseTime(s.ExpiresAt)
+	if want := time.Now().Add(30 * 24 * time.Hour); !ok || expires.Sub(want).Abs() > time.Minute {
+		t.Fatalf("expires_at = %q, want about 30 days from now", s.ExpiresAt)
+	}
+}
@@ -0,0 +1,254 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flat name=value formats for bulk import and export.
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+)
+
+// FormatFromPath guesses a format from a file name, or returns "".
+func FormatFromPath(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case base == ".env" || strings.HasSuffix(base, ".env") || strings.HasPrefix(base, ".env."):
+		return FormatDotenv
+	case strings.HasSuffix(base, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(base, ".yaml") || strings.HasSuffix(base, ".yml"):
+		return FormatYAML
+	}
+	return ""
+}
+
+// Parse reads a flat name -> value document. JSON and YAML values may be
+// strings, numbers or booleans; nested values are rejected.
+func Parse(format string, data []byte) (map[string]string, error) {
+	switch format {
+	case FormatDotenv:
+		return parseDotenv(data)
+	case FormatJSON:
+		var raw map[string]any
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		return flatten(raw)
+	case FormatYAML:
+		return parseYAML(data)
+	}
+	return nil, fmt.Errorf("unknown format %q (want dotenv, json or yaml)", format)
+}
+
+// parseYAML keeps each scalar exactly as written, so 0123, 1.10, yes and
+// 2024-01-01 stay those strings instead of becoming a number, bool or
+// timestamp and being printed back differently.
+func parseYAML(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return map[string]string{}, nil
+	}
+	m := doc.Content[0]
+	if m.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("yaml: want a mapping of names to values")
+	}
+	out := make(map[string]string, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, v := m.Content[i], m.Content[i+1]
+		if v.Kind == yaml.AliasNode {
+			v = v.Alias
+		}
+		if k.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("yaml: line %d: keys must be plain names", k.Line)
+		}
+		if v.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s: nested values are not supported", k.Value)
+		}
+		if _, dup := out[k.Value]; dup {
+			return nil, fmt.Errorf("yaml: line %d: %s is set twice", k.Line, k.Value)
+		}
+		if v.Tag == "!!null" {
+			out[k.Value] = ""
+		} else {
+			out[k.Value] = v.Value
+		}
+	}
+	return out, nil
+}
+
+func flatten(raw map[string]any) (map[string]string, error) {
+	out := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case string:
+			out[k] = v
+		case json.Number, int, float64, bool:
+			out[k] = fmt.Sprint(v)
+		case nil:
+			out[k] = ""
+		default:
+			return nil, fmt.Errorf("%s: nested values are not supported", k)
+		}
+	}
+	return out, nil
+}
+
+// Format writes items as a flat document keyed by secret name, sorted by
+// name.
+func Format(format string, items []Secret) ([]byte, error) {
+	values := make(map[string]string, len(items))
+	for _, s := range items {
+		values[s.Name] = s.Value
+	}
+	switch format {
+	case FormatDotenv:
+		names := make([]string, 0, len(values))
+		for n := range values {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		var b bytes.Buffer
+		for _, n := range names {
+			fmt.Fprintf(&b, "%s=%s\n", n, quoteDotenv(values[n]))
+		}
+		return b.Bytes(), nil
+	case FormatJSON:
+		b, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(values)
+	}
+	return nil, fmt.Errorf("unknown format %q (want dotenv, json or yaml)", format)
+}
+
+// parseDotenv accepts KEY=value lines with an optional "export " prefix.
+// Double-quoted values understand \n, \r, \t, \", \\ and \$; single-quoted
+// values are literal; unquoted values end at " #".
+func parseDotenv(data []byte) (map[string]string, error) {
+	out := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: want KEY=value", n)
+		}
+		val = strings.TrimSpace(val)
+		switch {
+		case strings.HasPrefix(val, `"`):
+			end := closingQuote(val)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			val = unescapeDotenv(val[1:end])
+		case strings.HasPrefix(val, "'"):
+			end := strings.Index(val[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			val = val[1 : end+1]
+		default:
+			if i := strings.Index(val, " #"); i >= 0 {
+				val = strings.TrimSpace(val[:i])
+			}
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", n, key)
+		}
+		out[key] = val
+	}
+	return out, sc.Err()
+}
+
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func quoteDotenv(v string) string {
+	plain := v != ""
+	for _, c := range v {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_-./:@+,=", c)) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return v
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range v {
+		switch c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '$':
+			b.WriteString(`\$`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAMLKeepsScalarsAsWritten(t *testing.T) {
+	got, err := Parse(FormatYAML, []byte(`
+zip: 0123
+version: 1.10
+enabled: yes
+date: 2024-01-01
+big: 12345678901234567890
+quoted: "007"
+empty:
+tilde: ~
+multi: |
+  line one
+  line two
+anchor: &a value
+ref: *a
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"zip": "0123", "version": "1.10", "enabled": "yes", "date": "2024-01-01",
+		"big": "12345678901234567890", "quoted": "007", "empty": "", "tilde": "",
+		"multi": "line one\nline two\n", "anchor": "value", "ref": "value",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse yaml =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestParseYAMLRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"nested":    "a:\n  b: c\n",
+		"list":      "a: [1, 2]\n",
+		"top list":  "- a\n- b\n",
+		"duplicate": "a: 1\na: 2\n",
+		"broken":    "a: 'x\n",
+	} {
+		if _, err := Parse(FormatYAML, []byte(doc)); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+func TestParseJSONKeepsNumbers(t *testing.T) {
+	got, err := Parse(FormatJSON, []byte(`{"port": 5432, "ratio": 1.10, "on": true, "none": null, "s": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"port": "5432", "ratio": "1.10", "on": "true", "none": "", "s": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse json = %v, want %v", got, want)
+	}
+	if _, err := Parse(FormatJSON, []byte(`{"a": {"b": 1}}`)); err == nil {
+		t.Fatal("nested json parsed")
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	got, err := Parse(FormatDotenv, []byte(`
+# comment
+export A=plain # trailing comment
+B="line\nbreak \"quoted\" \$HOME"
+C='single $literal \n'
+D=
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A": "plain", "B": "line\nbreak \"quoted\" $HOME", "C": `single $literal \n`, "D": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Parse dotenv = %q, want %q", got, want)
+	}
+	for _, doc := range []string{"NOEQUALS\n", "A=\"open\n", "A=1\nA=2\n"} {
+		if _, err := Parse(FormatDotenv, []byte(doc)); err == nil {
+			t.Errorf("dotenv %q parsed", doc)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	items := []Secret{
+		{Name: "plain", Value: "abc"},
+		{Name: "zip", Value: "0123"},
+		{Name: "bool", Value: "yes"},
+		{Name: "empty", Value: ""},
+		{Name: "tricky", Value: "a \"b\" $c\\d\n\te # f"},
+		{Name: "json", Value: `{"username":"u","password":"p"}`},
+	}
+	want := map[string]string{}
+	for _, s := range items {
+		want[s.Name] = s.Value
+	}
+	for _, f := range []string{FormatDotenv, FormatJSON, FormatYAML} {
+		out, err := Format(f, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(f, out)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", f, err, out)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s round trip =\n%q\nwant\n%q", f, got, want)
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		".env": FormatDotenv, "prod.env": FormatDotenv, ".env.local": FormatDotenv,
+		"a.JSON": FormatJSON, "x/y.yml": FormatYAML, "b.yaml": FormatYAML, "c.txt": "",
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Meta is the descriptive part of a secret. It is stored unencrypted so
+// secrets can be searched without unlocking the vault; never put the
+// secret itself in here.
+type Meta struct {
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Fields      []Field  `json:"fields,omitempty"`
+}
+
+// Field is a named, typed custom value such as a login URL or username.
+type Field struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// FieldTypes are the accepted Field.Type values; "text" is the default.
+var FieldTypes = []string{"text", "url", "username", "email"}
+
+// NormalizeMeta trims m, sorts and de-duplicates its tags and checks each
+// field's name, type and value.
+func NormalizeMeta(m Meta) (Meta, error) {
+	m.Description = strings.TrimSpace(m.Description)
+	m.Owner = strings.TrimSpace(m.Owner)
+
+	var tags []string
+	for _, t := range m.Tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if strings.ContainsAny(t, ", \t\n") {
+			return Meta{}, fmt.Errorf("tag %q: tags cannot contain commas or spaces", t)
+		}
+		tags = append(tags, t)
+	}
+	slices.Sort(tags)
+	m.Tags = slices.Compact(tags)
+
+	seen := map[string]bool{}
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		f.Name = strings.TrimSpace(f.Name)
+		if f.Type == "" {
+			f.Type = "text"
+		}
+		if f.Name == "" {
+			return Meta{}, fmt.Errorf("field with empty name")
+		}
+		if seen[f.Name] {
+			return Meta{}, fmt.Errorf("field %s is set twice", f.Name)
+		}
+		seen[f.Name] = true
+		if err := checkField(*f); err != nil {
+			return Meta{}, err
+		}
+	}
+	return m, nil
+}
+
+func checkField(f Field) error {
+	switch f.Type {
+	case "text", "username":
+		return nil
+	case "url":
+		u, err := url.Parse(f.Value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("field %s: %q is not an absolute URL", f.Name, f.Value)
+		}
+		return nil
+	case "email":
+		if _, err := mail.ParseAddress(f.Value); err != nil {
+			return fmt.Errorf("field %s: %q is not an email address", f.Name, f.Value)
+		}
+		return nil
+	}
+	return fmt.Errorf("field %s: unknown type %q (want %s)", f.Name, f.Type, strings.Join(FieldTypes, ", "))
+}
+
+// HasTags reports whether s carries every one of tags.
+func (s Secret) HasTags(tags []string) bool {
+	for _, t := range tags {
+		if !slices.Contains(s.Tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMeta replaces a secret's metadata. The value and its timestamps are
+// left alone, so editing a description doesn't count as a rotation.
+func (m Meta) empty() bool {
+	return m.Description == "" && m.Owner == "" && len(m.Tags) == 0 && len(m.Fields) == 0
+}
+
+func SetMeta(database *sql.DB, category, name string, m Meta) error {
+	return setMeta(database, category, name, m)
+}
+
+func setMeta(ex execer, category, name string, m Meta) error {
+	m, err := NormalizeMeta(m)
+	if err != nil {
+		return err
+	}
+	tags, fields, err := encodeMeta(m)
+	if err != nil {
+		return err
+	}
+	res, err := ex.Exec(`UPDATE secrets SET description=?, owner=?, tags=?, fields=? WHERE category=? AND name=?`,
+		m.Description, m.Owner, tags, fields, category, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, Path(category, name))
+	}
+	return nil
+}
+
+// encodeMeta stores tags and fields as JSON, or "" when there are none.
+func encodeMeta(m Meta) (string, string, error) {
+	var tags, fields string
+	if len(m.Tags) > 0 {
+		b, err := json.Marshal(m.Tags)
+		if err != nil {
+			return "", "", err
+		}
+		tags = string(b)
+	}
+	if len(m.Fields) > 0 {
+		b, err := json.Marshal(m.Fields)
+		if err != nil {
+			return "", "", err
+		}
+		fields = string(b)
+	}
+	return tags, fields, nil
+}
+
+func decodeMeta(m *Meta, tags, fields string) error {
+	if tags != "" {
+		if err := json.Unmarshal([]byte(tags), &m.Tags); err != nil {
+			return fmt.Errorf("tags: %w", err)
+		}
+	}
+	if fields != "" {
+		if err := json.Unmarshal([]byte(fields), &m.Fields); err != nil {
+			return fmt.Errorf("fields: %w", err)
+		}
+	}
+	return nil
+}
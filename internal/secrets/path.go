@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"vault-cli/internal/db"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid secret path")
+	ErrExists      = errors.New("secret already exists")
+)
+
+// A secret's path is its category and name joined by "/", for example
+// prod/payments/stripe/api_key: the last segment is the name and the rest
+// the category. Secrets without a category have a one-segment path.
+
+// Path joins category and name.
+func Path(category, name string) string {
+	if category == "" {
+		return name
+	}
+	return category + "/" + name
+}
+
+// SplitPath checks p and splits it into category and name.
+func SplitPath(p string) (category, name string, err error) {
+	if err := ValidatePath(p); err != nil {
+		return "", "", err
+	}
+	i := strings.LastIndex(p, "/")
+	if i < 0 {
+		return "", p, nil
+	}
+	return p[:i], p[i+1:], nil
+}
+
+// ValidatePath rejects empty segments, "." and "..", surrounding spaces
+// and control characters.
+func ValidatePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidPath)
+	}
+	for _, seg := range strings.Split(p, "/") {
+		switch {
+		case seg == "":
+			return fmt.Errorf("%w %q: empty segment", ErrInvalidPath, p)
+		case seg == "." || seg == "..":
+			return fmt.Errorf("%w %q: %q segment", ErrInvalidPath, p, seg)
+		case strings.TrimSpace(seg) != seg:
+			return fmt.Errorf("%w %q: segment %q has surrounding spaces", ErrInvalidPath, p, seg)
+		case strings.IndexFunc(seg, unicode.IsControl) >= 0:
+			return fmt.Errorf("%w %q: control character", ErrInvalidPath, p)
+		}
+	}
+	return nil
+}
+
+// CheckPath reports whether category and name are the split of a valid
+// path, which rules out a "/" in the name.
+func CheckPath(category, name string) error {
+	c, n, err := SplitPath(Path(category, name))
+	if err != nil {
+		return err
+	}
+	if c != category || n != name {
+		return fmt.Errorf("%w %q: name cannot contain \"/\"", ErrInvalidPath, name)
+	}
+	return nil
+}
+
+// InPrefix reports whether path is prefix itself or lies below it. An
+// empty prefix holds everything.
+func InPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// cleanPath is the migration's best effort at a valid path: empty, "."
+// and ".." segments are dropped and segments trimmed.
+func cleanPath(p string) string {
+	var segs []string
+	for _, seg := range strings.Split(p, "/") {
+		seg = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, strings.TrimSpace(seg))
+		if seg != "" && seg != "." && seg != ".." {
+			segs = append(segs, seg)
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// pathsVersion is the schema version at which MigratePaths has run.
+const pathsVersion = 1
+
+// MigratePaths rewrites rows stored before secrets were addressed by path
+// so that every (category, name) is the split of a valid path: a name
+// containing "/" moves its leading segments into the category, and stray
+// slashes or spaces are removed. A row whose cleaned path is taken gets
+// its id appended to the name. Rows that are already valid are untouched.
+// It runs once per database: the schema version records that it has.
+func MigratePaths(database *sql.DB) error {
+	v, err := db.SchemaVersion(database)
+	if err != nil || v >= pathsVersion {
+		return err
+	}
+	rows, err := database.Query(`SELECT id, category, name FROM secrets`)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id             int64
+		category, name string
+	}
+	var bad []row
+	taken := map[string]bool{}
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.category, &r.name); err != nil {
+			rows.Close()
+			return err
+		}
+		p := Path(r.category, r.name)
+		if c, n, err := SplitPath(p); err == nil && c == r.category && n == r.name {
+			taken[p] = true
+			continue
+		}
+		bad = append(bad, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	for _, r := range bad {
+		p := cleanPath(Path(r.category, r.name))
+		if p == "" {
+			p = "unnamed"
+		}
+		for base, n := p, r.id; taken[p]; n++ {
+			p = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[p] = true
+		c, n, err := SplitPath(p)
+		if err == nil {
+			_, err = tx.Exec(`UPDATE secrets SET category=?, name=? WHERE id=?`, c, n, r.id)
+		}
+		if err == nil {
+			_, err = tx.Exec(`INSERT INTO audit(action, filename, target, success, err, ts) VALUES('secret:migrate', ?, ?, 1, '', ?)`,
+				Path(r.category, r.name), p, now())
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migrate secret %q/%q: %w", r.category, r.name, err)
+		}
+	}
+	if err := db.SetSchemaVersion(tx, pathsVersion); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
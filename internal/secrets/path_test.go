@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/db/dbtest"
+)
+
+func TestSplitPath(t *testing.T) {
+	for _, tc := range []struct{ path, category, name string }{
+		{"token", "", "token"},
+		{"prod/token", "prod", "token"},
+		{"prod/payments/stripe/api_key", "prod/payments/stripe", "api_key"},
+	} {
+		c, n, err := SplitPath(tc.path)
+		if err != nil || c != tc.category || n != tc.name {
+			t.Errorf("SplitPath(%q) = %q, %q, %v", tc.path, c, n, err)
+		}
+		if p := Path(c, n); p != tc.path {
+			t.Errorf("Path(%q, %q) = %q", c, n, p)
+		}
+	}
+	for _, p := range []string{"", "/token", "prod/", "prod//token", "prod/../token", "./token", " prod/token", "prod/tok\x00en"} {
+		if _, _, err := SplitPath(p); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("SplitPath(%q): got %v, want ErrInvalidPath", p, err)
+		}
+	}
+	if err := CheckPath("prod", "db/password"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("CheckPath with a slash in the name: %v", err)
+	}
+}
+
+func TestInPrefix(t *testing.T) {
+	for _, tc := range []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"prod/db/pw", "", true},
+		{"prod/db/pw", "prod", true},
+		{"prod/db/pw", "prod/", true},
+		{"prod/db/pw", "prod/db/pw", true},
+		{"prod/db/pw", "prod/d", false},
+		{"production/pw", "prod", false},
+	} {
+		if got := InPrefix(tc.path, tc.prefix); got != tc.want {
+			t.Errorf("InPrefix(%q, %q) = %v", tc.path, tc.prefix, got)
+		}
+	}
+}
+
+// rename stores a row under category and name directly, as builds from
+// before path validation could.
+func rename(t *testing.T, database *sql.DB, from, category, name string) {
+	t.Helper()
+	c, n, _ := SplitPath(from)
+	if _, err := database.Exec(`UPDATE secrets SET category=?, name=? WHERE category=? AND name=?`, category, name, c, n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigratePaths(t *testing.T) {
+	database := dbtest.Open(t)
+	for _, p := range []string{"ok/token", "a", "b", "c"} {
+		c, n, _ := SplitPath(p)
+		if err := Add(database, local, Secret{Category: c, Name: n, Value: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rename(t, database, "a", "prod", "db/password")
+	rename(t, database, "b", " ci ", "token")
+	rename(t, database, "c", "", "ok//token")
+
+	if err := MigratePaths(database); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"prod/db/password": "a",
+		"ci/token":         "b",
+		"ok/token":         "ok/token",
+		"ok/token-4":       "c",
+	} {
+		c, n, _ := SplitPath(path)
+		if v, err := Get(database, local, c, n); err != nil || v != want {
+			t.Errorf("%s = %q, %v; want %q", path, v, err, want)
+		}
+	}
+	var audited int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM audit WHERE action='secret:migrate'`).Scan(&audited); err != nil || audited != 3 {
+		t.Fatalf("audited %d migrations, %v; want 3", audited, err)
+	}
+	if v, err := db.SchemaVersion(database); err != nil || v != pathsVersion {
+		t.Fatalf("schema version = %d, %v; want %d", v, err, pathsVersion)
+	}
+
+	// Later starts don't scan again.
+	rename(t, database, "ci/token", "ci", "x/y")
+	if err := MigratePaths(database); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Lookup(database, "ci", "x/y"); err != nil {
+		t.Fatalf("second run rewrote a row: %v", err)
+	}
+}
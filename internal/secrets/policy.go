@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vault-cli/internal/core"
+)
+
+var ErrNoPolicy = errors.New("no such policy")
+
+// Policy sets defaults for every secret under a path prefix. The most
+// specific prefix that sets a field wins.
+//
+// RotateEvery applies to secrets without their own rotation period, and
+// is looked up whenever secrets are read, so changing a policy takes
+// effect at once. TTL makes each value written under the prefix expire
+// that long after it was written, unless the write sets an expiry.
+type Policy struct {
+	Prefix      string `json:"prefix"`
+	RotateEvery string `json:"rotate_every,omitempty"`
+	TTL         string `json:"ttl,omitempty"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+}
+
+func SetPolicy(database *sql.DB, p Policy) error {
+	if err := ValidatePath(p.Prefix); err != nil {
+		return err
+	}
+	for _, d := range []struct{ flag, v string }{{"rotate_every", p.RotateEvery}, {"ttl", p.TTL}} {
+		if d.v == "" {
+			continue
+		}
+		if _, err := core.ParseDuration(d.v); err != nil {
+			return fmt.Errorf("%s: %w", d.flag, err)
+		}
+	}
+	if p.RotateEvery == "" && p.TTL == "" {
+		return fmt.Errorf("policy for %s sets nothing", p.Prefix)
+	}
+	_, err := database.Exec(`
+		INSERT INTO policies(prefix, rotate_every, ttl, updated_at) VALUES(?,?,?,?)
+		ON CONFLICT(prefix) DO UPDATE SET rotate_every=excluded.rotate_every, ttl=excluded.ttl, updated_at=excluded.updated_at
+	`, p.Prefix, p.RotateEvery, p.TTL, now())
+	return err
+}
+
+func DeletePolicy(database *sql.DB, prefix string) error {
+	res, err := database.Exec(`DELETE FROM policies WHERE prefix=?`, prefix)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrNoPolicy, prefix)
+	}
+	return nil
+}
+
+func Policies(database *sql.DB) ([]Policy, error) {
+	rows, err := database.Query(`SELECT prefix, rotate_every, ttl, updated_at FROM policies ORDER BY prefix`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Policy
+	for rows.Next() {
+		var p Policy
+		if err := rows.Scan(&p.Prefix, &p.RotateEvery, &p.TTL, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// policyFor returns the most specific policy under which path falls that
+// sets the field get returns.
+func policyFor(ps []Policy, path string, get func(Policy) string) (Policy, bool) {
+	var best Policy
+	found := false
+	for _, p := range ps {
+		if get(p) != "" && InPrefix(path, p.Prefix) && (!found || len(p.Prefix) > len(best.Prefix)) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// inherit fills in a rotation period from ps when s has none of its own.
+func inherit(s *Secret, ps []Policy) {
+	if s.RotateEvery != "" {
+		return
+	}
+	p, ok := policyFor(ps, Path(s.Category, s.Name), func(p Policy) string { return p.RotateEvery })
+	if !ok {
+		return
+	}
+	s.RotateEvery, s.RotatePolicy = p.RotateEvery, p.Prefix
+	if due, ok := s.RotationDue(); ok {
+		s.RotateDue = due.UTC().Format(time.RFC3339)
+	}
+}
+
+// applyTTL gives s an expiry from its prefix's policy when the write
+// doesn't set one.
+func applyTTL(ps []Policy, s *Secret) error {
+	if s.ExpiresAt != "" {
+		return nil
+	}
+	p, ok := policyFor(ps, Path(s.Category, s.Name), func(p Policy) string { return p.TTL })
+	if !ok {
+		return nil
+	}
+	ttl, err := core.ParseDuration(p.TTL)
+	if err != nil {
+		return fmt.Errorf("policy %s: %w", p.Prefix, err)
+	}
+	s.ExpiresAt = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+	return nil
+}
@@ -7,31 +7,37 @@ import (
 	"vault-cli/internal/config"
 )
 
+// Rotate re-encrypts every secret under fresh data keys. The values do not
+// change, so expired secrets are included and updated_at is kept: key
+// rotation must not reset anyone's rotation reminder.
 func Rotate(database *sql.DB, cfg *config.Config) (int, error) {
-	rows, err := database.Query(`SELECT category, name FROM secrets`)
+	// List collects everything first: re-encrypting writes to the table,
+	// which SQLite refuses while a read cursor is still open.
+	items, err := List(database, "")
 	if err != nil {
 		return 0, err
 	}
-	defer rows.Close()
 
 	count := 0
-	for rows.Next() {
-		var c, n string
-		if err := rows.Scan(&c, &n); err != nil {
-			return count, err
-		}
+	for _, s := range items {
+		c, n := s.Category, s.Name
 
-		plain, err := Get(database, cfg, c, n)
+		plain, err := GetIncludingExpired(database, cfg, c, n)
 		if err != nil {
-			return count, fmt.Errorf("get secret %s/%s: %w", c, n, err)
+			return count, fmt.Errorf("get secret %s: %w", Path(c, n), err)
 		}
 
-		if err := Add(database, cfg, Secret{Category: c, Name: n, Value: plain}); err != nil {
-			return count, fmt.Errorf("re-encrypt %s/%s: %w", c, n, err)
+		row, err := seal(cfg, Secret{Category: c, Name: n, Type: s.Type, Value: plain})
+		if err == nil {
+			row.updated = s.UpdatedAt
+			err = row.put(database)
+		}
+		if err != nil {
+			return count, fmt.Errorf("re-encrypt %s: %w", Path(c, n), err)
 		}
 
 		count++
 	}
 
-	return count, rows.Err()
+	return count, nil
 }
@@ -6,77 +6,279 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"vault-cli/internal/aws"
 	"vault-cli/internal/config"
 	"vault-cli/internal/core"
+	"vault-cli/internal/keyring"
+)
+
+var (
+	ErrNotFound  = errors.New("secret not found")
+	ErrIntegrity = errors.New("secret failed integrity check")
 )
 
 type Secret struct {
-	Category string
-	Name     string
-	Value    string
-    CreatedAt string
-    UpdatedAt string
+	// Path is Category and Name joined by "/", filled in on read.
+	Path     string `json:"path,omitempty"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	// Type is empty for a plain string value, otherwise one of Types.
+	Type      string `json:"type,omitempty"`
+	Value     string `json:"value,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	// ExpiresAt (RFC 3339) is when reads start being refused. RotateEvery
+	// is a duration such as "90d"; RotateDue is derived from it on read.
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	RotateEvery string `json:"rotate_every,omitempty"`
+	RotateDue   string `json:"rotate_due,omitempty"`
+	// RotatePolicy is the prefix whose policy supplied RotateEvery, when
+	// the secret has no rotation period of its own.
+	RotatePolicy string `json:"rotate_policy,omitempty"`
+	Meta
 }
 
 func Add(database *sql.DB, cfg *config.Config, s Secret) error {
+	if err := CheckPath(s.Category, s.Name); err != nil {
+		return err
+	}
+	if err := prepare(&s); err != nil {
+		return err
+	}
+	ps, err := Policies(database)
+	if err != nil {
+		return err
+	}
+	if err := applyTTL(ps, &s); err != nil {
+		return err
+	}
+	row, err := seal(cfg, s)
+	if err != nil {
+		return err
+	}
+	return row.put(database)
+}
+
+// Import stores items in a single transaction. Every value is encrypted
+// before the transaction opens, so a KMS failure part way through leaves
+// the store untouched. Type, expiry, rotation period and metadata set on
+// items are stored with them; typed values are checked first.
+func Import(database *sql.DB, cfg *config.Config, items []Secret) error {
+	ps, err := Policies(database)
+	if err != nil {
+		return err
+	}
+	rows := make([]sealed, 0, len(items))
+	for i := range items {
+		s := items[i]
+		if err := CheckPath(s.Category, s.Name); err != nil {
+			return err
+		}
+		if err := prepare(&s); err != nil {
+			return fmt.Errorf("%s: %w", Path(s.Category, s.Name), err)
+		}
+		if err := applyTTL(ps, &s); err != nil {
+			return err
+		}
+		row, err := seal(cfg, s)
+		if err != nil {
+			return fmt.Errorf("encrypt %s: %w", Path(s.Category, s.Name), err)
+		}
+		rows = append(rows, row)
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		err := row.put(tx)
+		if m := items[i].Meta; err == nil && !m.empty() {
+			err = setMeta(tx, row.category, row.name, m)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("store %s: %w", Path(row.category, row.name), err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Restore writes items inside tx exactly as given: timestamps, expiry,
+// rotation period and metadata included, so secrets come back from a backup the way
+// they left. A rotation period that came from a policy is not copied
+// onto the secret.
+func Restore(tx *sql.Tx, cfg *config.Config, items []Secret) error {
+	for _, s := range items {
+		if s.RotatePolicy != "" {
+			s.RotateEvery = ""
+		}
+		row, err := seal(cfg, s)
+		if err != nil {
+			return fmt.Errorf("encrypt %s: %w", Path(s.Category, s.Name), err)
+		}
+		row.created, row.updated = s.CreatedAt, s.UpdatedAt
+		if row.expires == "" {
+			row.expires = Never
+		}
+		if row.rotateEvery == "" {
+			row.rotateEvery = Never
+		}
+		if err := row.put(tx); err != nil {
+			return fmt.Errorf("store %s: %w", Path(s.Category, s.Name), err)
+		}
+		if err := setMeta(tx, s.Category, s.Name, s.Meta); err != nil {
+			return fmt.Errorf("store %s: %w", Path(s.Category, s.Name), err)
+		}
+	}
+	return nil
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// sealed is a secret encrypted and ready to be written. Empty timestamps
+// mean now; empty expires and rotateEvery keep the stored values.
+type sealed struct {
+	category, name, typ, ciphertext, nonce, mode, hash string
+	created, updated                                   string
+	expires, rotateEvery                               string
+}
+
+func seal(cfg *config.Config, s Secret) (sealed, error) {
 	plain := []byte(s.Value)
+	ciphertext, nonce, mode, err := Encrypt(cfg, plain)
+	if err != nil {
+		return sealed{}, err
+	}
+	return sealed{
+		category:    s.Category,
+		name:        s.Name,
+		typ:         s.Type,
+		ciphertext:  ciphertext,
+		nonce:       nonce,
+		mode:        mode,
+		hash:        core.BytesSHA256Hex(plain),
+		expires:     s.ExpiresAt,
+		rotateEvery: s.RotateEvery,
+	}, nil
+}
+
+func (r sealed) put(ex execer) error {
+	if r.created == "" {
+		r.created = now()
+	}
+	if r.updated == "" {
+		r.updated = now()
+	}
+	keepExpires, keepRotate := r.expires == "", r.rotateEvery == ""
+	if r.expires == Never {
+		r.expires = ""
+	}
+	if r.rotateEvery == Never {
+		r.rotateEvery = ""
+	}
+	_, err := ex.Exec(`
+		INSERT INTO secrets(category, name, type, ciphertext, nonce, mode, hash, created_at, updated_at, expires_at, rotate_every)
+		VALUES(?,?,?,?,?,?,?,?,?,?,?) 
+		ON CONFLICT(category, name) DO UPDATE SET 
+			type=excluded.type,
+			ciphertext=excluded.ciphertext,
+			nonce=excluded.nonce,
+			mode=excluded.mode,
+			hash=excluded.hash,
+			updated_at=excluded.updated_at,
+			expires_at=CASE WHEN ? THEN secrets.expires_at ELSE excluded.expires_at END,
+			rotate_every=CASE WHEN ? THEN secrets.rotate_every ELSE excluded.rotate_every END
+	`, r.category, r.name, r.typ, r.ciphertext, r.nonce, r.mode, r.hash, r.created, r.updated, r.expires, r.rotateEvery, keepExpires, keepRotate)
+	return err
+}
+
+// Get decrypts a secret, refusing once it has expired.
+func Get(database *sql.DB, cfg *config.Config, category, name string) (string, error) {
+	return get(database, cfg, category, name, false)
+}
+
+// GetIncludingExpired is Get without the expiry check, for backups, key
+// rotation and reads the user explicitly allows.
+func GetIncludingExpired(database *sql.DB, cfg *config.Config, category, name string) (string, error) {
+	return get(database, cfg, category, name, true)
+}
+
+func get(database *sql.DB, cfg *config.Config, category, name string, allowExpired bool) (string, error) {
+	row := database.QueryRow(`SELECT ciphertext, nonce, mode, expires_at FROM secrets WHERE category=? AND name=?`, category, name)
+	var storedCT, nonceB64, mode, expires string
+	if err := row.Scan(&storedCT, &nonceB64, &mode, &expires); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, Path(category, name))
+		}
+		return "", err
+	}
+	if s := (Secret{ExpiresAt: expires}); !allowExpired && s.Expired(time.Now()) {
+		return "", fmt.Errorf("%w: %s expired at %s", ErrExpired, Path(category, name), expires)
+	}
+	plain, err := Decrypt(cfg, storedCT, nonceB64, mode)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// ModeLocalKeyring marks local-mode records whose data key is wrapped by
+// the keyring master key rather than stored raw.
+const ModeLocalKeyring = "local-kr"
+
+// Encrypt seals plain under a fresh data key (KMS or local, per cfg.Mode)
+// and returns the ciphertext, nonce and the mode to record. The ciphertext
+// is "<wrapped key b64>.<ciphertext b64>", the format stored in the secrets
+// table.
+func Encrypt(cfg *config.Config, plain []byte) (string, string, string, error) {
 	var plainKey, wrappedKey []byte
 	var err error
+	mode := cfg.Mode
 
 	if cfg.Mode == "local" {
 		plainKey, wrappedKey, err = aws.LocalKey()
+		if err == nil && keyring.Initialized() {
+			wrappedKey, err = keyring.Wrap(plainKey)
+			mode = ModeLocalKeyring
+		}
 	} else {
 		plainKey, wrappedKey, err = aws.GenerateDataKey(cfg.KmsKey)
 	}
 	if err != nil {
-		return fmt.Errorf("generate key: %w", err)
+		return "", "", "", fmt.Errorf("generate key: %w", err)
 	}
 	defer zero(plainKey)
 
 	block, err := aes.NewCipher(plainKey)
 	if err != nil {
-		return err
+		return "", "", "", err
 	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return err
+		return "", "", "", err
 	}
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return err
+		return "", "", "", err
 	}
 	ciphertext := gcm.Seal(nil, nonce, plain, nil)
 
-	hash := core.BytesSHA256Hex(plain)
 	encodedWrapped := base64.StdEncoding.EncodeToString(wrappedKey)
-
-	_, err = database.Exec(`
-		INSERT INTO secrets(category, name, ciphertext, nonce, mode, hash, created_at, updated_at)
-		VALUES(?,?,?,?,?,?,?,?) 
-		ON CONFLICT(category, name) DO UPDATE SET 
-			ciphertext=excluded.ciphertext,
-			nonce=excluded.nonce,
-			mode=excluded.mode,
-			hash=excluded.hash,
-			updated_at=excluded.updated_at
-	`, s.Category, s.Name, encodedWrapped+"."+base64.StdEncoding.EncodeToString(ciphertext),
-		base64.StdEncoding.EncodeToString(nonce), cfg.Mode, hash, now(), now())
-	return err
+	return encodedWrapped + "." + base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(nonce), mode, nil
 }
 
-func Get(database *sql.DB, cfg *config.Config, category, name string) (string, error) {
-	row := database.QueryRow(`SELECT ciphertext, nonce, mode FROM secrets WHERE category=? AND name=?`, category, name)
-	var storedCT, nonceB64, mode string
-	if err := row.Scan(&storedCT, &nonceB64, &mode); err != nil {
-		return "", err
-	}
-
+func Decrypt(cfg *config.Config, storedCT, nonceB64, mode string) ([]byte, error) {
 	dot := -1
 	for i := 0; i < len(storedCT); i++ {
 		if storedCT[i] == '.' {
@@ -85,79 +287,150 @@ func Get(database *sql.DB, cfg *config.Config, category, name string) (string, e
 		}
 	}
 	if dot < 0 {
-		return "", fmt.Errorf("malformed ciphertext record")
+		return nil, fmt.Errorf("%w: malformed ciphertext record", ErrIntegrity)
 	}
 	wrappedB64 := storedCT[:dot]
 	ctB64 := storedCT[dot+1:]
 
 	wrappedKey, err := base64.StdEncoding.DecodeString(wrappedB64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	ct, err := base64.StdEncoding.DecodeString(ctB64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	nonce, err := base64.StdEncoding.DecodeString(nonceB64)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var plainKey []byte
-	if mode == "local" || cfg.Mode == "local" {
+	if mode == ModeLocalKeyring {
+		plainKey, err = keyring.Unwrap(wrappedKey)
+		if err != nil {
+			return nil, err
+		}
+	} else if mode == "local" || cfg.Mode == "local" {
 		plainKey = wrappedKey
 	} else {
 		plainKey, err = aws.DecryptDataKey(wrappedKey)
 		if err != nil {
-			return "", fmt.Errorf("decrypt data key: %w", err)
+			return nil, fmt.Errorf("decrypt data key: %w", err)
 		}
 	}
 	defer zero(plainKey)
 
 	block, err := aes.NewCipher(plainKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	plain, err := gcm.Open(nil, nonce, ct, nil)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("%w: %v", ErrIntegrity, err)
 	}
-	return string(plain), nil
+	return plain, nil
 }
 
-func List(database *sql.DB, category string) ([]Secret, error) {
-    q := `SELECT category, name, created_at, updated_at FROM secrets`
-	args := []any{}
-	if category != "" {
-		q += ` WHERE category=?`
-		args = append(args, category)
+// List returns the secrets at or below prefix, or all of them when
+// prefix is empty.
+func List(database *sql.DB, prefix string) ([]Secret, error) {
+	ps, err := Policies(database)
+	if err != nil {
+		return nil, err
 	}
-	q += ` ORDER BY category, name`
-	rows, err := database.Query(q, args...)
+	rows, err := database.Query(`SELECT ` + metaColumns + ` FROM secrets ORDER BY category, name`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var out []Secret
-    for rows.Next() {
-        var c, n, created, updated string
-        if err := rows.Scan(&c, &n, &created, &updated); err != nil {
+	for rows.Next() {
+		s, err := scanMeta(rows)
+		if err != nil {
 			return nil, err
 		}
-        out = append(out, Secret{Category: c, Name: n, CreatedAt: created, UpdatedAt: updated})
+		if !InPrefix(s.Path, prefix) {
+			continue
+		}
+		inherit(&s, ps)
+		out = append(out, s)
 	}
 	return out, rows.Err()
 }
 
+// Lookup returns a secret's metadata without decrypting it.
+func Lookup(database *sql.DB, category, name string) (Secret, error) {
+	row := database.QueryRow(`SELECT `+metaColumns+` FROM secrets WHERE category=? AND name=?`, category, name)
+	s, err := scanMeta(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Secret{}, fmt.Errorf("%w: %s", ErrNotFound, Path(category, name))
+	}
+	if err != nil {
+		return Secret{}, err
+	}
+	ps, err := Policies(database)
+	if err != nil {
+		return Secret{}, err
+	}
+	inherit(&s, ps)
+	return s, nil
+}
+
+const metaColumns = `category, name, type, created_at, updated_at, expires_at, rotate_every, description, owner, tags, fields`
+
+func scanMeta(row interface{ Scan(...any) error }) (Secret, error) {
+	var s Secret
+	var tags, fields string
+	if err := row.Scan(&s.Category, &s.Name, &s.Type, &s.CreatedAt, &s.UpdatedAt, &s.ExpiresAt, &s.RotateEvery,
+		&s.Description, &s.Owner, &tags, &fields); err != nil {
+		return Secret{}, err
+	}
+	s.Path = Path(s.Category, s.Name)
+	if err := decodeMeta(&s.Meta, tags, fields); err != nil {
+		return Secret{}, fmt.Errorf("%s: %w", Path(s.Category, s.Name), err)
+	}
+	if due, ok := s.RotationDue(); ok {
+		s.RotateDue = due.UTC().Format(time.RFC3339)
+	}
+	return s, nil
+}
+
+// Version returns an opaque tag for HTTP ETags. It changes whenever the
+// stored ciphertext does (every write uses a fresh nonce) and whenever the
+// metadata a read returns alongside the value does.
+func Version(database *sql.DB, category, name string) (string, error) {
+	cols := make([]string, 9)
+	ptrs := make([]any, len(cols))
+	for i := range cols {
+		ptrs[i] = &cols[i]
+	}
+	err := database.QueryRow(`SELECT ciphertext, nonce, type, expires_at, rotate_every, description, owner, tags, fields
+		FROM secrets WHERE category=? AND name=?`, category, name).Scan(ptrs...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, Path(category, name))
+	}
+	if err != nil {
+		return "", err
+	}
+	return core.BytesSHA256Hex([]byte(strings.Join(cols, "\x00")))[:32], nil
+}
+
 func Delete(database *sql.DB, category, name string) error {
-	_, err := database.Exec(`DELETE FROM secrets WHERE category=? AND name=?`, category, name)
-	return err
+	res, err := database.Exec(`DELETE FROM secrets WHERE category=? AND name=?`, category, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, Path(category, name))
+	}
+	return nil
 }
 
 func zero(b []byte) {
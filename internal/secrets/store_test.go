@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+
+	"vault-cli/internal/config"
+	"vault-cli/internal/db/dbtest"
+)
+
+var local = &config.Config{Mode: "local"}
+
+func TestImportKeepsTypeExpiryAndMeta(t *testing.T) {
+	database := dbtest.Open(t)
+	login := Secret{
+		Category: "prod", Name: "db", Type: TypeLogin,
+		Value:       `{"username":"app","password":"pw"}`,
+		ExpiresAt:   "2099-01-01T00:00:00Z",
+		RotateEvery: "90d",
+		Meta:        Meta{Description: "main db", Owner: "ops", Tags: []string{"pci"}},
+	}
+	plain := Secret{Category: "prod", Name: "token", Value: "t"}
+	if err := Import(database, local, []Secret{login, plain}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Lookup(database, "prod", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != TypeLogin || got.ExpiresAt != login.ExpiresAt || got.RotateEvery != "90d" {
+		t.Fatalf("Lookup = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Meta, login.Meta) {
+		t.Fatalf("meta = %+v, want %+v", got.Meta, login.Meta)
+	}
+	if v, err := Get(database, local, "prod", "db"); err != nil || v != `{"password":"pw","username":"app"}` {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+}
+
+func TestImportIsAllOrNothing(t *testing.T) {
+	database := dbtest.Open(t)
+	err := Import(database, local, []Secret{
+		{Category: "prod", Name: "ok", Value: "v"},
+		{Category: "prod", Name: "bad", Type: TypeLogin, Value: `{"username":"app"}`},
+	})
+	if err == nil {
+		t.Fatal("typed secret missing its password was imported")
+	}
+	if items, _ := List(database, ""); len(items) != 0 {
+		t.Fatalf("%d secrets stored by a failed import", len(items))
+	}
+}
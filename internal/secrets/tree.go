@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Transfer is one secret a copy or move writes. Replaces is set when a
+// secret already exists at To.
+type Transfer struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Replaces bool   `json:"replaces,omitempty"`
+}
+
+// Paths returns the paths of the secrets at or below prefix.
+func Paths(database *sql.DB, prefix string) ([]string, error) {
+	rows, err := database.Query(`SELECT category, name FROM secrets ORDER BY category, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var c, n string
+		if err := rows.Scan(&c, &n); err != nil {
+			return nil, err
+		}
+		if p := Path(c, n); InPrefix(p, prefix) {
+			out = append(out, p)
+		}
+	}
+	return out, rows.Err()
+}
+
+// PlanTransfer works out what copying or moving src to dst would write.
+// Without recursive src must be a secret. With it, src may also be a
+// prefix, and everything below it keeps its place relative to src under
+// dst: prod/payments/stripe/key moved from prod/payments to staging
+// lands at staging/stripe/key.
+func PlanTransfer(database *sql.DB, src, dst string, recursive bool) ([]Transfer, error) {
+	if err := ValidatePath(src); err != nil {
+		return nil, err
+	}
+	if err := ValidatePath(dst); err != nil {
+		return nil, err
+	}
+	if src == dst || (recursive && InPrefix(dst, src)) {
+		return nil, fmt.Errorf("%w: cannot copy or move %s into itself", ErrInvalidPath, src)
+	}
+
+	all, err := Paths(database, "")
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(all))
+	for _, p := range all {
+		exists[p] = true
+	}
+
+	var out []Transfer
+	from := map[string]bool{}
+	for _, p := range all {
+		if p == src || (recursive && InPrefix(p, src)) {
+			out = append(out, Transfer{From: p, To: dst + strings.TrimPrefix(p, src)})
+			from[p] = true
+		}
+	}
+	for i := range out {
+		t := &out[i]
+		if from[t.To] {
+			return nil, fmt.Errorf("%w: %s would land on %s, which is itself being transferred", ErrInvalidPath, t.From, t.To)
+		}
+		t.Replaces = exists[t.To]
+	}
+	if len(out) == 0 {
+		if !recursive && len(all) > 0 {
+			if under, _ := Paths(database, src); len(under) > 0 {
+				return nil, fmt.Errorf("%w: %s is a prefix of %d secrets; use --recursive", ErrInvalidPath, src, len(under))
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, src)
+	}
+	return out, nil
+}
+
+// ApplyTransfer copies or moves the secrets in ts in one transaction.
+// Values are not decrypted: the stored ciphertext, metadata, expiry and
+// rotation period go along unchanged, and so does updated_at, so a copy
+// inherits the original's rotation clock. A transfer that would replace
+// a secret fails with ErrExists unless overwrite is set.
+func ApplyTransfer(database *sql.DB, ts []Transfer, move, overwrite bool) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		if err := transfer(tx, t, move, overwrite); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s -> %s: %w", t.From, t.To, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func transfer(tx *sql.Tx, t Transfer, move, overwrite bool) error {
+	fc, fn, err := SplitPath(t.From)
+	if err != nil {
+		return err
+	}
+	tc, tn, err := SplitPath(t.To)
+	if err != nil {
+		return err
+	}
+	if t.Replaces {
+		if !overwrite {
+			return fmt.Errorf("%w: %s", ErrExists, t.To)
+		}
+		if _, err := tx.Exec(`DELETE FROM secrets WHERE category=? AND name=?`, tc, tn); err != nil {
+			return err
+		}
+	}
+	var res sql.Result
+	if move {
+		res, err = tx.Exec(`UPDATE secrets SET category=?, name=? WHERE category=? AND name=?`, tc, tn, fc, fn)
+	} else {
+		res, err = tx.Exec(`
+			INSERT INTO secrets(category, name, type, ciphertext, nonce, mode, hash, created_at, updated_at, expires_at, rotate_every, description, owner, tags, fields)
+			SELECT ?, ?, type, ciphertext, nonce, mode, hash, ?, updated_at, expires_at, rotate_every, description, owner, tags, fields
+			FROM secrets WHERE category=? AND name=?
+		`, tc, tn, now(), fc, fn)
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, t.From)
+	}
+	return nil
+}
+
+// DeletePaths deletes the secrets at paths in one transaction.
+func DeletePaths(database *sql.DB, paths []string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		c, n, err := SplitPath(p)
+		if err == nil {
+			_, err = tx.Exec(`DELETE FROM secrets WHERE category=? AND name=?`, c, n)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("delete %s: %w", p, err)
+		}
+	}
+	return tx.Commit()
+}
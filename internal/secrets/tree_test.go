@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"vault-cli/internal/db/dbtest"
+)
+
+// tree returns a database holding a secret at each path whose value is
+// the path itself and whose owner is ops.
+func tree(t *testing.T, paths ...string) *sql.DB {
+	t.Helper()
+	database := dbtest.Open(t)
+	for _, p := range paths {
+		c, n, err := SplitPath(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Add(database, local, Secret{Category: c, Name: n, Value: p}); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetMeta(database, c, n, Meta{Owner: "ops"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
+
+// values maps every stored path to its decrypted value.
+func values(t *testing.T, database *sql.DB) map[string]string {
+	t.Helper()
+	paths, err := Paths(database, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]string{}
+	for _, p := range paths {
+		c, n, _ := SplitPath(p)
+		v, err := Get(database, local, c, n)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		out[p] = v
+	}
+	return out
+}
+
+func TestPlanTransferRecursive(t *testing.T) {
+	database := tree(t, "prod/db", "prod/payments/paypal", "prod/payments/stripe/key", "staging/stripe/key")
+
+	got, err := PlanTransfer(database, "prod/payments", "staging", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Transfer{
+		{From: "prod/payments/paypal", To: "staging/paypal"},
+		{From: "prod/payments/stripe/key", To: "staging/stripe/key", Replaces: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("PlanTransfer = %+v, want %+v", got, want)
+	}
+
+	// A single secret needs no --recursive, and a prefix refuses without it.
+	if got, err := PlanTransfer(database, "prod/db", "prod/database", false); err != nil || len(got) != 1 || got[0].To != "prod/database" {
+		t.Fatalf("single secret: %+v, %v", got, err)
+	}
+	if _, err := PlanTransfer(database, "prod/payments", "staging", false); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("prefix without recursive: got %v, want ErrInvalidPath", err)
+	}
+	if _, err := PlanTransfer(database, "prod/missing", "staging", true); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing source: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestPlanTransferRefusesItself(t *testing.T) {
+	database := tree(t, "prod/db", "prod/payments/key")
+	for _, tc := range []struct {
+		src, dst  string
+		recursive bool
+	}{
+		{"prod/db", "prod/db", false},
+		{"prod", "prod", true},
+		{"prod", "prod/archive", true},
+		{"prod/payments", "prod/payments/old", true},
+	} {
+		if _, err := PlanTransfer(database, tc.src, tc.dst, tc.recursive); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%s -> %s (recursive=%v): got %v, want ErrInvalidPath", tc.src, tc.dst, tc.recursive, err)
+		}
+	}
+	// A sibling that merely shares a name prefix is not inside.
+	if _, err := PlanTransfer(database, "prod", "production", true); err != nil {
+		t.Errorf("prod -> production: %v", err)
+	}
+}
+
+func TestApplyTransferForceReplaces(t *testing.T) {
+	database := tree(t, "prod/payments/paypal", "prod/payments/stripe/key", "staging/stripe/key")
+	plan, err := PlanTransfer(database, "prod/payments", "staging", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := values(t, database)
+
+	// staging/stripe/key exists, so without --force nothing moves, not
+	// even the paypal secret planned ahead of it.
+	if err := ApplyTransfer(database, plan, true, false); !errors.Is(err, ErrExists) {
+		t.Fatalf("without overwrite: got %v, want ErrExists", err)
+	}
+	if after := values(t, database); !reflect.DeepEqual(after, before) {
+		t.Fatalf("failed move changed the vault:\n got %v\nwant %v", after, before)
+	}
+
+	if err := ApplyTransfer(database, plan, true, true); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"staging/paypal":     "prod/payments/paypal",
+		"staging/stripe/key": "prod/payments/stripe/key",
+	}
+	if got := values(t, database); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after move: %v, want %v", got, want)
+	}
+	if s, err := Lookup(database, "staging", "paypal"); err != nil || s.Owner != "ops" {
+		t.Fatalf("metadata did not move along: %+v, %v", s, err)
+	}
+}
+
+func TestApplyTransferCopyKeepsSource(t *testing.T) {
+	database := tree(t, "prod/api/token")
+	plan, err := PlanTransfer(database, "prod/api", "staging/api", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyTransfer(database, plan, false, false); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"prod/api/token": "prod/api/token", "staging/api/token": "prod/api/token"}
+	if got := values(t, database); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after copy: %v, want %v", got, want)
+	}
+}
+
+func TestApplyTransferRollsBack(t *testing.T) {
+	database := tree(t, "prod/a", "prod/b", "prod/c")
+	plan, err := PlanTransfer(database, "prod", "archive", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// prod/c goes away between planning and applying, so its transfer
+	// fails after the first two have run.
+	if err := Delete(database, "prod", "c"); err != nil {
+		t.Fatal(err)
+	}
+	before := values(t, database)
+	if err := ApplyTransfer(database, plan, true, false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("stale plan: got %v, want ErrNotFound", err)
+	}
+	if got := values(t, database); !reflect.DeepEqual(got, before) {
+		t.Fatalf("partial move was kept:\n got %v\nwant %v", got, before)
+	}
+}
+
+func TestDeletePaths(t *testing.T) {
+	database := tree(t, "prod/a", "prod/b", "prod/c")
+	if err := DeletePaths(database, []string{"prod/a", "prod//bad"}); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("bad path: got %v, want ErrInvalidPath", err)
+	}
+	if got := values(t, database); len(got) != 3 {
+		t.Fatalf("a failed delete removed secrets: %v", got)
+	}
+	if err := DeletePaths(database, []string{"prod/a", "prod/c"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := values(t, database); !reflect.DeepEqual(got, map[string]string{"prod/b": "prod/b"}) {
+		t.Fatalf("after delete: %v", got)
+	}
+}
@@ -0,0 +1,257 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Secret types. A typed secret's value is a Document encoded as JSON and
+// encrypted as a whole; an untyped one is an opaque string.
+const (
+	TypeKV    = "kv"
+	TypeLogin = "login"
+	TypeTLS   = "tls"
+	TypeSSH   = "ssh"
+	TypeToken = "token"
+)
+
+var Types = []string{TypeKV, TypeLogin, TypeTLS, TypeSSH, TypeToken}
+
+var ErrNoFields = errors.New("secret has no fields")
+
+// Document is the decrypted value of a typed secret.
+type Document map[string]string
+
+// schema lists a type's fields; sensitive ones are those that must not
+// be typed on a command line.
+type schema struct{ required, optional, sensitive []string }
+
+var schemas = map[string]schema{
+	TypeLogin: {[]string{"username", "password"}, []string{"url", "host", "port", "database", "notes"}, []string{"password"}},
+	TypeTLS:   {[]string{"certificate", "private_key"}, []string{"chain"}, []string{"private_key"}},
+	TypeSSH:   {[]string{"private_key"}, []string{"public_key", "passphrase", "comment"}, []string{"private_key", "passphrase"}},
+	TypeToken: {[]string{"token"}, []string{"issuer", "scopes", "expires_at", "notes"}, []string{"token"}},
+}
+
+// Sensitive reports whether field of a typ document holds secret material.
+// Every kv field might, so all of them count.
+func Sensitive(typ, field string) bool {
+	sc, ok := schemas[typ]
+	return !ok || slices.Contains(sc.sensitive, field)
+}
+
+// ParseDocument decodes a typed secret's value.
+func ParseDocument(value string) (Document, error) {
+	var d Document
+	if err := json.Unmarshal([]byte(value), &d); err != nil || d == nil {
+		return nil, ErrNoFields
+	}
+	return d, nil
+}
+
+// Encode returns d as the JSON stored for a typed secret, keys sorted.
+func (d Document) Encode() string {
+	b, _ := json.Marshal(d)
+	return string(b)
+}
+
+// GetField returns one field of a typed secret's value.
+func GetField(value, name string) (string, error) {
+	d, err := ParseDocument(value)
+	if err != nil {
+		return "", err
+	}
+	v, ok := d[name]
+	if !ok {
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		return "", fmt.Errorf("no field %q (have %s)", name, strings.Join(keys, ", "))
+	}
+	return v, nil
+}
+
+// Info is what Check learned about a document.
+type Info struct {
+	// NotAfter is when the certificate or token stops being valid, if
+	// the document says.
+	NotAfter    time.Time
+	Subject     string
+	Fingerprint string
+}
+
+// Check validates d against typ. Fields that can be derived, such as an
+// SSH public key, are filled in.
+func Check(typ string, d Document) (Info, error) {
+	if typ == TypeKV {
+		if len(d) == 0 {
+			return Info{}, errors.New("kv: at least one field is required")
+		}
+		for k := range d {
+			if strings.TrimSpace(k) == "" {
+				return Info{}, errors.New("kv: empty field name")
+			}
+		}
+		return Info{}, nil
+	}
+	sc, ok := schemas[typ]
+	if !ok {
+		return Info{}, fmt.Errorf("unknown secret type %q (want %s)", typ, strings.Join(Types, ", "))
+	}
+	for _, k := range sc.required {
+		if strings.TrimSpace(d[k]) == "" {
+			return Info{}, fmt.Errorf("%s: %s is required", typ, k)
+		}
+	}
+	for k := range d {
+		if !slices.Contains(sc.required, k) && !slices.Contains(sc.optional, k) {
+			return Info{}, fmt.Errorf("%s: unknown field %q (want %s)", typ, k, strings.Join(slices.Concat(sc.required, sc.optional), ", "))
+		}
+	}
+
+	switch typ {
+	case TypeLogin:
+		return Info{}, checkLogin(d)
+	case TypeTLS:
+		return checkTLS(d)
+	case TypeSSH:
+		return checkSSH(d)
+	case TypeToken:
+		if exp := d["expires_at"]; exp != "" {
+			t, err := time.Parse(time.RFC3339, exp)
+			if err != nil {
+				return Info{}, fmt.Errorf("token: expires_at %q is not an RFC 3339 time", exp)
+			}
+			return Info{NotAfter: t}, nil
+		}
+	}
+	return Info{}, nil
+}
+
+func checkLogin(d Document) error {
+	if p := d["port"]; p != "" {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("login: port %q is not a port number", p)
+		}
+	}
+	if u := d["url"]; u != "" {
+		if pu, err := url.Parse(u); err != nil || pu.Scheme == "" || pu.Host == "" {
+			return fmt.Errorf("login: url %q is not an absolute URL", u)
+		}
+	}
+	return nil
+}
+
+func checkTLS(d Document) (Info, error) {
+	certPEM := []byte(d["certificate"])
+	if d["chain"] != "" {
+		chain, err := parseCerts([]byte(d["chain"]))
+		if err != nil || len(chain) == 0 {
+			return Info{}, fmt.Errorf("tls: chain: no PEM certificates found")
+		}
+		certPEM = append(append(bytes.TrimSpace(certPEM), '\n'), d["chain"]...)
+	}
+	pair, err := tls.X509KeyPair(certPEM, []byte(d["private_key"]))
+	if err != nil {
+		return Info{}, err // already prefixed "tls: "
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return Info{}, fmt.Errorf("tls: certificate: %w", err)
+	}
+	subject := leaf.Subject.CommonName
+	if subject == "" && len(leaf.DNSNames) > 0 {
+		subject = leaf.DNSNames[0]
+	}
+	return Info{NotAfter: leaf.NotAfter, Subject: subject}, nil
+}
+
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+}
+
+func checkSSH(d Document) (Info, error) {
+	var key any
+	var err error
+	if pass := d["passphrase"]; pass != "" {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(d["private_key"]), []byte(pass))
+	} else {
+		key, err = ssh.ParseRawPrivateKey([]byte(d["private_key"]))
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("ssh: private_key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return Info{}, fmt.Errorf("ssh: private_key: %w", err)
+	}
+	pub := signer.PublicKey()
+	if d["public_key"] != "" {
+		given, _, _, _, err := ssh.ParseAuthorizedKey([]byte(d["public_key"]))
+		if err != nil {
+			return Info{}, fmt.Errorf("ssh: public_key: %w", err)
+		}
+		if !bytes.Equal(given.Marshal(), pub.Marshal()) {
+			return Info{}, errors.New("ssh: public_key does not match private_key")
+		}
+	} else {
+		d["public_key"] = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	}
+	return Info{Subject: d["comment"], Fingerprint: ssh.FingerprintSHA256(pub)}, nil
+}
+
+// prepare checks a typed secret's document before it is stored and
+// re-encodes it. Unless an expiry was given, a certificate's or token's
+// own expiry becomes the secret's.
+func prepare(s *Secret) error {
+	if s.Type == "" {
+		return nil
+	}
+	d, err := ParseDocument(s.Value)
+	if err != nil {
+		return fmt.Errorf("%s secret: value must be a JSON object of strings", s.Type)
+	}
+	info, err := Check(s.Type, d)
+	if err != nil {
+		return err
+	}
+	s.Value = d.Encode()
+	if s.ExpiresAt == "" && !info.NotAfter.IsZero() {
+		s.ExpiresAt = info.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return nil
+}
+
+// ValidateValue checks a typed secret's document without storing it.
+func ValidateValue(s Secret) error {
+	return prepare(&s)
+}
@@ -0,0 +1,234 @@
+package secrets
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"vault-cli/internal/db/dbtest"
+)
+
+// testCert issues a certificate for cn valid until notAfter, signed by
+// parent (self-signed when parent is nil). It returns the certificate
+// and key as PEM along with the parsed certificate and key for signing
+// further certificates.
+func testCert(t *testing.T, cn string, notAfter time.Time, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM), cert, key
+}
+
+func TestCheckTLS(t *testing.T) {
+	notAfter := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	caPEM, _, ca, caKey := testCert(t, "Test CA", notAfter.Add(time.Hour), true, nil, nil)
+	certPEM, keyPEM, _, _ := testCert(t, "api.example.com", notAfter, false, ca, caKey)
+	_, otherKey, _, _ := testCert(t, "other", notAfter, false, nil, nil)
+
+	info, err := Check(TypeTLS, Document{"certificate": certPEM, "private_key": keyPEM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "api.example.com" || !info.NotAfter.Equal(notAfter) {
+		t.Fatalf("Check = %+v, want api.example.com until %v", info, notAfter)
+	}
+	info, err = Check(TypeTLS, Document{"certificate": certPEM, "private_key": keyPEM, "chain": caPEM})
+	if err != nil {
+		t.Fatalf("with a chain: %v", err)
+	}
+	if info.Subject != "api.example.com" {
+		t.Fatalf("with a chain the leaf should stay first, got %q", info.Subject)
+	}
+
+	for name, d := range map[string]Document{
+		"mismatched key":  {"certificate": certPEM, "private_key": otherKey},
+		"garbage cert":    {"certificate": "not a certificate", "private_key": keyPEM},
+		"garbage key":     {"certificate": certPEM, "private_key": "not a key"},
+		"key as cert":     {"certificate": keyPEM, "private_key": keyPEM},
+		"chain not PEM":   {"certificate": certPEM, "private_key": keyPEM, "chain": "not a chain"},
+		"chain only keys": {"certificate": certPEM, "private_key": keyPEM, "chain": keyPEM},
+		"missing key":     {"certificate": certPEM},
+		"unknown field":   {"certificate": certPEM, "private_key": keyPEM, "ca": caPEM},
+	} {
+		if _, err := Check(TypeTLS, d); err == nil {
+			t.Errorf("%s: Check accepted it", name)
+		}
+	}
+}
+
+func TestCheckSSH(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "deploy@ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(block))
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+
+	d := Document{"private_key": keyPEM, "comment": "deploy@ci"}
+	info, err := Check(TypeSSH, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d["public_key"] != want {
+		t.Fatalf("derived public_key = %q, want %q", d["public_key"], want)
+	}
+	if info.Fingerprint != ssh.FingerprintSHA256(signer.PublicKey()) || info.Subject != "deploy@ci" {
+		t.Fatalf("Check = %+v", info)
+	}
+	if _, err := Check(TypeSSH, Document{"private_key": keyPEM, "public_key": want + " deploy@ci"}); err != nil {
+		t.Fatalf("matching public_key: %v", err)
+	}
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := ssh.NewSignerFromKey(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub := string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey()))
+	if _, err := Check(TypeSSH, Document{"private_key": keyPEM, "public_key": otherPub}); err == nil {
+		t.Error("a public_key of another key was accepted")
+	}
+	if _, err := Check(TypeSSH, Document{"private_key": "not a key"}); err == nil {
+		t.Error("a garbage private_key was accepted")
+	}
+
+	block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("open sesame"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := string(pem.EncodeToMemory(block))
+	if _, err := Check(TypeSSH, Document{"private_key": locked, "passphrase": "open sesame"}); err != nil {
+		t.Fatalf("with the right passphrase: %v", err)
+	}
+	for _, pass := range []string{"", "wrong"} {
+		if _, err := Check(TypeSSH, Document{"private_key": locked, "passphrase": pass}); err == nil {
+			t.Errorf("passphrase %q opened the key", pass)
+		}
+	}
+}
+
+func TestPrepareTakesExpiryFromCertificate(t *testing.T) {
+	notAfter := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	certPEM, keyPEM, _, _ := testCert(t, "api.example.com", notAfter, true, nil, nil)
+	value := Document{"certificate": certPEM, "private_key": keyPEM}.Encode()
+
+	database := dbtest.Open(t)
+	if err := Add(database, local, Secret{Category: "prod", Name: "tls", Type: TypeTLS, Value: value}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Lookup(database, "prod", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := notAfter.UTC().Format(time.RFC3339); s.ExpiresAt != want {
+		t.Fatalf("ExpiresAt = %q, want the certificate's NotAfter %q", s.ExpiresAt, want)
+	}
+
+	// An expiry given explicitly wins over the certificate's.
+	s = Secret{Type: TypeTLS, Value: value, ExpiresAt: "2026-01-01T00:00:00Z"}
+	if err := prepare(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ExpiresAt != "2026-01-01T00:00:00Z" {
+		t.Fatalf("explicit ExpiresAt replaced by %q", s.ExpiresAt)
+	}
+
+	s = Secret{Type: TypeToken, Value: `{"token":"t","expires_at":"2027-03-04T05:06:07+01:00"}`}
+	if err := prepare(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ExpiresAt != "2027-03-04T04:06:07Z" {
+		t.Fatalf("token ExpiresAt = %q", s.ExpiresAt)
+	}
+}
+
+func TestPrepareRejectsBadDocuments(t *testing.T) {
+	for _, s := range []Secret{
+		{Type: TypeLogin, Value: "plain string"},
+		{Type: TypeLogin, Value: `{"username":"u"}`},
+		{Type: TypeLogin, Value: `{"username":"u","password":"p","port":"70000"}`},
+		{Type: TypeLogin, Value: `{"username":"u","password":"p","url":"example.com"}`},
+		{Type: TypeToken, Value: `{"token":"t","expires_at":"tomorrow"}`},
+		{Type: TypeKV, Value: `{}`},
+		{Type: "bogus", Value: `{"a":"b"}`},
+	} {
+		if err := ValidateValue(s); err == nil {
+			t.Errorf("ValidateValue(%s %s) accepted it", s.Type, s.Value)
+		}
+	}
+	// ValidateValue leaves its argument alone; prepare re-encodes.
+	s := Secret{Type: TypeKV, Value: `{ "b": "2", "a": "1" }`}
+	if err := prepare(&s); err != nil || s.Value != `{"a":"1","b":"2"}` {
+		t.Fatalf("prepare = %q, %v", s.Value, err)
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	for _, tc := range []struct {
+		typ, field string
+		want       bool
+	}{
+		{TypeLogin, "password", true},
+		{TypeLogin, "username", false},
+		{TypeTLS, "private_key", true},
+		{TypeTLS, "certificate", false},
+		{TypeSSH, "passphrase", true},
+		{TypeSSH, "public_key", false},
+		{TypeToken, "token", true},
+		{TypeToken, "issuer", false},
+		{TypeKV, "anything", true},
+		{"bogus", "anything", true},
+	} {
+		if got := Sensitive(tc.typ, tc.field); got != tc.want {
+			t.Errorf("Sensitive(%q, %q) = %v, want %v", tc.typ, tc.field, got, tc.want)
+		}
+	}
+}
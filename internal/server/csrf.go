@@ -0,0 +1,128 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vault-cli/internal/session"
+)
+
+// CSRF protection for cookie-less but ambient (server-wide session) auth:
+// state-changing API requests must come from this origin or an allowed
+// one, and carry an X-CSRF-Token header holding the token for the current
+// session: an HMAC of the session under a key that lives only in this
+// process. Tokens are handed out at login and, while a session is active,
+// by GET /api/csrf and in the SameSite=Strict vault_csrf cookie the
+// dashboard reads; a new login invalidates them. Bearer-token requests are
+// exempt because browsers never attach them on their own.
+
+const (
+	csrfCookie = "vault_csrf"
+	csrfHeader = "X-CSRF-Token"
+)
+
+func (s *Server) withCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !s.sameOrAllowedOrigin(r) {
+			s.fail(w, r, http.StatusForbidden, "cross_origin_rejected", "cross-origin request rejected")
+			return
+		}
+		if r.URL.Path != "/api/login" && r.URL.Path != "/api/v1/login" && !s.validCSRF(r) {
+			s.fail(w, r, http.StatusForbidden, "csrf_failed", "missing or invalid CSRF token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(m string) bool {
+	return m == http.MethodGet || m == http.MethodHead || m == http.MethodOptions
+}
+
+// sameOrAllowedOrigin checks Origin (falling back to Referer). Requests
+// with neither come from non-browser clients and are let through.
+func (s *Server) sameOrAllowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		ref := r.Header.Get("Referer")
+		if ref == "" {
+			return true
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return s.originAllowed(origin)
+}
+
+// csrfToken returns the token for the current session. ok is false when
+// the server needs a login and there is no session to bind a token to.
+func (s *Server) csrfToken() (token string, ok bool) {
+	id := "no-login"
+	if sess, err := session.Load(); err == nil {
+		id = sess.User + "@" + sess.StartedAt.Format(time.RFC3339Nano)
+	} else if s.loginRequired() {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, s.csrfKey)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), true
+}
+
+func (s *Server) validCSRF(r *http.Request) bool {
+	want, ok := s.csrfToken()
+	h := r.Header.Get(csrfHeader)
+	return ok && h != "" && subtle.ConstantTimeCompare([]byte(want), []byte(h)) == 1
+}
+
+// setCSRFCookie puts the current session's token in the vault_csrf cookie
+// and returns it, or returns "" when there is no session.
+func (s *Server) setCSRFCookie(w http.ResponseWriter, r *http.Request) string {
+	token, ok := s.csrfToken()
+	if !ok {
+		return ""
+	}
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value == token {
+		return token
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+		Secure:   r.TLS != nil,
+	})
+	return token
+}
+
+func (s *Server) handleCSRF(w http.ResponseWriter, r *http.Request) {
+	token := s.setCSRFCookie(w, r)
+	if token == "" {
+		s.fail(w, r, http.StatusUnauthorized, "unauthorized", session.ErrNoSession.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"csrfToken": token})
+}
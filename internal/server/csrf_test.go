@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vault-cli/internal/keyring"
+)
+
+func TestHostAllowList(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.cfg.AllowedHosts = []string{"vault.internal"}
+	for _, tc := range []struct {
+		host string
+		want bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+		{"10.1.2.3", true},
+		{"localhost:8080", true},
+		{"vault.internal:8443", true},
+		{"VAULT.internal.", true},
+		{"attacker.example:8080", false},
+		{"vault.internal.attacker.example", false},
+	} {
+		if got := s.hostAllowed(tc.host); got != tc.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tc.host, got, tc.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Host = "attacker.example"
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMisdirectedRequest {
+		t.Fatalf("request for an unlisted host: status %d, want 421", rec.Code)
+	}
+}
+
+func TestCORSNeverHonoursWildcard(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.cfg.CORSOrigins = []string{"*", "https://app.example.com"}
+
+	rec := serve(s, http.MethodGet, "/api/health", "", "Origin", "https://evil.example")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("wildcard reflected %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("wildcard sent Allow-Credentials %q", got)
+	}
+	rec = serve(s, http.MethodGet, "/api/health", "", "Origin", "https://app.example.com")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("listed origin: Allow-Origin %q", got)
+	}
+	if err := s.Start("127.0.0.1:0", TLSOptions{}); err == nil || !strings.Contains(err.Error(), "VAULT_CORS_ORIGINS") {
+		t.Fatalf("Start with a wildcard origin: got %v", err)
+	}
+}
+
+func TestCSRFTokenIsBoundToSession(t *testing.T) {
+	s, _ := newTestServer(t)
+	keyring.Lock()
+
+	if rec := serve(s, http.MethodGet, "/api/csrf", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("/api/csrf without a session: status %d, want 401", rec.Code)
+	}
+
+	rec := serve(s, http.MethodPost, "/api/login", login(testPassword, ""))
+	var first struct {
+		CSRFToken string `json:"csrfToken"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil || first.CSRFToken == "" {
+		t.Fatalf("login returned no token: %s", rec.Body)
+	}
+	if rec := serve(s, http.MethodGet, "/api/csrf", ""); !strings.Contains(rec.Body.String(), first.CSRFToken) {
+		t.Fatalf("/api/csrf in the session returned %s", rec.Body)
+	}
+
+	del := func(token string) int {
+		return serve(s, http.MethodDelete, "/api/secrets?category=a&name=b", "", csrfHeader, token).Code
+	}
+	if code := del(""); code != http.StatusForbidden {
+		t.Fatalf("no token: status %d, want 403", code)
+	}
+	if code := del("made-up"); code != http.StatusForbidden {
+		t.Fatalf("made-up token: status %d, want 403", code)
+	}
+	if code := del(first.CSRFToken); code == http.StatusForbidden {
+		t.Fatalf("session token rejected with %d", code)
+	}
+
+	// A new login starts a new session; the old token dies with the old one.
+	if rec := serve(s, http.MethodPost, "/api/login", login(testPassword, "")); rec.Code != http.StatusOK {
+		t.Fatalf("second login: status %d", rec.Code)
+	}
+	if code := del(first.CSRFToken); code != http.StatusForbidden {
+		t.Fatalf("token from the previous session: status %d, want 403", code)
+	}
+}
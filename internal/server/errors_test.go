@@ -0,0 +1,43 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/aws"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/secrets"
+)
+
+func TestErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{secrets.ErrNotFound, http.StatusNotFound, "secret_not_found"},
+		{secrets.ErrExpired, http.StatusGone, "secret_expired"},
+		{secrets.ErrInvalidPath, http.StatusBadRequest, "invalid_path"},
+		{aws.ErrNotFound, http.StatusNotFound, "file_not_found"},
+		{auth.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+		{keyring.ErrLocked, http.StatusLocked, "vault_locked"},
+		{secrets.ErrIntegrity, http.StatusInternalServerError, "integrity_check_failed"},
+		{aws.ErrIntegrity, http.StatusInternalServerError, "integrity_check_failed"},
+		{aws.ErrBackendUnavailable, http.StatusServiceUnavailable, "backend_unavailable"},
+		{errors.New("boom"), http.StatusInternalServerError, "internal"},
+	} {
+		for _, err := range []error{tc.err, fmt.Errorf("prod/db: %w", tc.err)} {
+			status, code := errorStatus(err)
+			if status != tc.status || code != tc.code {
+				t.Errorf("errorStatus(%v) = %d %q, want %d %q", err, status, code, tc.status, tc.code)
+			}
+		}
+	}
+	tooLarge := fmt.Errorf("upload: %w", &http.MaxBytesError{Limit: 1})
+	if got := uploadStatus(tooLarge); got != http.StatusRequestEntityTooLarge {
+		t.Errorf("uploadStatus(%v) = %d, want 413", tooLarge, got)
+	}
+}
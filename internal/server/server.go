@@ -1,24 +1,33 @@
 package server
 
 import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
     "database/sql"
+    "encoding/hex"
     "embed"
     "encoding/json"
     "errors"
     "fmt"
     "io"
+    "net"
     "net/http"
     "os"
     "path/filepath"
+    "slices"
     "strconv"
     "strings"
+    "sync"
     "time"
 
+    "vault-cli/internal/apikey"
     "vault-cli/internal/auth"
     "vault-cli/internal/aws"
     "vault-cli/internal/config"
-    "vault-cli/internal/core"
     "vault-cli/internal/db"
+    "vault-cli/internal/keyring"
+    "vault-cli/internal/mfa"
     "vault-cli/internal/secrets"
     "vault-cli/internal/session"
 )
@@ -27,22 +36,49 @@ import (
 var staticFS embed.FS
 
 type Server struct {
-    cfg *config.Config
-    db  *sql.DB
+    cfg  *config.Config
+    db   *sql.DB
+    hsts bool
+
+    // csrfKey signs CSRF tokens to the web session they were issued for.
+    csrfKey []byte
+
+    // writeMu serialises conditional (If-Match) writes so the version check
+    // and the write happen atomically.
+    writeMu sync.Mutex
 }
 
 func New(cfg *config.Config, database *sql.DB) *Server {
-    return &Server{cfg: cfg, db: database}
+    key := make([]byte, 32)
+    _, _ = rand.Read(key)
+    return &Server{cfg: cfg, db: database, csrfKey: key}
 }
 
-func (s *Server) Start(addr string) error {
+func (s *Server) Start(addr string, opts TLSOptions) error {
+    if slices.Contains(s.cfg.CORSOrigins, "*") {
+        return errors.New("VAULT_CORS_ORIGINS: \"*\" is not allowed because the web session applies to every client; list the origins")
+    }
+    tlsCfg, err := opts.config(addr)
+    if err != nil {
+        return err
+    }
+    s.hsts = tlsCfg != nil
+
     srv := &http.Server{
         Addr:              addr,
         Handler:           s.routes(),
         ReadHeaderTimeout: 10 * time.Second,
+        TLSConfig:         tlsCfg,
+    }
+    if tlsCfg == nil {
+        if !isLoopback(addr) {
+            fmt.Println("⚠️  Serving plain HTTP on a non-loopback address; passwords and secrets are sent unencrypted.")
+        }
+        fmt.Printf("🌐 Vault UI listening on http://%s\n", addr)
+        return srv.ListenAndServe()
     }
-    fmt.Printf("🌐 Vault UI listening on http://%s\n", addr)
-    return srv.ListenAndServe()
+    fmt.Printf("🌐 Vault UI listening on https://%s\n", addr)
+    return srv.ListenAndServeTLS("", "")
 }
 
 func (s *Server) routes() http.Handler {
@@ -50,6 +86,7 @@ func (s *Server) routes() http.Handler {
 
     mux.HandleFunc("/api/health", s.handleHealth)
     mux.HandleFunc("/api/login", s.handleLogin)
+    mux.HandleFunc("/api/csrf", s.handleCSRF)
     mux.HandleFunc("/api/files", s.wrapAuth(s.handleListFiles))
     mux.HandleFunc("/api/audit", s.wrapAuth(s.handleListAudit))
     mux.HandleFunc("/api/upload", s.wrapAuth(s.handleUpload))
@@ -57,18 +94,82 @@ func (s *Server) routes() http.Handler {
     mux.HandleFunc("/api/secrets", s.wrapAuth(s.handleSecrets))
     mux.HandleFunc("/api/secrets/value", s.wrapAuth(s.handleSecretValue))
 
+    s.registerV1(mux)
+
     fileServer := http.FileServer(http.FS(staticFS))
     mux.Handle("/", s.serveIndex(fileServer))
 
-    return withCORS(mux)
+    return s.withHosts(s.withSecurityHeaders(s.withCORS(s.withCSRF(mux))))
+}
+
+// withHosts rejects requests whose Host is a name not listed in
+// VAULT_ALLOWED_HOSTS, so a page that rebinds its own domain to this
+// server's address can't talk to it. IP addresses and localhost are always
+// accepted: a rebinding page still sends its own name.
+func (s *Server) withHosts(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !s.hostAllowed(r.Host) {
+            s.fail(w, r, http.StatusMisdirectedRequest, "host_not_allowed", "host not allowed; add it to VAULT_ALLOWED_HOSTS")
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+func (s *Server) hostAllowed(host string) bool {
+    if h, _, err := net.SplitHostPort(host); err == nil {
+        host = h
+    }
+    host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+    if host == "localhost" || net.ParseIP(host) != nil {
+        return true
+    }
+    return slices.Contains(s.cfg.AllowedHosts, host)
+}
+
+func (s *Server) withSecurityHeaders(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        h := w.Header()
+        if s.hsts {
+            h.Set("Strict-Transport-Security", "max-age=31536000")
+        }
+        h.Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:; object-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'")
+        h.Set("X-Frame-Options", "DENY")
+        h.Set("X-Content-Type-Options", "nosniff")
+        h.Set("Referrer-Policy", "no-referrer")
+        if strings.HasPrefix(r.URL.Path, "/api/") {
+            h.Set("Cache-Control", "no-store")
+            h.Set("Pragma", "no-cache")
+        }
+        next.ServeHTTP(w, r)
+    })
 }
 
-func withCORS(next http.Handler) http.Handler {
+// withCORS only answers cross-origin requests from origins listed in
+// VAULT_CORS_ORIGINS; everything else gets no CORS headers, so browsers keep
+// other sites from reading API responses. A wildcard is never honoured: the
+// session is server-wide, so every origin would be sending credentials.
+func (s *Server) withCORS(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        w.Header().Set("Access-Control-Allow-Origin", "*")
-        w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-        w.Header().Set("Access-Control-Allow-Methods", "GET,POST,DELETE,OPTIONS")
-        if r.Method == http.MethodOptions {
+        origin := r.Header.Get("Origin")
+        allowed := origin != "" && s.originAllowed(origin)
+        if origin != "" {
+            w.Header().Add("Vary", "Origin")
+        }
+        if allowed {
+            w.Header().Set("Access-Control-Allow-Origin", origin)
+            w.Header().Set("Access-Control-Allow-Credentials", "true")
+        }
+
+        if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+            if !allowed {
+                s.fail(w, r, http.StatusForbidden, "origin_not_allowed", "origin not allowed")
+                return
+            }
+            w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+csrfHeader)
+            w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+            w.Header().Set("Access-Control-Expose-Headers", "ETag")
+            w.Header().Set("Access-Control-Max-Age", "600")
             w.WriteHeader(http.StatusNoContent)
             return
         }
@@ -76,6 +177,15 @@ func withCORS(next http.Handler) http.Handler {
     })
 }
 
+func (s *Server) originAllowed(origin string) bool {
+    for _, o := range s.cfg.CORSOrigins {
+        if o != "*" && strings.EqualFold(o, origin) {
+            return true
+        }
+    }
+    return false
+}
+
 func (s *Server) serveIndex(fs http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         if strings.HasPrefix(r.URL.Path, "/api/") {
@@ -83,6 +193,7 @@ func (s *Server) serveIndex(fs http.Handler) http.Handler {
             return
         }
         if r.URL.Path == "/" {
+            s.setCSRFCookie(w, r)
             f, err := staticFS.Open("static/index.html")
             if err != nil {
                 http.Error(w, "index missing", http.StatusInternalServerError)
@@ -97,11 +208,39 @@ func (s *Server) serveIndex(fs http.Handler) http.Handler {
     })
 }
 
+type ctxKey int
+
+const apiKeyCtx ctxKey = iota
+
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+    r.status = code
+    r.ResponseWriter.WriteHeader(code)
+}
+
 func (s *Server) wrapAuth(handler http.HandlerFunc) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
-        if s.cfg.RequirePassword {
+        if token, ok := bearerToken(r); ok {
+            key, err := apikey.Authenticate(s.db, token)
+            if err != nil {
+                _ = db.RecordAudit(s.db, "apikey:denied", r.URL.Path, clientIP(r), false, err.Error())
+                s.fail(w, r, http.StatusUnauthorized, "invalid_api_key", err.Error())
+                return
+            }
+            s.serveAudited(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtx, key)), "apikey", key.Identity(), handler)
+            return
+        }
+        if id := clientCertIdentity(r.TLS); id != "" {
+            s.serveAudited(w, r, "cert", id, handler)
+            return
+        }
+        if s.loginRequired() {
             if err := session.Require(); err != nil {
-                s.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+                s.fail(w, r, http.StatusUnauthorized, "unauthorized", err.Error())
                 return
             }
         }
@@ -109,67 +248,138 @@ func (s *Server) wrapAuth(handler http.HandlerFunc) http.HandlerFunc {
     }
 }
 
+// loginRequired reports whether session requests need a login. A vault
+// with a keyring always does, or anyone could use a keyring another client
+// unlocked.
+func (s *Server) loginRequired() bool {
+    return s.cfg.RequirePassword || keyring.Initialized()
+}
+
+// serveAudited runs handler and records the request in the audit log under
+// a non-session identity (API key or client certificate).
+func (s *Server) serveAudited(w http.ResponseWriter, r *http.Request, kind, identity string, handler http.HandlerFunc) {
+    rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+    handler(rec, r)
+    errMsg := ""
+    if rec.status >= 400 {
+        errMsg = http.StatusText(rec.status)
+    }
+    _ = db.RecordAudit(s.db, kind+":"+strings.ToLower(r.Method), r.URL.RequestURI(), identity, rec.status < 400, errMsg)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+    h := r.Header.Get("Authorization")
+    token, ok := strings.CutPrefix(h, "Bearer ")
+    if !ok || strings.TrimSpace(token) == "" {
+        return "", false
+    }
+    return strings.TrimSpace(token), true
+}
+
+func apiKeyFrom(r *http.Request) *apikey.Key {
+    k, _ := r.Context().Value(apiKeyCtx).(*apikey.Key)
+    return k
+}
+
+// authorize enforces API key scopes. Session (password) logins are not
+// scoped and always pass.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, action, resource string) bool {
+    k := apiKeyFrom(r)
+    if k == nil || k.Allows(action, resource) {
+        return true
+    }
+    s.fail(w, r, http.StatusForbidden, "insufficient_scope", fmt.Sprintf("api key %s lacks scope %s:%s", k.ID, action, resource))
+    return false
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
     s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
     if r.Method != http.MethodPost {
-        s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+        s.fail(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
         return
     }
 
-    if !s.cfg.RequirePassword {
+    if !s.loginRequired() {
         s.writeJSON(w, http.StatusOK, map[string]any{"ok": true, "requiresPassword": false})
         return
     }
 
     var req struct {
         Password string `json:"password"`
+        Code     string `json:"code"`
     }
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        s.writeError(w, http.StatusBadRequest, "invalid json")
+        s.fail(w, r, http.StatusBadRequest, "invalid_json", "invalid json")
         return
     }
     if strings.TrimSpace(req.Password) == "" {
-        s.writeError(w, http.StatusBadRequest, "password required")
+        s.fail(w, r, http.StatusBadRequest, "password_required", "password required")
         return
     }
 
-    ok, err := auth.CheckPassword(s.cfg.PasswordFile, req.Password)
-    if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+    err := mfa.Login(s.db, s.cfg, clientIP(r), req.Password, req.Code)
+    var lockout *auth.LockoutError
+    switch {
+    case errors.As(err, &lockout):
+        w.Header().Set("Retry-After", strconv.Itoa(int(lockout.RetryAfter().Seconds())))
+        s.fail(w, r, http.StatusTooManyRequests, "locked_out", err.Error())
         return
-    }
-    if !ok {
-        s.writeError(w, http.StatusUnauthorized, "invalid credentials")
+    case errors.Is(err, auth.ErrInvalidPassword):
+        s.fail(w, r, http.StatusUnauthorized, "invalid_credentials", "invalid credentials")
+        return
+    case errors.Is(err, mfa.ErrCodeRequired), errors.Is(err, mfa.ErrInvalidCode):
+        if isV1(r) {
+            s.fail(w, r, http.StatusUnauthorized, "mfa_required", err.Error())
+            return
+        }
+        s.writeJSON(w, http.StatusUnauthorized, map[string]any{"error": err.Error(), "mfaRequired": true})
+        return
+    case err != nil:
+        s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
         return
     }
 
     if err := session.Save("web", 15*time.Minute); err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+        s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
         return
     }
+    token := s.setCSRFCookie(w, r)
     if sess, err := session.Load(); err == nil {
         s.writeJSON(w, http.StatusOK, map[string]any{
             "ok":        true,
             "expiresAt": sess.ExpiresAt,
+            "csrfToken": token,
         })
         return
     }
-    s.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+    s.writeJSON(w, http.StatusOK, map[string]any{"ok": true, "csrfToken": token})
 }
 
 func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
     items, err := db.ListFiles(s.db)
     if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+        s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
         return
     }
+    if k := apiKeyFrom(r); k != nil {
+        visible := items[:0]
+        for _, f := range items {
+            if k.Allows("download", f.Filename) {
+                visible = append(visible, f)
+            }
+        }
+        items = visible
+    }
     s.writeJSON(w, http.StatusOK, items)
 }
 
 func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+    if !s.authorize(w, r, "audit", "log") {
+        return
+    }
     limit := 100
     if v := r.URL.Query().Get("limit"); v != "" {
         if n, err := strconv.Atoi(v); err == nil && n > 0 {
@@ -178,7 +388,7 @@ func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
     }
     items, err := db.ListAudit(s.db, limit)
     if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+        s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
         return
     }
     s.writeJSON(w, http.StatusOK, items)
@@ -190,172 +400,207 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    if err := r.ParseMultipartForm(128 << 20); err != nil {
-        s.writeError(w, http.StatusBadRequest, fmt.Sprintf("parse form: %v", err))
-        return
-    }
-    file, header, err := r.FormFile("file")
-    if err != nil {
-        s.writeError(w, http.StatusBadRequest, "file field required")
-        return
-    }
-    defer file.Close()
-
-    filename := sanitizeFilename(header.Filename)
-    if filename == "" {
-        s.writeError(w, http.StatusBadRequest, "invalid filename")
-        return
-    }
-
-    tempDir, err := os.MkdirTemp("", "vault-upload-")
+    // The body is read part by part and streamed straight into storage;
+    // nothing is buffered in memory or spooled to a temp directory.
+    r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadBytes)
+    mr, err := r.MultipartReader()
     if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
-        return
-    }
-    defer os.RemoveAll(tempDir)
-
-    tempPath := filepath.Join(tempDir, filename)
-    tempFile, err := os.Create(tempPath)
-    if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
-        return
-    }
-    if _, err := io.Copy(tempFile, file); err != nil {
-        tempFile.Close()
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+        s.writeError(w, http.StatusBadRequest, fmt.Sprintf("parse form: %v", err))
         return
     }
-    tempFile.Close()
+    for {
+        part, err := mr.NextPart()
+        if err == io.EOF {
+            s.writeError(w, http.StatusBadRequest, "file field required")
+            return
+        }
+        if err != nil {
+            s.fail(w, r, uploadStatus(err), "upload_failed", err.Error())
+            return
+        }
+        if part.FormName() != "file" {
+            part.Close()
+            continue
+        }
 
-    if s.cfg.Mode == "local" {
-        if err := s.handleLocalUpload(tempPath, filename); err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+        filename := sanitizeFilename(part.FileName())
+        if !validFilename(filename) {
+            s.writeError(w, http.StatusBadRequest, "invalid filename")
             return
         }
-    } else {
-        if err := aws.EncryptAndUpload(tempPath, s.cfg, s.db); err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+        if !s.authorize(w, r, "upload", filename) {
             return
         }
+        if _, err := s.storeFile(r.Context(), filename, part); err != nil {
+            s.fail(w, r, uploadStatus(err), "upload_failed", err.Error())
+            return
+        }
+        s.writeJSON(w, http.StatusCreated, map[string]string{"message": "upload complete"})
+        return
     }
+}
+
+func uploadStatus(err error) int {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        return http.StatusRequestEntityTooLarge
+    }
+    status, _ := errorStatus(err)
+    return status
+}
 
-    s.writeJSON(w, http.StatusCreated, map[string]string{"message": "upload complete"})
+func validFilename(name string) bool {
+    return name != "" && name != "." && name != ".."
 }
 
-func (s *Server) handleLocalUpload(srcPath, name string) error {
-    destDir := s.cfg.LocalPath
-    if destDir == "" {
-        destDir = os.Getenv("VAULT_REMOTE_PATH")
+func (s *Server) localDir() (string, error) {
+    dir := s.cfg.LocalPath
+    if dir == "" {
+        dir = os.Getenv("VAULT_REMOTE_PATH")
     }
-    if destDir == "" {
-        return errors.New("local path not configured")
+    if dir == "" {
+        return "", errors.New("local path not configured")
     }
-    if err := os.MkdirAll(destDir, 0755); err != nil {
-        return err
-    }
-    destPath := filepath.Join(destDir, filepath.Base(name))
+    return dir, nil
+}
 
-    if err := copyFile(srcPath, destPath); err != nil {
-        return err
+// storeFile streams src into the local store or, in KMS mode, through the
+// chunked encryptor to S3, and returns the resulting file record.
+func (s *Server) storeFile(ctx context.Context, name string, src io.Reader) (db.FileRecord, error) {
+    if s.cfg.Mode != "local" {
+        res, err := aws.Upload(ctx, name, src, s.cfg, s.db, nil)
+        if err != nil {
+            return db.FileRecord{}, err
+        }
+        return db.FileRecord{Filename: name, Hash: res.Hash, Size: res.Size, Location: "s3", Mode: s.cfg.Mode}, nil
     }
 
-    info, err := os.Stat(srcPath)
+    destDir, err := s.localDir()
     if err != nil {
-        return err
+        return db.FileRecord{}, err
     }
-    hash, err := core.FileSHA256(srcPath)
-    if err != nil {
-        return err
-    }
-    if s.db != nil {
-        _ = db.RecordFile(s.db, name, hash, info.Size(), destDir, "local")
-        _ = db.RecordAudit(s.db, "upload", name, destDir, true, "")
+    if err := os.MkdirAll(destDir, 0755); err != nil {
+        return db.FileRecord{}, err
     }
-    return nil
-}
 
-func copyFile(src, dest string) error {
-    in, err := os.Open(src)
+    // Write next to the destination and rename, so a failed or oversized
+    // upload never replaces the existing file.
+    tmp, err := os.CreateTemp(destDir, "."+name+".part-")
     if err != nil {
-        return err
+        return db.FileRecord{}, err
     }
-    defer in.Close()
+    defer os.Remove(tmp.Name())
 
-    out, err := os.Create(dest)
+    h := sha256.New()
+    size, err := io.Copy(tmp, io.TeeReader(src, h))
+    if err == nil {
+        err = tmp.Sync()
+    }
+    if cerr := tmp.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(tmp.Name(), filepath.Join(destDir, name))
+    }
     if err != nil {
-        return err
+        if s.db != nil {
+            _ = db.RecordAudit(s.db, "upload", name, destDir, false, err.Error())
+        }
+        return db.FileRecord{}, err
     }
-    defer out.Close()
 
-    if _, err := io.Copy(out, in); err != nil {
-        return err
+    rec := db.FileRecord{Filename: name, Hash: hex.EncodeToString(h.Sum(nil)), Size: size, Location: destDir, Mode: "local"}
+    if s.db != nil {
+        _ = db.RecordFile(s.db, name, rec.Hash, size, destDir, "local")
+        _ = db.RecordAudit(s.db, "upload", name, destDir, true, "")
     }
-    return out.Sync()
+    return rec, nil
 }
 
 func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
     name := sanitizeFilename(r.URL.Query().Get("name"))
-    if name == "" {
+    if !validFilename(name) {
         s.writeError(w, http.StatusBadRequest, "name required")
         return
     }
+    if !s.authorize(w, r, "download", name) {
+        return
+    }
+    s.serveFile(w, r, name)
+}
 
-    var data []byte
-    var err error
-
+// serveFile streams a stored file to the client, decrypting on the fly in
+// KMS mode. http.ServeContent handles Range, If-Range and HEAD; only the
+// chunks a range touches are fetched and decrypted.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+    var (
+        content io.ReadSeeker
+        modTime time.Time
+    )
     if s.cfg.Mode == "local" {
-        destDir := s.cfg.LocalPath
-        if destDir == "" {
-            destDir = os.Getenv("VAULT_REMOTE_PATH")
+        destDir, err := s.localDir()
+        if err != nil {
+            s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
+            return
         }
-        if destDir == "" {
-            s.writeError(w, http.StatusInternalServerError, "local path not configured")
+        f, err := os.Open(filepath.Join(destDir, name))
+        if errors.Is(err, os.ErrNotExist) {
+            s.fail(w, r, http.StatusNotFound, "file_not_found", fmt.Sprintf("file %s not found", name))
             return
         }
-        path := filepath.Join(destDir, name)
-        data, err = os.ReadFile(path)
         if err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+            s.fail(w, r, http.StatusInternalServerError, "internal", err.Error())
             return
         }
+        defer f.Close()
+        if info, err := f.Stat(); err == nil {
+            modTime = info.ModTime()
+        }
+        content = f
         if s.db != nil {
             _ = db.RecordAudit(s.db, "download", name, destDir, true, "")
         }
     } else {
-        if err := aws.DownloadAndDecrypt(name, s.cfg, s.db); err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
-            return
-        }
-        localPath := "decrypted_" + filepath.Base(name)
-        data, err = os.ReadFile(localPath)
-        _ = os.Remove(localPath)
+        obj, err := aws.Open(r.Context(), name, s.cfg, s.db)
         if err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+            s.failErr(w, r, err)
             return
         }
+        defer obj.Close()
+        content, modTime = obj, obj.ModTime
     }
 
     w.Header().Set("Content-Type", "application/octet-stream")
     w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
-    w.WriteHeader(http.StatusOK)
-    _, _ = w.Write(data)
+    http.ServeContent(w, r, name, modTime, content)
 }
 
 func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
     switch r.Method {
     case http.MethodGet:
-        cat := r.URL.Query().Get("category")
-        items, err := secrets.List(s.db, cat)
+        items, err := secrets.List(s.db, listPrefix(r))
         if err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+            s.failErr(w, r, err)
             return
         }
+        k := apiKeyFrom(r)
+        tags := r.URL.Query()["tag"]
+        visible := items[:0]
+        for _, it := range items {
+            if it.HasTags(tags) && (k == nil || k.Allows("read", secrets.Path(it.Category, it.Name))) {
+                visible = append(visible, it)
+            }
+        }
+        items = visible
         s.writeJSON(w, http.StatusOK, items)
     case http.MethodPost:
         var req struct {
-            Category string `json:"category"`
-            Name     string `json:"name"`
-            Value    string `json:"value"`
+            Category    string `json:"category"`
+            Name        string `json:"name"`
+            Type        string `json:"type"`
+            Value       string `json:"value"`
+            ExpiresAt   string `json:"expires_at"`
+            RotateEvery string `json:"rotate_every"`
         }
         if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
             s.writeError(w, http.StatusBadRequest, "invalid json")
@@ -365,13 +610,24 @@ func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
             s.writeError(w, http.StatusBadRequest, "category and name required")
             return
         }
-        sec := secrets.Secret{Category: req.Category, Name: req.Name, Value: req.Value}
+        if !s.authorize(w, r, "write", secrets.Path(req.Category, req.Name)) {
+            return
+        }
+        sec := secrets.Secret{Category: req.Category, Name: req.Name, Type: req.Type, Value: req.Value, ExpiresAt: req.ExpiresAt, RotateEvery: req.RotateEvery}
+        if err := secrets.ValidateExpiry(sec); err != nil {
+            s.writeError(w, http.StatusBadRequest, err.Error())
+            return
+        }
+        if err := secrets.ValidateValue(sec); err != nil {
+            s.writeError(w, http.StatusBadRequest, err.Error())
+            return
+        }
         if err := secrets.Add(s.db, s.cfg, sec); err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+            s.failErr(w, r, err)
             return
         }
         if s.db != nil {
-            _ = db.RecordAudit(s.db, "secret:add", fmt.Sprintf("%s/%s", sec.Category, sec.Name), "secrets", true, "")
+            _ = db.RecordAudit(s.db, "secret:add", secrets.Path(sec.Category, sec.Name), "secrets", true, "")
         }
         s.writeJSON(w, http.StatusCreated, map[string]string{"message": "secret stored"})
     case http.MethodDelete:
@@ -381,12 +637,15 @@ func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
             s.writeError(w, http.StatusBadRequest, "category and name required")
             return
         }
+        if !s.authorize(w, r, "delete", secrets.Path(cat, name)) {
+            return
+        }
         if err := secrets.Delete(s.db, cat, name); err != nil {
-            s.writeError(w, http.StatusInternalServerError, err.Error())
+            s.failErr(w, r, err)
             return
         }
         if s.db != nil {
-            _ = db.RecordAudit(s.db, "secret:delete", fmt.Sprintf("%s/%s", cat, name), "secrets", true, "")
+            _ = db.RecordAudit(s.db, "secret:delete", secrets.Path(cat, name), "secrets", true, "")
         }
         s.writeJSON(w, http.StatusOK, map[string]string{"message": "secret deleted"})
     default:
@@ -405,14 +664,57 @@ func (s *Server) handleSecretValue(w http.ResponseWriter, r *http.Request) {
         s.writeError(w, http.StatusBadRequest, "category and name required")
         return
     }
+    if !s.authorize(w, r, "read", secrets.Path(cat, name)) {
+        return
+    }
     val, err := secrets.Get(s.db, s.cfg, cat, name)
     if err != nil {
-        s.writeError(w, http.StatusInternalServerError, err.Error())
+        s.failErr(w, r, err)
         return
     }
     s.writeJSON(w, http.StatusOK, map[string]string{"value": val})
 }
 
+// errorStatus maps the sentinel errors of the secrets, aws, auth and
+// keyring packages onto an HTTP status and v1 error code. Anything else
+// is a 500.
+func errorStatus(err error) (int, string) {
+    switch {
+    case errors.Is(err, secrets.ErrNotFound):
+        return http.StatusNotFound, "secret_not_found"
+    case errors.Is(err, secrets.ErrExpired):
+        return http.StatusGone, "secret_expired"
+    case errors.Is(err, secrets.ErrInvalidPath):
+        return http.StatusBadRequest, "invalid_path"
+    case errors.Is(err, aws.ErrNotFound):
+        return http.StatusNotFound, "file_not_found"
+    case errors.Is(err, auth.ErrUnauthorized):
+        return http.StatusUnauthorized, "unauthorized"
+    case errors.Is(err, keyring.ErrLocked):
+        return http.StatusLocked, "vault_locked"
+    case errors.Is(err, secrets.ErrIntegrity), errors.Is(err, aws.ErrIntegrity):
+        return http.StatusInternalServerError, "integrity_check_failed"
+    case errors.Is(err, aws.ErrBackendUnavailable):
+        return http.StatusServiceUnavailable, "backend_unavailable"
+    }
+    return http.StatusInternalServerError, "internal"
+}
+
+// listPrefix is the subtree a secret listing asks for; category is the
+// name the parameter had before secrets were addressed by path.
+func listPrefix(r *http.Request) string {
+    if p := r.URL.Query().Get("prefix"); p != "" {
+        return p
+    }
+    return r.URL.Query().Get("category")
+}
+
+// failErr reports err under the status errorStatus picks for it.
+func (s *Server) failErr(w http.ResponseWriter, r *http.Request, err error) {
+    status, code := errorStatus(err)
+    s.fail(w, r, status, code, err.Error())
+}
+
 func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
     s.writeJSON(w, status, map[string]string{"error": msg})
 }
@@ -423,6 +725,14 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, payload any) {
     _ = json.NewEncoder(w).Encode(payload)
 }
 
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
 func sanitizeFilename(name string) string {
     name = filepath.Base(strings.TrimSpace(name))
     name = strings.ReplaceAll(name, "\\", "")
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vault-cli/internal/auth"
+	"vault-cli/internal/config"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/keyring"
+	"vault-cli/internal/mfa"
+)
+
+const testPassword = "correct horse"
+
+// newTestServer returns a server over a fresh database whose keyring is
+// created and then locked, as it is when `vault server` starts. HOME is
+// moved so sessions stay inside the test.
+func newTestServer(t *testing.T) (*Server, *sql.DB) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	database, empty := dbtest.Open(t), dbtest.Open(t)
+	t.Cleanup(func() {
+		keyring.Lock()
+		_ = keyring.Load(empty)
+	})
+
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Mode: "local", PasswordFile: filepath.Join(t.TempDir(), "pw")}
+	if err := os.WriteFile(cfg.PasswordFile, hash, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Create(database, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	return New(cfg, database), database
+}
+
+func serve(s *Server, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "127.0.0.1:8080"
+	req.RemoteAddr = "127.0.0.1:5000"
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func login(password, code string) string {
+	b, _ := json.Marshal(map[string]string{"password": password, "code": code})
+	return string(b)
+}
+
+func TestKeyringRequiresSession(t *testing.T) {
+	s, _ := newTestServer(t)
+	// Even an unlocked keyring must not be usable without a login.
+	if rec := serve(s, http.MethodGet, "/api/secrets", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without a session: status %d, want 401", rec.Code)
+	}
+	keyring.Lock()
+
+	if rec := serve(s, http.MethodPost, "/api/login", login("wrong", "")); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status %d, want 401", rec.Code)
+	}
+	if rec := serve(s, http.MethodPost, "/api/login", login(testPassword, "")); rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body)
+	}
+	if keyring.Locked() {
+		t.Fatal("keyring still locked after login")
+	}
+	if rec := serve(s, http.MethodGet, "/api/secrets", ""); rec.Code != http.StatusOK {
+		t.Fatalf("with a session: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestLoginChecksCodeBeforeUnlocking(t *testing.T) {
+	s, database := newTestServer(t)
+	e, _, err := mfa.NewEnrollment(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mfa.Save(database, s.cfg, auth.MasterUser, e); err != nil {
+		t.Fatal(err)
+	}
+	keyring.Lock()
+
+	for _, code := range []string{"", "000000"} {
+		rec := serve(s, http.MethodPost, "/api/login", login(testPassword, code))
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "mfaRequired") {
+			t.Fatalf("code %q: status %d: %s", code, rec.Code, rec.Body)
+		}
+		if !keyring.Locked() {
+			t.Fatalf("code %q left the keyring unlocked", code)
+		}
+	}
+	var n int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM audit WHERE action='login'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("%d successful logins audited before the code passed", n)
+	}
+
+	if rec := serve(s, http.MethodPost, "/api/login", login(testPassword, e.TOTP().Code())); rec.Code != http.StatusOK {
+		t.Fatalf("right code: status %d: %s", rec.Code, rec.Body)
+	}
+	if keyring.Locked() {
+		t.Fatal("keyring still locked after a full login")
+	}
+}
@@ -22,18 +22,34 @@ const els = {
     auditList: document.getElementById('audit-list'),
     refreshAudit: document.getElementById('refresh-audit'),
     loginPassword: document.getElementById('password'),
+    loginCode: document.getElementById('mfa-code'),
     secretTemplate: document.getElementById('secret-item-template'),
 };
 
+function csrfToken() {
+    const match = document.cookie.match(/(?:^|;\s*)vault_csrf=([^;]+)/);
+    return match ? decodeURIComponent(match[1]) : '';
+}
+
 async function api(path, options = {}) {
     try {
-        const res = await fetch(path, Object.assign({
-            headers: {
-                'Accept': 'application/json',
-            },
-        }, options));
+        const headers = Object.assign({
+            'Accept': 'application/json',
+        }, options.headers || {});
+        const method = (options.method || 'GET').toUpperCase();
+        if (method !== 'GET' && method !== 'HEAD') {
+            headers['X-CSRF-Token'] = csrfToken();
+        }
+        const res = await fetch(path, Object.assign({}, options, {
+            headers,
+            credentials: 'same-origin',
+        }));
 
         if (res.status === 401) {
+            const body = await res.json().catch(() => ({}));
+            if (body.mfaRequired) {
+                return body;
+            }
             handleAuthRequired();
             return null;
         }
@@ -145,7 +161,20 @@ function renderSecrets(items) {
         groups[category].forEach((item) => {
             const tpl = els.secretTemplate.content.cloneNode(true);
             tpl.querySelector('.name').textContent = item.Name || item.name;
-            tpl.querySelector('.timestamp').textContent = item.UpdatedAt ? `Updated ${formatDate(item.UpdatedAt)}` : '';
+            tpl.querySelector('.timestamp').textContent = (item.UpdatedAt || item.updated_at) ? `Updated ${formatDate(item.UpdatedAt || item.updated_at)}` : '';
+            tpl.querySelector('.description').textContent = [item.description, item.owner && `Owner: ${item.owner}`].filter(Boolean).join(' · ');
+            const tags = tpl.querySelector('.tags');
+            (item.tags || []).forEach((t) => {
+                const tag = document.createElement('span');
+                tag.className = 'tag';
+                tag.textContent = t;
+                tags.appendChild(tag);
+            });
+            const due = secretDue(item);
+            if (due) {
+                tpl.querySelector('.secret-item').classList.add(due.level);
+                tpl.querySelector('.due').textContent = due.text;
+            }
             const viewBtn = tpl.querySelector('.view');
             const delBtn = tpl.querySelector('.delete');
             viewBtn.dataset.category = item.Category || item.category;
@@ -159,6 +188,21 @@ function renderSecrets(items) {
     });
 }
 
+const DUE_SOON_MS = 14 * 24 * 60 * 60 * 1000;
+
+// secretDue flags expired secrets, overdue rotations and either coming up
+// in the next two weeks, most urgent first.
+function secretDue(item) {
+    const now = Date.now();
+    const expires = item.expires_at ? Date.parse(item.expires_at) : NaN;
+    const rotate = item.rotate_due ? Date.parse(item.rotate_due) : NaN;
+    if (expires <= now) return { level: 'expired', text: `Expired ${formatDate(item.expires_at)}` };
+    if (rotate <= now) return { level: 'overdue', text: `Rotation overdue since ${formatDate(item.rotate_due)}` };
+    if (expires - now <= DUE_SOON_MS) return { level: 'due-soon', text: `Expires ${formatDate(item.expires_at)}` };
+    if (rotate - now <= DUE_SOON_MS) return { level: 'due-soon', text: `Rotation due ${formatDate(item.rotate_due)}` };
+    return null;
+}
+
 function renderAudit(items) {
     els.auditList.innerHTML = '';
     if (!items.length) {
@@ -207,12 +251,20 @@ async function onLogin(event) {
     event.preventDefault();
     const password = els.loginPassword.value;
     if (!password) return;
+    const code = els.loginCode.value.trim();
     els.loginFeedback.textContent = 'Verifying...';
     const res = await api('/api/login', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ password }),
+        body: JSON.stringify({ password, code }),
     });
+    if (res && res.mfaRequired) {
+        els.loginCode.hidden = false;
+        els.loginCode.value = '';
+        els.loginCode.focus();
+        els.loginFeedback.textContent = code ? res.error : 'Enter the code from your authenticator app.';
+        return;
+    }
     if (!res || res.error) {
         els.loginFeedback.textContent = res && res.error ? res.error : 'Login failed';
         toast(els.status, 'Login failed', true);
@@ -220,6 +272,8 @@ async function onLogin(event) {
     }
     els.loginFeedback.textContent = '';
     els.loginPassword.value = '';
+    els.loginCode.value = '';
+    els.loginCode.hidden = true;
     toast(els.status, 'Unlocked');
     await loadAll();
 }
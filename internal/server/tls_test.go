@@ -0,0 +1,138 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPlainHTTPNeedsLoopback(t *testing.T) {
+	for _, tc := range []struct {
+		addr     string
+		insecure bool
+		ok       bool
+	}{
+		{"127.0.0.1:8080", false, true},
+		{"[::1]:8080", false, true},
+		{"localhost:8080", false, true},
+		{"0.0.0.0:8080", false, false},
+		{":8080", false, false},
+		{"10.1.2.3:8080", false, false},
+		{"vault.internal:8080", false, false},
+		{"0.0.0.0:8080", true, true},
+	} {
+		cfg, err := TLSOptions{AllowInsecure: tc.insecure}.config(tc.addr)
+		if tc.ok && (err != nil || cfg != nil) {
+			t.Errorf("%s insecure=%v: got %v, %v; want plain HTTP", tc.addr, tc.insecure, cfg, err)
+		}
+		if !tc.ok && (err == nil || !strings.Contains(err.Error(), "refusing to serve plain HTTP")) {
+			t.Errorf("%s insecure=%v: got %v, want a refusal", tc.addr, tc.insecure, err)
+		}
+	}
+
+	s, _ := newTestServer(t)
+	if err := s.Start("0.0.0.0:0", TLSOptions{}); err == nil || !strings.Contains(err.Error(), "--insecure") {
+		t.Fatalf("Start on 0.0.0.0 without TLS: got %v", err)
+	}
+}
+
+func TestTLSOptionConflicts(t *testing.T) {
+	for _, o := range []TLSOptions{
+		{CertFile: "server.crt"},
+		{KeyFile: "server.key"},
+		{SelfSigned: true, CertFile: "server.crt", KeyFile: "server.key"},
+		{ClientCAFile: "ca.crt"},
+	} {
+		if _, err := o.config("127.0.0.1:8443"); err == nil {
+			t.Errorf("config(%+v) accepted it", o)
+		}
+	}
+}
+
+func TestSelfSignedCoversBindHost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tc, err := TLSOptions{SelfSigned: true}.config("10.1.2.3:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(tc.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "10.1.2.3"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("self-signed certificate: %v", err)
+		}
+	}
+
+	// The same certificate is reused, and can serve as a client CA.
+	certFile, _, err := ensureSelfSigned("10.1.2.3:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err = TLSOptions{SelfSigned: true, ClientCAFile: certFile, RequireClientCert: true}.config("10.1.2.3:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := x509.ParseCertificate(tc.Certificates[0].Certificate[0]); !again.Equal(leaf) {
+		t.Error("a valid self-signed certificate was regenerated")
+	}
+	if tc.ClientAuth != tls.RequireAndVerifyClientCert || tc.ClientCAs == nil {
+		t.Errorf("client auth = %v", tc.ClientAuth)
+	}
+
+	notPEM := certFile + ".txt"
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (TLSOptions{SelfSigned: true, ClientCAFile: notPEM}).config("127.0.0.1:8443"); err == nil {
+		t.Error("a client CA file without certificates was accepted")
+	}
+}
+
+func TestClientCertIdentity(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "ci-runner"}}
+	verified := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	for _, tc := range []struct {
+		name string
+		cs   *tls.ConnectionState
+		want string
+	}{
+		{"plain HTTP", nil, ""},
+		{"no certificate", &tls.ConnectionState{}, ""},
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, ""},
+		{"empty chain", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{}}}, ""},
+		{"verified", verified, "cert:ci-runner"},
+	} {
+		if got := clientCertIdentity(tc.cs); got != tc.want {
+			t.Errorf("%s: clientCertIdentity = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	// A verified certificate stands in for a session; an unverified one
+	// does not.
+	s, _ := newTestServer(t)
+	for _, tc := range []struct {
+		cs   *tls.ConnectionState
+		want int
+	}{
+		{&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, http.StatusUnauthorized},
+		{verified, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/secrets", nil)
+		req.Host = "127.0.0.1:8443"
+		req.RemoteAddr = net.JoinHostPort("127.0.0.1", "5000")
+		req.TLS = tc.cs
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("verified=%v: status %d, want %d: %s", len(tc.cs.VerifiedChains) > 0, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vault-cli/internal/db"
+	"vault-cli/internal/secrets"
+)
+
+// The /api/v1 surface is declared once in v1Routes; the same table drives
+// mux registration and the OpenAPI document at /api/v1/openapi.json.
+
+const v1Prefix = "/api/v1"
+
+type route struct {
+	Method  string
+	Path    string // OpenAPI path template
+	Pattern string // mux pattern when it differs from Path
+	Summary string
+	Auth    bool
+	Query   []string
+	Request string // component schema name, "binary" for raw bodies
+	Result  string // component schema name for the success body
+	Status  int
+	Errors  []int
+
+	handler http.HandlerFunc
+}
+
+func (rt route) pattern() string {
+	if rt.Pattern != "" {
+		return rt.Pattern
+	}
+	return rt.Path
+}
+
+func (s *Server) v1Routes() []route {
+	return []route{
+		{Method: "GET", Path: "/health", Summary: "Liveness check", Result: "Health", Status: 200, handler: s.handleHealth},
+		{Method: "POST", Path: "/login", Summary: "Start a web session with the master password and optional TOTP code", Request: "Login", Result: "LoginResult", Status: 200, Errors: []int{400, 401, 429}, handler: s.handleLogin},
+		{Method: "GET", Path: "/secrets", Summary: "List secrets (values omitted) at or below prefix; repeat tag to require several", Auth: true, Query: []string{"prefix", "tag"}, Result: "SecretList", Status: 200, handler: s.v1ListSecrets},
+		{Method: "GET", Path: "/secrets/{path}", Pattern: "/secrets/{path...}", Summary: "Read a secret value; expired secrets are 410", Auth: true, Result: "Secret", Status: 200, Errors: []int{304, 404, 410, 423}, handler: s.v1GetSecret},
+		{Method: "PUT", Path: "/secrets/{path}", Pattern: "/secrets/{path...}", Summary: "Create or replace a secret; honours If-Match and If-None-Match", Auth: true, Request: "SecretWrite", Result: "Secret", Status: 200, Errors: []int{400, 412, 423}, handler: s.v1PutSecret},
+		{Method: "DELETE", Path: "/secrets/{path}", Pattern: "/secrets/{path...}", Summary: "Delete a secret; honours If-Match", Auth: true, Status: 204, Errors: []int{404, 412}, handler: s.v1DeleteSecret},
+		{Method: "GET", Path: "/files", Summary: "List stored files", Auth: true, Result: "FileList", Status: 200, handler: s.handleListFiles},
+		{Method: "GET", Path: "/files/{name}", Summary: "Download a file; supports Range requests", Auth: true, Result: "binary", Status: 200, Errors: []int{404, 416, 503}, handler: s.v1GetFile},
+		{Method: "PUT", Path: "/files/{name}", Summary: "Upload a file from the raw request body", Auth: true, Request: "binary", Result: "FileRecord", Status: 201, Errors: []int{400, 413, 503}, handler: s.v1PutFile},
+		{Method: "GET", Path: "/audit", Summary: "Recent audit events", Auth: true, Query: []string{"limit"}, Result: "AuditList", Status: 200, handler: s.handleListAudit},
+		{Method: "GET", Path: "/openapi.json", Summary: "This document", Status: 200, handler: s.handleOpenAPI},
+	}
+}
+
+func (s *Server) registerV1(mux *http.ServeMux) {
+	allowed := map[string][]string{}
+	for _, rt := range s.v1Routes() {
+		h := rt.handler
+		if rt.Auth {
+			h = s.wrapAuth(h)
+		}
+		mux.HandleFunc(rt.Method+" "+v1Prefix+rt.pattern(), h)
+		allowed[rt.pattern()] = append(allowed[rt.pattern()], rt.Method)
+	}
+	// Method-less patterns catch what the method-specific ones don't, so
+	// 405s and unknown paths get the same envelope as everything else.
+	for p, methods := range allowed {
+		allow := strings.Join(methods, ", ")
+		mux.HandleFunc(v1Prefix+p, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			s.fail(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+		})
+	}
+	mux.HandleFunc(v1Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		s.fail(w, r, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+}
+
+func isV1(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, v1Prefix+"/")
+}
+
+// fail writes an error in the shape the request's API version expects:
+// {"error": {"code", "message", "status"}} under /api/v1, and the legacy
+// {"error": "message"} elsewhere.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, status int, code, msg string) {
+	if !isV1(r) {
+		s.writeError(w, status, msg)
+		return
+	}
+	s.writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: msg, Status: status}})
+}
+
+func (s *Server) secretPath(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	cat, name, err := secrets.SplitPath(r.PathValue("path"))
+	if err != nil {
+		s.fail(w, r, http.StatusBadRequest, "invalid_path", err.Error())
+		return "", "", false
+	}
+	return cat, name, true
+}
+
+func etag(version string) string {
+	return `"` + version + `"`
+}
+
+// etagMatches reports whether an If-Match / If-None-Match header value
+// matches the current version ("" meaning the resource does not exist).
+func etagMatches(header, version string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" && version != "" {
+			return true
+		}
+		if version != "" && tag == etag(version) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) secretVersion(category, name string) (string, error) {
+	v, err := secrets.Version(s.db, category, name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return "", nil
+	}
+	return v, err
+}
+
+func (s *Server) v1ListSecrets(w http.ResponseWriter, r *http.Request) {
+	items, err := secrets.List(s.db, listPrefix(r))
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	tags := r.URL.Query()["tag"]
+	out := []secrets.Secret{}
+	for _, it := range items {
+		if !it.HasTags(tags) {
+			continue
+		}
+		if k := apiKeyFrom(r); k == nil || k.Allows("read", secrets.Path(it.Category, it.Name)) {
+			out = append(out, it)
+		}
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) v1GetSecret(w http.ResponseWriter, r *http.Request) {
+	cat, name, ok := s.secretPath(w, r)
+	if !ok || !s.authorize(w, r, "read", secrets.Path(cat, name)) {
+		return
+	}
+	version, err := s.secretVersion(cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	if version == "" {
+		s.fail(w, r, http.StatusNotFound, "secret_not_found", fmt.Sprintf("secret %s not found", secrets.Path(cat, name)))
+		return
+	}
+	w.Header().Set("ETag", etag(version))
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	val, err := secrets.Get(s.db, s.cfg, cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	sec, err := secrets.Lookup(s.db, cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	sec.Value = val
+	s.writeJSON(w, http.StatusOK, sec)
+}
+
+func (s *Server) v1PutSecret(w http.ResponseWriter, r *http.Request) {
+	cat, name, ok := s.secretPath(w, r)
+	if !ok || !s.authorize(w, r, "write", secrets.Path(cat, name)) {
+		return
+	}
+	var req struct {
+		Type        string  `json:"type"`
+		Value       *string `json:"value"`
+		ExpiresAt   string  `json:"expires_at"`
+		RotateEvery string  `json:"rotate_every"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.fail(w, r, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	if req.Value == nil {
+		s.fail(w, r, http.StatusBadRequest, "value_required", "value required")
+		return
+	}
+	sec := secrets.Secret{Category: cat, Name: name, Type: req.Type, Value: *req.Value, ExpiresAt: req.ExpiresAt, RotateEvery: req.RotateEvery}
+	if err := secrets.ValidateExpiry(sec); err != nil {
+		s.fail(w, r, http.StatusBadRequest, "invalid_expiry", err.Error())
+		return
+	}
+	if err := secrets.ValidateValue(sec); err != nil {
+		s.fail(w, r, http.StatusBadRequest, "invalid_value", err.Error())
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current, err := s.secretVersion(cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	if !s.preconditionsMet(w, r, current) {
+		return
+	}
+	if err := secrets.Add(s.db, s.cfg, sec); err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	_ = db.RecordAudit(s.db, "secret:add", secrets.Path(cat, name), "secrets", true, "")
+
+	version, err := s.secretVersion(cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(version))
+	status := http.StatusOK
+	if current == "" {
+		status = http.StatusCreated
+	}
+	s.writeJSON(w, status, secrets.Secret{Category: cat, Name: name})
+}
+
+func (s *Server) v1DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	cat, name, ok := s.secretPath(w, r)
+	if !ok || !s.authorize(w, r, "delete", secrets.Path(cat, name)) {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current, err := s.secretVersion(cat, name)
+	if err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	if current == "" {
+		s.fail(w, r, http.StatusNotFound, "secret_not_found", fmt.Sprintf("secret %s not found", secrets.Path(cat, name)))
+		return
+	}
+	if !s.preconditionsMet(w, r, current) {
+		return
+	}
+	if err := secrets.Delete(s.db, cat, name); err != nil {
+		s.failErr(w, r, err)
+		return
+	}
+	_ = db.RecordAudit(s.db, "secret:delete", secrets.Path(cat, name), "secrets", true, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// preconditionsMet applies If-Match / If-None-Match against the current
+// version and answers 412 when they fail.
+func (s *Server) preconditionsMet(w http.ResponseWriter, r *http.Request, current string) bool {
+	if im := r.Header.Get("If-Match"); im != "" && !etagMatches(im, current) {
+		s.fail(w, r, http.StatusPreconditionFailed, "precondition_failed", "If-Match does not match the current version")
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, current) {
+		s.fail(w, r, http.StatusPreconditionFailed, "precondition_failed", "resource already exists")
+		return false
+	}
+	return true
+}
+
+func (s *Server) v1GetFile(w http.ResponseWriter, r *http.Request) {
+	name := sanitizeFilename(r.PathValue("name"))
+	if !validFilename(name) {
+		s.fail(w, r, http.StatusBadRequest, "invalid_filename", "invalid filename")
+		return
+	}
+	if !s.authorize(w, r, "download", name) {
+		return
+	}
+	s.serveFile(w, r, name)
+}
+
+func (s *Server) v1PutFile(w http.ResponseWriter, r *http.Request) {
+	name := sanitizeFilename(r.PathValue("name"))
+	if !validFilename(name) {
+		s.fail(w, r, http.StatusBadRequest, "invalid_filename", "invalid filename")
+		return
+	}
+	if !s.authorize(w, r, "upload", name) {
+		return
+	}
+	rec, err := s.storeFile(r.Context(), name, http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadBytes))
+	if err != nil {
+		status, code := errorStatus(err)
+		if uploadStatus(err) == http.StatusRequestEntityTooLarge {
+			status, code = http.StatusRequestEntityTooLarge, "too_large"
+		}
+		s.fail(w, r, status, code, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, rec)
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.openAPI())
+}
+
+func (s *Server) openAPI() map[string]any {
+	paths := map[string]map[string]any{}
+	for _, rt := range s.v1Routes() {
+		op := map[string]any{
+			"operationId": operationID(rt),
+			"summary":     rt.Summary,
+		}
+
+		var params []map[string]any
+		for _, seg := range strings.Split(rt.Path, "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params = append(params, map[string]any{
+					"name": strings.Trim(seg, "{}"), "in": "path", "required": true,
+					"schema": map[string]string{"type": "string"},
+				})
+			}
+		}
+		for _, q := range rt.Query {
+			params = append(params, map[string]any{"name": q, "in": "query", "schema": map[string]string{"type": "string"}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if rt.Request != "" {
+			op["requestBody"] = map[string]any{"required": true, "content": content(rt.Request)}
+		}
+
+		responses := map[string]any{}
+		ok := map[string]any{"description": http.StatusText(rt.Status)}
+		if rt.Result != "" {
+			ok["content"] = content(rt.Result)
+		}
+		responses[strconv.Itoa(rt.Status)] = ok
+		errs := rt.Errors
+		if rt.Auth {
+			errs = append(errs, 401, 403)
+		}
+		for _, code := range append(errs, 500) {
+			if code == http.StatusNotModified {
+				responses["304"] = map[string]any{"description": "Not Modified"}
+				continue
+			}
+			responses[strconv.Itoa(code)] = map[string]any{
+				"description": http.StatusText(code),
+				"content":     content("Error"),
+			}
+		}
+		op["responses"] = responses
+
+		if rt.Auth {
+			op["security"] = []map[string][]string{{"bearer": {}}, {"session": {}}}
+		} else {
+			op["security"] = []map[string][]string{}
+		}
+
+		if paths[v1Prefix+rt.Path] == nil {
+			paths[v1Prefix+rt.Path] = map[string]any{}
+		}
+		paths[v1Prefix+rt.Path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "vault-cli API",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": v1Schemas,
+			"securitySchemes": map[string]any{
+				"bearer":  map[string]string{"type": "http", "scheme": "bearer", "description": "API key from `vault apikey create`"},
+				"session": map[string]string{"type": "apiKey", "in": "cookie", "name": csrfCookie, "description": "Web session from POST /api/v1/login; state-changing requests also need the X-CSRF-Token header"},
+			},
+		},
+	}
+}
+
+func operationID(rt route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(rt.Method))
+	for _, seg := range strings.Split(rt.Path, "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.TrimSuffix(seg, ".json")
+		if seg == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
+
+func content(schema string) map[string]any {
+	if schema == "binary" {
+		return map[string]any{"application/octet-stream": map[string]any{"schema": map[string]string{"type": "string", "format": "binary"}}}
+	}
+	return map[string]any{"application/json": map[string]any{"schema": map[string]string{"$ref": "#/components/schemas/" + schema}}}
+}
+
+func object(required []string, props map[string]any) map[string]any {
+	sort.Strings(required)
+	o := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = required
+	}
+	return o
+}
+
+var (
+	str     = map[string]string{"type": "string"}
+	integer = map[string]string{"type": "integer"}
+	boolean = map[string]string{"type": "boolean"}
+
+	// secretType is empty for a plain value; typed values are a JSON
+	// object of strings, encoded into "value".
+	secretType = map[string]any{"type": "string", "enum": append([]string{""}, secrets.Types...)}
+)
+
+var v1Schemas = map[string]any{
+	"Error": object([]string{"error"}, map[string]any{
+		"error": object([]string{"code", "message", "status"}, map[string]any{
+			"code": str, "message": str, "status": integer,
+		}),
+	}),
+	"Health": object(nil, map[string]any{"status": str}),
+	"Login":  object([]string{"password"}, map[string]any{"password": str, "code": str}),
+	"LoginResult": object(nil, map[string]any{
+		"ok": boolean, "requiresPassword": boolean, "expiresAt": str, "csrfToken": str,
+	}),
+	"Secret": object([]string{"category", "name"}, map[string]any{
+		"path": str, "category": str, "name": str, "value": str, "type": secretType, "created_at": str, "updated_at": str,
+		"expires_at": str, "rotate_every": str, "rotate_due": str, "rotate_policy": str,
+		"description": str, "owner": str,
+		"tags":   map[string]any{"type": "array", "items": str},
+		"fields": map[string]any{"type": "array", "items": map[string]string{"$ref": "#/components/schemas/Field"}},
+	}),
+	"Field": object([]string{"name", "type", "value"}, map[string]any{
+		"name": str, "value": str,
+		"type": map[string]any{"type": "string", "enum": secrets.FieldTypes},
+	}),
+	"SecretList":  map[string]any{"type": "array", "items": map[string]string{"$ref": "#/components/schemas/Secret"}},
+	"SecretWrite": object([]string{"value"}, map[string]any{"value": str, "type": secretType, "expires_at": str, "rotate_every": str}),
+	"FileRecord": object(nil, map[string]any{
+		"id": integer, "filename": str, "uploaded_at": str, "hash": str, "size": integer, "location": str, "mode": str,
+	}),
+	"FileList": map[string]any{"type": "array", "items": map[string]string{"$ref": "#/components/schemas/FileRecord"}},
+	"AuditRecord": object(nil, map[string]any{
+		"id": integer, "action": str, "filename": str, "target": str, "success": boolean, "error": str, "timestamp": str,
+	}),
+	"AuditList": map[string]any{"type": "array", "items": map[string]string{"$ref": "#/components/schemas/AuditRecord"}},
+}
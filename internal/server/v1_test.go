@@ -0,0 +1,200 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vault-cli/internal/apikey"
+	"vault-cli/internal/secrets"
+)
+
+// apiKey returns an Authorization header pair for a key with every scope.
+func apiKey(t *testing.T, s *Server) []string {
+	t.Helper()
+	token, _, err := apikey.Create(s.db, "test", []string{"*:*"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{"Authorization", "Bearer " + token}
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error apiError `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %s: %v", rec.Body, err)
+	}
+	if body.Error.Status != rec.Code {
+		t.Fatalf("envelope status %d on a %d response", body.Error.Status, rec.Code)
+	}
+	return body.Error.Code
+}
+
+func TestV1Routes(t *testing.T) {
+	s, _ := newTestServer(t)
+	auth := apiKey(t, s)
+
+	for _, tc := range []struct {
+		method, path string
+		auth         bool
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/api/v1/health", false, http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/secrets", false, http.StatusUnauthorized, "unauthorized"},
+		{http.MethodGet, "/api/v1/secrets", true, http.StatusOK, ""},
+		{http.MethodGet, "/api/v1/secrets/prod/missing", true, http.StatusNotFound, "secret_not_found"},
+		{http.MethodGet, "/api/v1/secrets/prod/", true, http.StatusBadRequest, "invalid_path"},
+		{http.MethodPost, "/api/v1/secrets", true, http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodGet, "/api/v1/nope", true, http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/api/v1/openapi.json", false, http.StatusOK, ""},
+	} {
+		var header []string
+		if tc.auth {
+			header = auth
+		}
+		rec := serve(s, tc.method, tc.path, "", header...)
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d: %s", tc.method, tc.path, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		if tc.code != "" {
+			if code := errorCode(t, rec); code != tc.code {
+				t.Errorf("%s %s: code %q, want %q", tc.method, tc.path, code, tc.code)
+			}
+		}
+	}
+
+	rec := serve(s, http.MethodPatch, "/api/v1/secrets/prod/db", "", auth...)
+	if allow := rec.Header().Get("Allow"); allow != "GET, PUT, DELETE" {
+		t.Errorf("405 Allow = %q", allow)
+	}
+}
+
+func TestV1SecretPreconditions(t *testing.T) {
+	s, database := newTestServer(t)
+	auth := apiKey(t, s)
+	with := func(h ...string) []string { return append(append([]string(nil), auth...), h...) }
+	const path = "/api/v1/secrets/prod/db"
+
+	rec := serve(s, http.MethodPut, path, `{"value":"one"}`, with("If-None-Match", "*")...)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	first := rec.Header().Get("ETag")
+	if first == "" {
+		t.Fatal("create returned no ETag")
+	}
+	if rec := serve(s, http.MethodPut, path, `{"value":"two"}`, with("If-None-Match", "*")...); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("If-None-Match * on an existing secret: status %d", rec.Code)
+	}
+
+	rec = serve(s, http.MethodGet, path, "", auth...)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != first || !strings.Contains(rec.Body.String(), `"value":"one"`) {
+		t.Fatalf("GET: status %d ETag %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+	rec = serve(s, http.MethodGet, path, "", with("If-None-Match", first)...)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("If-None-Match current: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodGet, path, "", with("If-None-Match", `"stale", W/`+first)...); rec.Code != http.StatusNotModified {
+		t.Fatalf("weak tag in a list: status %d", rec.Code)
+	}
+
+	// Editing only the metadata must change the ETag, or a cached copy
+	// would keep the old description.
+	if err := secrets.SetMeta(database, "prod", "db", secrets.Meta{Description: "primary"}); err != nil {
+		t.Fatal(err)
+	}
+	rec = serve(s, http.MethodGet, path, "", with("If-None-Match", first)...)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"description":"primary"`) {
+		t.Fatalf("after SetMeta: status %d: %s", rec.Code, rec.Body)
+	}
+	edited := rec.Header().Get("ETag")
+	if edited == first {
+		t.Fatal("SetMeta left the ETag unchanged")
+	}
+
+	rec = serve(s, http.MethodPut, path, `{"value":"two"}`, with("If-Match", first)...)
+	if rec.Code != http.StatusPreconditionFailed || errorCode(t, rec) != "precondition_failed" {
+		t.Fatalf("If-Match stale: status %d: %s", rec.Code, rec.Body)
+	}
+	rec = serve(s, http.MethodPut, path, `{"value":"two"}`, with("If-Match", edited)...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("If-Match current: status %d: %s", rec.Code, rec.Body)
+	}
+	second := rec.Header().Get("ETag")
+	if second == edited {
+		t.Fatal("PUT left the ETag unchanged")
+	}
+
+	if rec := serve(s, http.MethodDelete, path, "", with("If-Match", edited)...); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("DELETE If-Match stale: status %d", rec.Code)
+	}
+	if rec := serve(s, http.MethodDelete, path, "", with("If-Match", second)...); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE If-Match current: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPut, path, `{"value":"three"}`, with("If-Match", "*")...); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("If-Match * on a missing secret: status %d", rec.Code)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	s, _ := newTestServer(t)
+	rec := serve(s, http.MethodGet, "/api/v1/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+
+	ids := map[string]bool{}
+	for _, rt := range s.v1Routes() {
+		raw, ok := doc.Paths[v1Prefix+rt.Path][strings.ToLower(rt.Method)]
+		if !ok {
+			t.Errorf("%s %s missing from the document", rt.Method, rt.Path)
+			continue
+		}
+		var op struct {
+			OperationID string                     `json:"operationId"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		}
+		if err := json.Unmarshal(raw, &op); err != nil {
+			t.Fatal(err)
+		}
+		if ids[op.OperationID] {
+			t.Errorf("operationId %q used twice", op.OperationID)
+		}
+		ids[op.OperationID] = true
+		for _, code := range append([]int{rt.Status, http.StatusInternalServerError}, rt.Errors...) {
+			if _, ok := op.Responses[strconv.Itoa(code)]; !ok {
+				t.Errorf("%s: no %d response", op.OperationID, code)
+			}
+		}
+	}
+
+	// Every $ref must name a schema the document defines.
+	for _, ref := range strings.Split(rec.Body.String(), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.IndexByte(ref, '"')]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("$ref to undefined schema %q", name)
+		}
+	}
+}
@@ -8,6 +8,11 @@ import (
 	"time"
 )
 
+var (
+	ErrNoSession = errors.New("no active session; run 'vault login'")
+	ErrExpired   = errors.New("session expired; run 'vault login'")
+)
+
 type Session struct {
 	User      string    `json:"user"`
 	StartedAt time.Time `json:"started_at"`
@@ -46,6 +51,9 @@ func Save(user string, ttl time.Duration) error {
 func Load() (*Session, error) {
 	p, _ := path()
 	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSession
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +62,7 @@ func Load() (*Session, error) {
 		return nil, err
 	}
 	if time.Now().UTC().After(s.ExpiresAt) {
-		return nil, errors.New("session expired")
+		return nil, ErrExpired
 	}
 	return &s, nil
 }
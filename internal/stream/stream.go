@@ -0,0 +1,248 @@
+// Package stream implements the chunked AES-256-GCM format used for stored
+// files, so they can be encrypted while being received and decrypted (or
+// range-read) without holding the whole plaintext in memory or on disk.
+//
+// Layout: a 15-byte header (magic "VLT\x01", big-endian chunk size, 7-byte
+// random nonce prefix) followed by sealed chunks of ChunkSize plaintext
+// bytes. Chunk i uses nonce prefix||uint32(i)||final, where final is 1 only
+// for the last chunk, and the header as additional data, so reordering,
+// truncation and header tampering all fail authentication.
+package stream
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	ChunkSize = 64 << 10
+
+	headerSize = 15
+	prefixSize = 7
+	overhead   = 16
+)
+
+var magic = []byte("VLT\x01")
+
+var (
+	ErrFormat    = errors.New("stream: not an encrypted vault stream")
+	ErrAuth      = errors.New("stream: authentication failed")
+	ErrTooLarge  = errors.New("stream: too many chunks")
+	errClosed    = errors.New("stream: write after close")
+	errBadWhence = errors.New("stream: invalid whence")
+)
+
+// IsStream reports whether b starts with a stream header.
+func IsStream(b []byte) bool {
+	return len(b) >= headerSize && bytes.Equal(b[:len(magic)], magic)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher init: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(prefix []byte, i uint32, final bool) []byte {
+	n := make([]byte, 12)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[prefixSize:], i)
+	if final {
+		n[11] = 1
+	}
+	return n
+}
+
+// Writer encrypts everything written to it onto the underlying writer.
+// Close must be called to seal the final chunk; it does not close w.
+type Writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint32
+	closed  bool
+}
+
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[4:8], ChunkSize)
+	if _, err := io.ReadFull(rand.Reader, header[8:]); err != nil {
+		return nil, fmt.Errorf("nonce generation: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, header: header, buf: make([]byte, 0, ChunkSize+overhead)}, nil
+}
+
+func (s *Writer) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errClosed
+	}
+	n := len(p)
+	for len(p) > 0 {
+		// Only flush once more data arrives, so the last chunk is always
+		// the one sealed by Close.
+		if len(s.buf) == ChunkSize {
+			if err := s.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		k := min(ChunkSize-len(s.buf), len(p))
+		s.buf = append(s.buf, p[:k]...)
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (s *Writer) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.seal(true)
+}
+
+func (s *Writer) seal(final bool) error {
+	if s.counter == math.MaxUint32 {
+		return ErrTooLarge
+	}
+	out := s.aead.Seal(s.buf[:0], nonce(s.header[8:], s.counter, final), s.buf, s.header)
+	s.counter++
+	_, err := s.w.Write(out)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// Reader decrypts a stream on demand. It implements io.ReadSeeker over the
+// plaintext, decrypting only the chunks that are actually read.
+type Reader struct {
+	r       io.ReaderAt
+	aead    cipher.AEAD
+	header  []byte
+	chunk   int64
+	ctSize  int64
+	chunks  int64
+	size    int64
+	off     int64
+	cur     int64
+	plain   []byte
+	scratch []byte
+}
+
+// NewReader reads a stream of ctSize bytes from r.
+func NewReader(r io.ReaderAt, ctSize int64, key []byte) (*Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	if !IsStream(header) {
+		return nil, ErrFormat
+	}
+	chunk := int64(binary.BigEndian.Uint32(header[4:8]))
+	if chunk == 0 || chunk > 16<<20 {
+		return nil, ErrFormat
+	}
+	body := ctSize - headerSize
+	sealed := chunk + overhead
+	chunks := (body + sealed - 1) / sealed
+	if body < overhead || (body%sealed != 0 && body%sealed < overhead) {
+		return nil, ErrFormat
+	}
+	return &Reader{
+		r:      r,
+		aead:   aead,
+		header: header,
+		chunk:  chunk,
+		ctSize: ctSize,
+		chunks: chunks,
+		size:   body - chunks*overhead,
+		cur:    -1,
+	}, nil
+}
+
+// Size is the plaintext length.
+func (s *Reader) Size() int64 { return s.size }
+
+func (s *Reader) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		// An empty stream still has to authenticate its single chunk.
+		if s.size == 0 && s.cur < 0 {
+			if err := s.load(0); err != nil {
+				return 0, err
+			}
+		}
+		return 0, io.EOF
+	}
+	idx := s.off / s.chunk
+	if idx != s.cur {
+		if err := s.load(idx); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain[s.off-idx*s.chunk:])
+	s.off += int64(n)
+	return n, nil
+}
+
+func (s *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errBadWhence
+	}
+	if offset < 0 {
+		return 0, errors.New("stream: negative position")
+	}
+	s.off = offset
+	return offset, nil
+}
+
+func (s *Reader) load(idx int64) error {
+	sealed := s.chunk + overhead
+	start := headerSize + idx*sealed
+	n := min(sealed, s.ctSize-start)
+	if cap(s.scratch) < int(sealed) {
+		s.scratch = make([]byte, sealed)
+	}
+	buf := s.scratch[:n]
+	if got, err := s.r.ReadAt(buf, start); got < len(buf) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	plain, err := s.aead.Open(s.plain[:0], nonce(s.header[8:], uint32(idx), idx == s.chunks-1), buf, s.header)
+	if err != nil {
+		return ErrAuth
+	}
+	s.plain = plain
+	s.cur = idx
+	return nil
+}
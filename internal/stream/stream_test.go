@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// seal encrypts plain, writing it in pieces of step bytes.
+func seal(t *testing.T, key, plain []byte, step int) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := NewWriter(&out, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := plain; len(p) > 0; {
+		k := min(step, len(p))
+		if _, err := w.Write(p[:k]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[k:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func open(ct, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(ct), int64(len(ct)), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func random(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, n := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 5} {
+		plain := random(t, n)
+		for _, step := range []int{1000, ChunkSize, n + 1} {
+			ct := seal(t, key, plain, step)
+			if !IsStream(ct) {
+				t.Fatalf("size %d: output has no stream header", n)
+			}
+			got, err := open(ct, key)
+			if err != nil {
+				t.Fatalf("size %d, step %d: %v", n, step, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("size %d, step %d: plaintext differs", n, step)
+			}
+		}
+	}
+}
+
+func TestSeekReadsRanges(t *testing.T) {
+	key := testKey(t)
+	plain := random(t, 3*ChunkSize+100)
+	ct := seal(t, key, plain, len(plain))
+	r, err := NewReader(bytes.NewReader(ct), int64(len(ct)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != int64(len(plain)) {
+		t.Fatalf("Size() = %d, want %d", r.Size(), len(plain))
+	}
+	for _, rg := range [][2]int{{0, 10}, {ChunkSize - 5, 10}, {2*ChunkSize + 7, ChunkSize}, {len(plain) - 3, 3}} {
+		if _, err := r.Seek(int64(rg[0]), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, rg[1])
+		if _, err := io.ReadFull(r, got); err != nil {
+			t.Fatalf("range %v: %v", rg, err)
+		}
+		if !bytes.Equal(got, plain[rg[0]:rg[0]+rg[1]]) {
+			t.Fatalf("range %v: wrong bytes", rg)
+		}
+	}
+	if pos, err := r.Seek(-4, io.SeekEnd); err != nil || pos != int64(len(plain)-4) {
+		t.Fatalf("Seek(-4, SeekEnd) = %d, %v", pos, err)
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("negative seek accepted")
+	}
+}
+
+func TestTamperingFails(t *testing.T) {
+	key := testKey(t)
+	ct := seal(t, key, random(t, 2*ChunkSize+10), ChunkSize)
+	sealed := ChunkSize + overhead
+	for name, off := range map[string]int{
+		"chunk size":   5,
+		"nonce prefix": 10,
+		"first chunk":  headerSize + 3,
+		"first tag":    headerSize + sealed - 1,
+		"middle chunk": headerSize + sealed + 100,
+		"final chunk":  len(ct) - overhead - 1,
+		"final tag":    len(ct) - 1,
+	} {
+		bad := append([]byte(nil), ct...)
+		bad[off] ^= 0x80
+		if _, err := open(bad, key); err == nil {
+			t.Errorf("%s: tampering at %d went unnoticed", name, off)
+		}
+	}
+	bad := append([]byte(nil), ct...)
+	bad[0] = 'X'
+	if _, err := open(bad, key); !errors.Is(err, ErrFormat) {
+		t.Errorf("bad magic: got %v, want ErrFormat", err)
+	}
+}
+
+func TestTruncationFails(t *testing.T) {
+	key := testKey(t)
+	ct := seal(t, key, random(t, 3*ChunkSize), ChunkSize)
+	sealed := ChunkSize + overhead
+	for _, n := range []int{
+		headerSize + 2*sealed, // drops the final chunk exactly
+		headerSize + sealed,   // drops two whole chunks
+		len(ct) - 1,
+		len(ct) - overhead,
+		headerSize + 5,
+		headerSize,
+		4,
+	} {
+		if _, err := open(ct[:n], key); err == nil {
+			t.Errorf("stream cut to %d of %d bytes opened", n, len(ct))
+		}
+	}
+	if _, err := open(append(ct, 0), key); err == nil {
+		t.Error("stream with a trailing byte opened")
+	}
+}
+
+func TestReorderedChunksFail(t *testing.T) {
+	key := testKey(t)
+	ct := seal(t, key, random(t, 3*ChunkSize), ChunkSize)
+	sealed := ChunkSize + overhead
+	a := headerSize
+	b := headerSize + sealed
+	swapped := append([]byte(nil), ct...)
+	copy(swapped[a:b], ct[b:b+sealed])
+	copy(swapped[b:b+sealed], ct[a:b])
+	if _, err := open(swapped, key); !errors.Is(err, ErrAuth) {
+		t.Fatalf("swapped chunks: got %v, want ErrAuth", err)
+	}
+}
+
+func TestWrongKeyFails(t *testing.T) {
+	ct := seal(t, testKey(t), []byte("secret"), 10)
+	if _, err := open(ct, testKey(t)); !errors.Is(err, ErrAuth) {
+		t.Fatalf("wrong key: got %v, want ErrAuth", err)
+	}
+	empty := seal(t, testKey(t), nil, 1)
+	if _, err := open(empty, testKey(t)); !errors.Is(err, ErrAuth) {
+		t.Fatalf("empty stream under the wrong key: got %v, want ErrAuth", err)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	w, err := NewWriter(io.Discard, testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Fatal("write after Close accepted")
+	}
+}
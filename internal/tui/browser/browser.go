@@ -0,0 +1,771 @@
+// Package browser is the interactive vault browser behind `vault tui`: a
+// bubbletea app over the secrets, files and audit tables.
+package browser
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bar "github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vault-cli/internal/aws"
+	"vault-cli/internal/clipboard"
+	"vault-cli/internal/config"
+	"vault-cli/internal/core"
+	"vault-cli/internal/db"
+	"vault-cli/internal/progress"
+	"vault-cli/internal/secrets"
+)
+
+// RevealTimeout is how long a revealed secret value stays on screen.
+const RevealTimeout = 15 * time.Second
+
+type tab int
+
+const (
+	tabSecrets tab = iota
+	tabFiles
+	tabAudit
+)
+
+var tabNames = []string{"Secrets", "Files", "Audit"}
+
+type mode int
+
+const (
+	modeBrowse mode = iota
+	modeSearch
+	modeForm
+	modeConfirm
+	modePath
+	modeTransfer
+)
+
+type Model struct {
+	db  *sql.DB
+	cfg *config.Config
+
+	tab       tab
+	mode      mode
+	width     int
+	height    int
+	status    string
+	statusErr bool
+
+	// secrets
+	all          []secrets.Secret
+	categories   []string // categories[0] is "" meaning all
+	catIdx       int
+	secIdx       int
+	focusSecrets bool
+	search       textinput.Model
+	revealedKey  string
+	revealedVal  string
+	revealGen    int
+	clip         *clipboard.Copied // cleared after cfg.ClipTimeout or on exit
+
+	form      []textinput.Model
+	formFocus int
+	editing   bool
+	edited    secrets.Secret // type and metadata of the secret being edited
+
+	confirmMsg    string
+	confirmAction tea.Cmd
+
+	// files
+	files      []string
+	versions   map[string][]db.FileRecord
+	fileIdx    int
+	pathInput  textinput.Model
+	pathAction string
+	bar        bar.Model
+	event      progress.Event
+	events     chan progress.Event
+	cancel     context.CancelFunc
+
+	// audit
+	audit    []db.AuditRecord
+	auditOff int
+}
+
+func New(database *sql.DB, cfg *config.Config) Model {
+	search := textinput.New()
+	search.Prompt = "/ "
+	search.Placeholder = "search category or name"
+
+	path := textinput.New()
+	path.Prompt = "> "
+
+	b := bar.New(bar.WithDefaultGradient())
+	b.Width = 50
+
+	return Model{
+		db:         database,
+		cfg:        cfg,
+		width:      100,
+		height:     30,
+		categories: []string{""},
+		versions:   map[string][]db.FileRecord{},
+		search:     search,
+		pathInput:  path,
+		bar:        b,
+	}
+}
+
+// Run starts the browser full-screen and blocks until the user quits.
+func Run(database *sql.DB, cfg *config.Config) error {
+	final, err := tea.NewProgram(New(database, cfg), tea.WithAltScreen()).Run()
+	if m, ok := final.(Model); ok && m.clip != nil {
+		_ = m.clip.Clear()
+	}
+	return err
+}
+
+type (
+	secretsMsg struct {
+		items []secrets.Secret
+		err   error
+	}
+	filesMsg struct {
+		items []db.FileRecord
+		err   error
+	}
+	auditMsg struct {
+		items []db.AuditRecord
+		err   error
+	}
+	revealMsg struct {
+		key, value string
+		copy       bool
+		err        error
+	}
+	hideMsg      struct{ gen int }
+	clearClipMsg struct{ clip *clipboard.Copied }
+	editMsg      struct {
+		sec secrets.Secret
+		err error
+	}
+	doneMsg struct {
+		status string
+		err    error
+		reload tea.Cmd
+	}
+	eventMsg    progress.Event
+	transferMsg struct {
+		res    *aws.Result
+		action string
+		err    error
+	}
+)
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.loadSecrets, m.loadFiles, m.loadAudit)
+}
+
+func (m Model) loadSecrets() tea.Msg {
+	items, err := secrets.List(m.db, "")
+	return secretsMsg{items, err}
+}
+
+func (m Model) loadFiles() tea.Msg {
+	items, err := db.ListFiles(m.db)
+	return filesMsg{items, err}
+}
+
+func (m Model) loadAudit() tea.Msg {
+	items, err := db.ListAudit(m.db, 500)
+	return auditMsg{items, err}
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.bar.Width = min(60, max(20, msg.Width-20))
+		return m, nil
+
+	case secretsMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		m.setSecrets(msg.items)
+		return m, nil
+
+	case filesMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		m.setFiles(msg.items)
+		return m, nil
+
+	case auditMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		m.audit = msg.items
+		m.auditOff = min(m.auditOff, max(0, len(m.audit)-1))
+		return m, nil
+
+	case revealMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		_ = db.RecordAudit(m.db, "secret:read", msg.key, "tui", true, "")
+		if msg.copy {
+			c, err := clipboard.Copy(msg.value)
+			if err != nil {
+				return m.flash(err), nil
+			}
+			if m.clip != nil {
+				_ = m.clip.Clear()
+			}
+			m.clip = c
+			if m.cfg.ClipTimeout == 0 {
+				return m.info("Copied " + msg.key + " to the clipboard"), nil
+			}
+			return m.info(fmt.Sprintf("Copied %s to the clipboard; clearing in %s", msg.key, m.cfg.ClipTimeout)),
+				tea.Tick(m.cfg.ClipTimeout, func(time.Time) tea.Msg { return clearClipMsg{c} })
+		}
+		m.revealGen++
+		m.revealedKey, m.revealedVal = msg.key, msg.value
+		gen := m.revealGen
+		return m, tea.Tick(RevealTimeout, func(time.Time) tea.Msg { return hideMsg{gen} })
+
+	case clearClipMsg:
+		if msg.clip != m.clip {
+			return m, nil
+		}
+		m.clip = nil
+		if err := msg.clip.Clear(); err != nil {
+			return m.flash(err), nil
+		}
+		return m.info("Clipboard cleared"), nil
+
+	case hideMsg:
+		if msg.gen == m.revealGen {
+			m.hide()
+		}
+		return m, nil
+
+	case editMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		cmd := m.openForm(msg.sec, true)
+		return m, cmd
+
+	case doneMsg:
+		if msg.err != nil {
+			return m.flash(msg.err), nil
+		}
+		m = m.info(msg.status)
+		return m, tea.Batch(msg.reload, m.loadAudit)
+
+	case eventMsg:
+		m.event = progress.Event(msg)
+		return m, m.waitEvent()
+
+	case transferMsg:
+		m.mode = modeBrowse
+		m.cancel = nil
+		if msg.err != nil {
+			return m.flash(msg.err), m.loadAudit
+		}
+		status := fmt.Sprintf("%s %s: %s, sha256 %s", msg.action, msg.res.Name, humanBytes(msg.res.Size), msg.res.Hash[:16])
+		return m.info(status), tea.Batch(m.loadFiles, m.loadAudit)
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		if m.mode == modeTransfer && m.cancel != nil {
+			m.cancel()
+			return m, nil
+		}
+		return m, tea.Quit
+	}
+
+	switch m.mode {
+	case modeSearch:
+		return m.searchKey(msg)
+	case modeForm:
+		return m.formKey(msg)
+	case modeConfirm:
+		switch msg.String() {
+		case "y", "Y":
+			m.mode = modeBrowse
+			return m, m.confirmAction
+		case "n", "N", "esc":
+			m.mode = modeBrowse
+			return m.info("Cancelled"), nil
+		}
+		return m, nil
+	case modePath:
+		return m.pathKey(msg)
+	case modeTransfer:
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "tab":
+		m.tab = (m.tab + 1) % tab(len(tabNames))
+		m.hide()
+		return m, nil
+	case "shift+tab":
+		m.tab = (m.tab + tab(len(tabNames)) - 1) % tab(len(tabNames))
+		m.hide()
+		return m, nil
+	case "1", "2", "3":
+		m.tab = tab(msg.String()[0] - '1')
+		m.hide()
+		return m, nil
+	case "r":
+		m.status = ""
+		return m, tea.Batch(m.loadSecrets, m.loadFiles, m.loadAudit)
+	}
+
+	switch m.tab {
+	case tabSecrets:
+		return m.secretsKey(msg)
+	case tabFiles:
+		return m.filesKey(msg)
+	default:
+		return m.auditKey(msg)
+	}
+}
+
+func (m *Model) hide() {
+	m.revealedKey, m.revealedVal = "", ""
+}
+
+func (m Model) flash(err error) Model {
+	m.status, m.statusErr = err.Error(), true
+	return m
+}
+
+func (m Model) info(s string) Model {
+	m.status, m.statusErr = s, false
+	return m
+}
+
+// Secrets tab.
+
+func (m *Model) setSecrets(items []secrets.Secret) {
+	m.all = items
+	seen := map[string]bool{}
+	cats := []string{""}
+	for _, s := range items {
+		if !seen[s.Category] {
+			seen[s.Category] = true
+			cats = append(cats, s.Category)
+		}
+	}
+	sort.Strings(cats[1:])
+	m.categories = cats
+	m.catIdx = min(m.catIdx, len(cats)-1)
+	m.secIdx = min(m.secIdx, max(0, len(m.visible())-1))
+}
+
+// visible is the secrets in the selected category matching the search.
+func (m Model) visible() []secrets.Secret {
+	cat := m.categories[m.catIdx]
+	q := strings.ToLower(strings.TrimSpace(m.search.Value()))
+	var out []secrets.Secret
+	for _, s := range m.all {
+		if cat != "" && s.Category != cat {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(secrets.Path(s.Category, s.Name)), q) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (m Model) selected() (secrets.Secret, bool) {
+	vis := m.visible()
+	if m.secIdx < 0 || m.secIdx >= len(vis) {
+		return secrets.Secret{}, false
+	}
+	return vis[m.secIdx], true
+}
+
+func key(s secrets.Secret) string { return secrets.Path(s.Category, s.Name) }
+
+func (m Model) secretsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "left", "h":
+		m.focusSecrets = false
+	case "right", "l":
+		m.focusSecrets = true
+	case "up", "k":
+		if m.focusSecrets {
+			m.secIdx = max(0, m.secIdx-1)
+		} else if m.catIdx > 0 {
+			m.catIdx--
+			m.secIdx = 0
+		}
+		m.hide()
+	case "down", "j":
+		if m.focusSecrets {
+			m.secIdx = min(max(0, len(m.visible())-1), m.secIdx+1)
+		} else if m.catIdx < len(m.categories)-1 {
+			m.catIdx++
+			m.secIdx = 0
+		}
+		m.hide()
+	case "/":
+		m.mode = modeSearch
+		m.focusSecrets = true
+		cmd := m.search.Focus()
+		return m, cmd
+	case "esc":
+		m.search.SetValue("")
+		m.secIdx = 0
+		m.hide()
+	case "enter", " ", "v":
+		s, ok := m.selected()
+		if !ok {
+			return m, nil
+		}
+		if m.revealedKey == key(s) {
+			m.hide()
+			return m, nil
+		}
+		return m, m.decrypt(s, false)
+	case "c", "y":
+		if s, ok := m.selected(); ok {
+			return m, m.decrypt(s, true)
+		}
+	case "a":
+		cmd := m.openForm(secrets.Secret{Category: m.categories[m.catIdx]}, false)
+		return m, cmd
+	case "e":
+		if s, ok := m.selected(); ok {
+			return m, func() tea.Msg {
+				s, err := secrets.Lookup(m.db, s.Category, s.Name)
+				if err != nil {
+					return editMsg{err: err}
+				}
+				s.Value, err = secrets.Get(m.db, m.cfg, s.Category, s.Name)
+				return editMsg{s, err}
+			}
+		}
+	case "d", "delete":
+		if s, ok := m.selected(); ok {
+			m.mode = modeConfirm
+			m.confirmMsg = fmt.Sprintf("Delete secret %s? (y/n)", key(s))
+			m.confirmAction = m.deleteSecret(s)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) decrypt(s secrets.Secret, copy bool) tea.Cmd {
+	return func() tea.Msg {
+		v, err := secrets.Get(m.db, m.cfg, s.Category, s.Name)
+		return revealMsg{key: key(s), value: v, copy: copy, err: err}
+	}
+}
+
+func (m Model) deleteSecret(s secrets.Secret) tea.Cmd {
+	return func() tea.Msg {
+		if err := secrets.Delete(m.db, s.Category, s.Name); err != nil {
+			return doneMsg{err: err}
+		}
+		_ = db.RecordAudit(m.db, "secret:delete", key(s), "tui", true, "")
+		return doneMsg{status: "Deleted " + key(s), reload: m.loadSecrets}
+	}
+}
+
+func (m Model) searchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.mode = modeBrowse
+		m.search.Blur()
+		return m, nil
+	case "esc":
+		m.mode = modeBrowse
+		m.search.Blur()
+		m.search.SetValue("")
+		m.secIdx = 0
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.secIdx = 0
+	m.hide()
+	return m, cmd
+}
+
+var formLabels = []string{"Category", "Name", "Value"}
+
+func (m *Model) openForm(s secrets.Secret, editing bool) tea.Cmd {
+	m.form = make([]textinput.Model, len(formLabels))
+	for i := range m.form {
+		in := textinput.New()
+		in.Prompt = ""
+		in.CharLimit = 0
+		m.form[i] = in
+	}
+	m.form[0].SetValue(s.Category)
+	m.form[1].SetValue(s.Name)
+	m.form[2].SetValue(s.Value)
+	m.form[2].EchoMode = textinput.EchoPassword
+	m.editing = editing
+	m.edited = secrets.Secret{}
+	if editing {
+		m.edited = secrets.Secret{Type: s.Type, Meta: s.Meta}
+	}
+	m.mode = modeForm
+	m.formFocus = 0
+	if editing {
+		m.formFocus = 2
+	} else if s.Category != "" {
+		m.formFocus = 1
+	}
+	return m.form[m.formFocus].Focus()
+}
+
+func (m Model) formKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	first := 0
+	if m.editing {
+		first = 2
+	}
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.form = nil
+		return m.info("Cancelled"), nil
+	case "ctrl+r":
+		if m.form[2].EchoMode == textinput.EchoPassword {
+			m.form[2].EchoMode = textinput.EchoNormal
+		} else {
+			m.form[2].EchoMode = textinput.EchoPassword
+		}
+		return m, nil
+	case "tab", "down", "shift+tab", "up":
+		m.form[m.formFocus].Blur()
+		if msg.String() == "tab" || msg.String() == "down" {
+			m.formFocus++
+		} else {
+			m.formFocus--
+		}
+		if m.formFocus > 2 {
+			m.formFocus = first
+		}
+		if m.formFocus < first {
+			m.formFocus = 2
+		}
+		return m, m.form[m.formFocus].Focus()
+	case "enter":
+		if m.formFocus < 2 {
+			m.form[m.formFocus].Blur()
+			m.formFocus++
+			return m, m.form[m.formFocus].Focus()
+		}
+		s := secrets.Secret{
+			Category: strings.TrimSpace(m.form[0].Value()),
+			Name:     strings.TrimSpace(m.form[1].Value()),
+			Value:    m.form[2].Value(),
+		}
+		if s.Category == "" || s.Name == "" {
+			return m.flash(fmt.Errorf("category and name are required")), nil
+		}
+		// An edit replaces the value only: a typed secret stays typed, so
+		// its new value must still be a valid document, and its metadata
+		// is written back with it.
+		editing := m.editing
+		if editing {
+			s.Type, s.Meta = m.edited.Type, m.edited.Meta
+			if err := secrets.ValidateValue(s); err != nil {
+				return m.flash(err), nil
+			}
+		}
+		m.mode = modeBrowse
+		m.form = nil
+		return m, func() tea.Msg {
+			if err := secrets.Add(m.db, m.cfg, s); err != nil {
+				return doneMsg{err: err}
+			}
+			if editing {
+				if err := secrets.SetMeta(m.db, s.Category, s.Name, s.Meta); err != nil {
+					return doneMsg{err: err}
+				}
+			}
+			_ = db.RecordAudit(m.db, "secret:add", key(s), "tui", true, "")
+			return doneMsg{status: "Saved " + key(s), reload: m.loadSecrets}
+		}
+	}
+	var cmd tea.Cmd
+	m.form[m.formFocus], cmd = m.form[m.formFocus].Update(msg)
+	return m, cmd
+}
+
+// Files tab.
+
+func (m *Model) setFiles(items []db.FileRecord) {
+	m.versions = map[string][]db.FileRecord{}
+	m.files = nil
+	// ListFiles is newest first, so each name's first row is its latest
+	// version and names come out ordered by most recent upload.
+	for _, f := range items {
+		if _, ok := m.versions[f.Filename]; !ok {
+			m.files = append(m.files, f.Filename)
+		}
+		m.versions[f.Filename] = append(m.versions[f.Filename], f)
+	}
+	m.fileIdx = min(m.fileIdx, max(0, len(m.files)-1))
+}
+
+func (m Model) selectedFile() (string, bool) {
+	if m.fileIdx < 0 || m.fileIdx >= len(m.files) {
+		return "", false
+	}
+	return m.files[m.fileIdx], true
+}
+
+func (m Model) filesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.fileIdx = max(0, m.fileIdx-1)
+	case "down", "j":
+		m.fileIdx = min(max(0, len(m.files)-1), m.fileIdx+1)
+	case "u":
+		m.mode = modePath
+		m.pathAction = "upload"
+		m.pathInput.Placeholder = "path of the file to upload"
+		m.pathInput.SetValue("")
+		cmd := m.pathInput.Focus()
+		return m, cmd
+	case "d":
+		name, ok := m.selectedFile()
+		if !ok {
+			return m, nil
+		}
+		m.mode = modePath
+		m.pathAction = "download"
+		m.pathInput.Placeholder = "save decrypted file as"
+		m.pathInput.SetValue("decrypted_" + name)
+		m.pathInput.CursorEnd()
+		cmd := m.pathInput.Focus()
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m Model) pathKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeBrowse
+		m.pathInput.Blur()
+		return m.info("Cancelled"), nil
+	case "enter":
+		path := strings.TrimSpace(m.pathInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.pathInput.Blur()
+		return m.startTransfer(path)
+	}
+	var cmd tea.Cmd
+	m.pathInput, cmd = m.pathInput.Update(msg)
+	return m, cmd
+}
+
+var transferVerbs = map[string][2]string{
+	"upload":   {"Uploading", "Uploaded"},
+	"download": {"Downloading", "Downloaded"},
+}
+
+// startTransfer runs the upload or download in the background; progress
+// events arrive through m.events and are re-armed by waitEvent.
+func (m Model) startTransfer(path string) (tea.Model, tea.Cmd) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan progress.Event, 16)
+	report := func(e progress.Event) {
+		select {
+		case events <- e:
+		default:
+			// Drop intermediate events rather than stall the transfer.
+		}
+	}
+
+	action, name := m.pathAction, ""
+	var job func() (*aws.Result, error)
+	if action == "upload" {
+		name = filepath.Base(path)
+		job = func() (*aws.Result, error) { return aws.UploadFile(ctx, path, m.cfg, m.db, report) }
+	} else {
+		name, _ = m.selectedFile()
+		job = func() (*aws.Result, error) { return core.DownloadToFile(ctx, name, path, false, m.cfg, m.db, report) }
+	}
+
+	m.mode = modeTransfer
+	m.cancel = cancel
+	m.events = events
+	m.event = progress.Event{Total: -1}
+	m.status = fmt.Sprintf("%s %s...", transferVerbs[action][0], name)
+	m.statusErr = false
+
+	run := func() tea.Msg {
+		defer cancel()
+		res, err := job()
+		close(events)
+		return transferMsg{res: res, action: transferVerbs[action][1], err: err}
+	}
+	return m, tea.Batch(run, m.waitEvent())
+}
+
+func (m Model) waitEvent() tea.Cmd {
+	events := m.events
+	return func() tea.Msg {
+		e, ok := <-events
+		if !ok {
+			return nil
+		}
+		return eventMsg(e)
+	}
+}
+
+// Audit tab.
+
+func (m Model) auditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := max(1, m.height-8)
+	last := max(0, len(m.audit)-1)
+	switch msg.String() {
+	case "up", "k":
+		m.auditOff = max(0, m.auditOff-1)
+	case "down", "j":
+		m.auditOff = min(last, m.auditOff+1)
+	case "pgup", "b":
+		m.auditOff = max(0, m.auditOff-page)
+	case "pgdown", "f", " ":
+		m.auditOff = min(last, m.auditOff+page)
+	case "g", "home":
+		m.auditOff = 0
+	case "G", "end":
+		m.auditOff = last
+	}
+	return m, nil
+}
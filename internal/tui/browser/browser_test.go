@@ -0,0 +1,99 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"vault-cli/internal/config"
+	"vault-cli/internal/db"
+	"vault-cli/internal/db/dbtest"
+	"vault-cli/internal/secrets"
+)
+
+var local = &config.Config{Mode: "local"}
+
+// save types value into an open edit form and presses enter.
+func save(t *testing.T, m Model, value string) (Model, tea.Cmd) {
+	t.Helper()
+	m.form[2].SetValue(value)
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return next.(Model), cmd
+}
+
+func TestEditKeepsTypeAndMeta(t *testing.T) {
+	database := dbtest.Open(t)
+	meta := secrets.Meta{Description: "main db", Owner: "ops", Tags: []string{"pci"}}
+	err := secrets.Import(database, local, []secrets.Secret{{
+		Category: "prod", Name: "db", Type: secrets.TypeLogin,
+		Value: `{"username":"app","password":"old"}`, Meta: meta,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(database, local)
+	m.all = []secrets.Secret{{Category: "prod", Name: "db"}}
+	m.focusSecrets = true
+	_, cmd := m.secretsKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	next, _ := m.Update(cmd())
+	m = next.(Model)
+	if !m.editing || m.edited.Type != secrets.TypeLogin {
+		t.Fatalf("edit form: editing=%v type=%q", m.editing, m.edited.Type)
+	}
+
+	m, cmd = save(t, m, "plain text")
+	if cmd != nil || !m.statusErr || m.mode != modeForm {
+		t.Fatalf("free text accepted for a typed secret (status %q)", m.status)
+	}
+	m, cmd = save(t, m, `{"username":"app","password":"new"}`)
+	if done := cmd().(doneMsg); done.err != nil {
+		t.Fatal(done.err)
+	}
+
+	got, err := secrets.Lookup(database, "prod", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != secrets.TypeLogin || !reflect.DeepEqual(got.Meta, meta) {
+		t.Fatalf("after edit: type %q, meta %+v", got.Type, got.Meta)
+	}
+	if v, _ := secrets.Get(database, local, "prod", "db"); v != `{"password":"new","username":"app"}` {
+		t.Fatalf("value = %q", v)
+	}
+}
+
+func TestFilesDownloadTheLatestVersion(t *testing.T) {
+	m := New(dbtest.Open(t), local)
+	m.tab = tabFiles
+	m.setFiles([]db.FileRecord{
+		{Filename: "a.txt", Uploaded: "2026-02-01 00:00:00", Hash: "new"},
+		{Filename: "b.txt", Uploaded: "2026-01-15 00:00:00", Hash: "b"},
+		{Filename: "a.txt", Uploaded: "2026-01-01 00:00:00", Hash: "old"},
+	})
+	if !reflect.DeepEqual(m.files, []string{"a.txt", "b.txt"}) || len(m.versions["a.txt"]) != 2 {
+		t.Fatalf("files = %q, versions of a.txt = %d", m.files, len(m.versions["a.txt"]))
+	}
+
+	// Moving "into" the history must not pick an older version: down
+	// still moves between files.
+	for _, k := range []string{"l", "enter", "j"} {
+		next, _ := m.filesKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = next.(Model)
+	}
+	if name, _ := m.selectedFile(); name != "b.txt" {
+		t.Fatalf("selected %q after moving down, want b.txt", name)
+	}
+	next, _ := m.filesKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	m = next.(Model)
+	next, _ = m.filesKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m = next.(Model)
+	if m.pathAction != "download" || m.pathInput.Value() != "decrypted_a.txt" {
+		t.Fatalf("d: action %q, path %q", m.pathAction, m.pathInput.Value())
+	}
+	if !strings.Contains(m.filesView(), "d downloads the latest") {
+		t.Fatal("the history pane does not say which version d downloads")
+	}
+}
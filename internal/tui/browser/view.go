@@ -0,0 +1,300 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"vault-cli/internal/secrets"
+)
+
+var (
+	accent = lipgloss.Color("#3b82f6")
+	muted  = lipgloss.Color("#64748b")
+	danger = lipgloss.Color("#ef4444")
+	good   = lipgloss.Color("#22c55e")
+
+	titleStyle    = lipgloss.NewStyle().Foreground(accent).Bold(true)
+	tabStyle      = lipgloss.NewStyle().Padding(0, 2).Foreground(muted)
+	activeTab     = tabStyle.Foreground(lipgloss.Color("#e2e8f0")).Background(accent).Bold(true)
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(muted).Padding(0, 1)
+	focusPane     = paneStyle.BorderForeground(accent)
+	selectedStyle = lipgloss.NewStyle().Foreground(accent).Bold(true)
+	mutedStyle    = lipgloss.NewStyle().Foreground(muted)
+	errStyle      = lipgloss.NewStyle().Foreground(danger)
+	okStyle       = lipgloss.NewStyle().Foreground(good)
+	secretStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#facc15"))
+)
+
+func (m Model) View() string {
+	var tabs []string
+	for i, name := range tabNames {
+		label := fmt.Sprintf("%d %s", i+1, name)
+		if tab(i) == m.tab {
+			tabs = append(tabs, activeTab.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+	header := lipgloss.JoinHorizontal(lipgloss.Top, titleStyle.Render("Vault  "), lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
+
+	var body string
+	switch m.tab {
+	case tabSecrets:
+		body = m.secretsView()
+	case tabFiles:
+		body = m.filesView()
+	default:
+		body = m.auditView()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, body, m.footer())
+}
+
+// bodyHeight is the number of list rows that fit between header and footer.
+func (m Model) bodyHeight() int {
+	return max(3, m.height-8)
+}
+
+// window returns the slice bounds that keep sel visible in n rows.
+func window(total, sel, n int) (int, int) {
+	if total <= n {
+		return 0, total
+	}
+	start := min(max(0, sel-n/2), total-n)
+	return start, start + n
+}
+
+func (m Model) secretsView() string {
+	rows := m.bodyHeight()
+	catWidth := max(16, m.width/4)
+	listWidth := max(30, m.width-catWidth-6)
+
+	var cats []string
+	start, end := window(len(m.categories), m.catIdx, rows)
+	for i := start; i < end; i++ {
+		name := m.categories[i]
+		if name == "" {
+			name = "(all)"
+		}
+		line := truncate(name, catWidth-2)
+		if i == m.catIdx {
+			line = selectedStyle.Render("▸ " + line)
+		} else {
+			line = "  " + line
+		}
+		cats = append(cats, line)
+	}
+
+	vis := m.visible()
+	var list []string
+	if m.mode == modeSearch || m.search.Value() != "" {
+		list = append(list, m.search.View())
+		rows--
+	}
+	if len(vis) == 0 {
+		list = append(list, mutedStyle.Render("no secrets"))
+	}
+	start, end = window(len(vis), m.secIdx, rows)
+	for i := start; i < end; i++ {
+		list = append(list, m.secretLine(vis[i], i == m.secIdx, listWidth-2))
+	}
+
+	catPane, listPane := paneStyle, focusPane
+	if !m.focusSecrets {
+		catPane, listPane = focusPane, paneStyle
+	}
+	left := catPane.Width(catWidth).Height(m.bodyHeight()).Render(strings.Join(cats, "\n"))
+	right := listPane.Width(listWidth).Height(m.bodyHeight()).Render(strings.Join(list, "\n"))
+	view := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+
+	switch m.mode {
+	case modeForm:
+		return lipgloss.JoinVertical(lipgloss.Left, view, m.formView())
+	case modeConfirm:
+		return lipgloss.JoinVertical(lipgloss.Left, view, errStyle.Render(m.confirmMsg))
+	}
+	return view
+}
+
+func (m Model) secretLine(s secrets.Secret, selected bool, width int) string {
+	name := s.Name
+	if m.categories[m.catIdx] == "" {
+		name = secrets.Path(s.Category, s.Name)
+	}
+	value := mutedStyle.Render("••••••••")
+	if m.revealedKey == key(s) {
+		value = secretStyle.Render(m.revealedVal)
+	}
+	updated := mutedStyle.Render(s.UpdatedAt)
+	line := fmt.Sprintf("%-*s %s  %s", min(32, width/2), truncate(name, min(32, width/2)), value, updated)
+	if selected {
+		return selectedStyle.Render("▸ ") + line
+	}
+	return "  " + line
+}
+
+func (m Model) formView() string {
+	title := "Add secret"
+	if m.editing {
+		title = "Edit secret"
+		if m.edited.Type != "" {
+			title += " (" + m.edited.Type + ", JSON value)"
+		}
+	}
+	lines := []string{titleStyle.Render(title)}
+	for i, in := range m.form {
+		label := fmt.Sprintf("%-9s", formLabels[i]+":")
+		if i == m.formFocus {
+			label = selectedStyle.Render(label)
+		} else {
+			label = mutedStyle.Render(label)
+		}
+		lines = append(lines, label+" "+in.View())
+	}
+	lines = append(lines, mutedStyle.Render("enter next/save · tab move · ctrl+r show value · esc cancel"))
+	return focusPane.Width(max(40, m.width-4)).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) filesView() string {
+	rows := m.bodyHeight()
+	fileWidth := max(20, m.width/3)
+	verWidth := max(40, m.width-fileWidth-6)
+
+	var files []string
+	if len(m.files) == 0 {
+		files = append(files, mutedStyle.Render("no files"))
+	}
+	start, end := window(len(m.files), m.fileIdx, rows)
+	for i := start; i < end; i++ {
+		line := truncate(m.files[i], fileWidth-4)
+		if n := len(m.versions[m.files[i]]); n > 1 {
+			line += mutedStyle.Render(fmt.Sprintf(" (%d)", n))
+		}
+		if i == m.fileIdx {
+			files = append(files, selectedStyle.Render("▸ ")+line)
+		} else {
+			files = append(files, "  "+line)
+		}
+	}
+
+	// Uploads replace the stored object, so only the latest version can be
+	// downloaded; the older rows are history from the files table.
+	var vers []string
+	if name, ok := m.selectedFile(); ok {
+		vers = append(vers, mutedStyle.Render("upload history · d downloads the latest"))
+		vers = append(vers, mutedStyle.Render(fmt.Sprintf("%-20s %10s  %-16s %s", "uploaded", "size", "sha256", "location")))
+		list := m.versions[name]
+		for i := 0; i < len(list) && i < rows-2; i++ {
+			f := list[i]
+			hash := f.Hash
+			if len(hash) > 16 {
+				hash = hash[:16]
+			}
+			vers = append(vers, fmt.Sprintf("%-20s %10s  %-16s %s", f.Uploaded, humanBytes(f.Size), hash, truncate(f.Location+" ("+f.Mode+")", verWidth-52)))
+		}
+	}
+
+	view := lipgloss.JoinHorizontal(lipgloss.Top,
+		focusPane.Width(fileWidth).Height(rows).Render(strings.Join(files, "\n")),
+		paneStyle.Width(verWidth).Height(rows).Render(strings.Join(vers, "\n")),
+	)
+
+	switch m.mode {
+	case modePath:
+		prompt := "Upload file"
+		if m.pathAction == "download" {
+			prompt = "Download to"
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, view,
+			focusPane.Width(max(40, m.width-4)).Render(titleStyle.Render(prompt)+"\n"+m.pathInput.View()))
+	case modeTransfer:
+		e := m.event
+		stats := humanBytes(e.Bytes)
+		if e.Total >= 0 {
+			stats += " / " + humanBytes(e.Total)
+		}
+		if e.Rate > 0 {
+			stats += fmt.Sprintf("  %s/s", humanBytes(int64(e.Rate)))
+		}
+		if e.ETA > 0 {
+			stats += fmt.Sprintf("  ETA %s", e.ETA.Round(time.Second))
+		}
+		stage := string(e.Stage)
+		if stage == "" {
+			stage = "starting"
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, view,
+			focusPane.Width(max(40, m.width-4)).Render(fmt.Sprintf("%s\n%s\n%s", stage, m.bar.ViewAs(e.Fraction()), stats)))
+	}
+	return view
+}
+
+func (m Model) auditView() string {
+	rows := m.bodyHeight()
+	lines := []string{mutedStyle.Render(fmt.Sprintf("%-20s %-18s %-28s %-20s %s", "time", "action", "subject", "target", "result"))}
+	end := min(len(m.audit), m.auditOff+rows-1)
+	for _, a := range m.audit[min(m.auditOff, end):end] {
+		result := okStyle.Render("ok")
+		if !a.Success {
+			result = errStyle.Render(truncate("failed "+a.Error, max(10, m.width-98)))
+		}
+		lines = append(lines, fmt.Sprintf("%-20s %-18s %-28s %-20s %s",
+			a.TS, truncate(a.Action, 18), truncate(a.Filename, 28), truncate(a.Target, 20), result))
+	}
+	if len(m.audit) == 0 {
+		lines = append(lines, mutedStyle.Render("no audit events"))
+	}
+	return focusPane.Width(max(40, m.width-4)).Height(rows).Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) footer() string {
+	var help string
+	switch {
+	case m.mode == modeSearch:
+		help = "type to filter · enter keep · esc clear"
+	case m.mode == modeTransfer:
+		help = "ctrl+c cancel transfer"
+	case m.tab == tabSecrets:
+		help = "←/→ pane · ↑/↓ move · / search · enter reveal · c copy · a add · e edit · d delete · tab next · q quit"
+	case m.tab == tabFiles:
+		help = "↑/↓ file · u upload · d download latest · r refresh · tab next · q quit"
+	default:
+		help = "↑/↓ scroll · pgup/pgdn page · g/G top/bottom · r refresh · tab next · q quit"
+	}
+	out := mutedStyle.Render(help)
+	if m.status != "" {
+		st := okStyle.Render(m.status)
+		if m.statusErr {
+			st = errStyle.Render(m.status)
+		}
+		out = st + "\n" + out
+	}
+	return out
+}
+
+func truncate(s string, n int) string {
+	if n <= 1 || lipgloss.Width(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if len(r) > n-1 {
+		r = r[:n-1]
+	}
+	return string(r) + "…"
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
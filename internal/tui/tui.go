@@ -1,11 +1,18 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	bar "github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+
+	"vault-cli/internal/progress"
 )
 
 var (
@@ -32,60 +39,138 @@ var (
 			Foreground(textBright)
 )
 
-type model struct {
-	progress int
-	message  string
-	done     bool
+// Field is one line of the results panel shown when a transfer finishes.
+type Field struct {
+	Label string
+	Value string
 }
 
-func (m model) Init() tea.Cmd {
-	return tick()
+// Enabled reports whether interactive output should be used: only when
+// stdout is a terminal, so pipes and CI logs get plain text.
+func Enabled() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
 }
 
-func tick() tea.Cmd {
-	return tea.Tick(time.Millisecond*80, func(t time.Time) tea.Msg {
-		return "tick"
-	})
+type eventMsg progress.Event
+
+type doneMsg struct {
+	fields []Field
+	err    error
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg {
-	case "tick":
-		if m.progress < 100 {
-			m.progress += 2
-			m.message = fmt.Sprintf("Uploading... %d%%", m.progress)
-			return m, tick()
+type transferModel struct {
+	title   string
+	bar     bar.Model
+	event   progress.Event
+	started bool
+	fields  []Field
+	err     error
+	done    bool
+	cancel  context.CancelFunc
+}
+
+func (m transferModel) Init() tea.Cmd { return nil }
+
+func (m transferModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case eventMsg:
+		m.event = progress.Event(msg)
+		m.started = true
+	case doneMsg:
+		m.fields, m.err, m.done = msg.fields, msg.err, true
+		return m, tea.Quit
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			// Cancel and wait for the job to unwind; doneMsg quits.
+			m.cancel()
 		}
-		m.done = true
-		m.message = "Upload complete! File successfully encrypted & stored."
-		return m, nil
 	}
 	return m, nil
 }
 
-func (m model) View() string {
-	left := leftBox.Render(fmt.Sprintf("Progress\n\n%s", m.message))
+func (m transferModel) View() string {
+	var left string
+	switch {
+	case m.done && m.err == nil:
+		left = fmt.Sprintf("%s\n\n%s\n\nDone: %s", m.title, m.bar.ViewAs(1), humanBytes(m.event.Bytes))
+	case !m.started:
+		left = fmt.Sprintf("%s\n\nStarting...", m.title)
+	default:
+		e := m.event
+		stats := humanBytes(e.Bytes)
+		if e.Total >= 0 {
+			stats += " / " + humanBytes(e.Total)
+		}
+		if e.Rate > 0 {
+			stats += fmt.Sprintf("  %s/s", humanBytes(int64(e.Rate)))
+		}
+		if e.ETA > 0 {
+			stats += fmt.Sprintf("  ETA %s", e.ETA.Round(time.Second))
+		}
+		left = fmt.Sprintf("%s\n\nStage: %s\n%s\n%s", m.title, e.Stage, m.bar.ViewAs(e.Fraction()), stats)
+	}
 
 	var right string
-	if m.done {
-		right = rightBox.Render(fmt.Sprintf("Results\n\nFile: sample.txt\nHash: SHA256:xxxxxx\nStorage: S3 Bucket\nTime: %s",
-			time.Now().Format("15:04:05")))
-	} else {
-		right = rightBox.Render("Results\n\nWaiting for upload to complete...")
+	switch {
+	case m.err != nil:
+		right = fmt.Sprintf("Failed\n\n%v", m.err)
+	case m.done:
+		var b strings.Builder
+		b.WriteString("Results\n")
+		for _, f := range m.fields {
+			fmt.Fprintf(&b, "\n%s: %s", f.Label, f.Value)
+		}
+		right = b.String()
+	default:
+		right = "Results\n\nWaiting for transfer to complete..."
 	}
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+	return lipgloss.JoinHorizontal(lipgloss.Top, leftBox.Render(left), rightBox.Render(right)) + "\n"
 }
 
-func RunTUI() {
-	p := tea.NewProgram(model{})
+// RunTransfer runs job while drawing a progress bar from the events it
+// reports, then leaves the results panel on screen. Ctrl+C cancels the
+// job's context. It returns the job's error.
+func RunTransfer(title string, job func(ctx context.Context, report progress.Func) ([]Field, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := bar.New(bar.WithDefaultGradient())
+	b.Width = 37
+	p := tea.NewProgram(transferModel{title: title, bar: b, cancel: cancel})
+
+	result := make(chan error, 1)
+	go func() {
+		fields, err := job(ctx, func(e progress.Event) { p.Send(eventMsg(e)) })
+		result <- err
+		p.Send(doneMsg{fields: fields, err: err})
+	}()
+
 	if _, err := p.Run(); err != nil {
-		fmt.Println("Error running TUI:", err)
+		cancel()
+		<-result
+		return err
+	}
+	return <-result
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
 
+// ShowVaultBanner prints to stderr so it never mixes with command output
+// that scripts read from stdout.
 func ShowVaultBanner() {
-	fmt.Println(lipgloss.NewStyle().
+	fmt.Fprintln(os.Stderr, lipgloss.NewStyle().
 		Foreground(borderBlue).
 		Bold(true).
 		Render(`
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"os"
+
 	"vault-cli/cmd"
-	"vault-cli/internal/tui"
 )
 
 func main() {
-	tui.ShowVaultBanner()
-	cmd.Execute()
+	err := cmd.Execute()
+	cmd.CloseDB()
+	os.Exit(cmd.ExitCode(err))
 }